@@ -0,0 +1,351 @@
+// Package awareness 实现与 y-protocols 兼容的感知协议
+//
+// 感知状态用于描述协作者的临时信息（例如用户名、光标位置），不会保存在文档中。
+// 每个客户端只能修改自己的状态，状态通过时钟判断新旧，长时间没有更新的客户端会被视为已离线
+package awareness
+
+import (
+	"CollabEdit/core"
+	"CollabEdit/struts"
+	"encoding/json"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// OutdatedTimeout 超过此时间没有收到更新的客户端会被移除，本地状态每隔一半的时间会重新发送一次
+const OutdatedTimeout = 30 * time.Second
+
+// MetaClientState 客户端状态的元信息
+type MetaClientState struct {
+	Clock       int       // 客户端状态的时钟，每次修改状态时递增
+	LastUpdated time.Time // 最后一次收到客户端状态的时间
+}
+
+// AwarenessChange 感知状态的变化，通过 change 与 update 事件发出
+// change 事件只包含状态真正改变的客户端，update 事件还包含只刷新了时钟的客户端
+type AwarenessChange struct {
+	Added   []int       // 新增的客户端
+	Updated []int       // 更新的客户端
+	Removed []int       // 移除的客户端
+	Origin  interface{} // 变化的来源，本地的修改为 "local"
+}
+
+// Awareness 管理所有客户端的感知状态，以文档的客户端ID区分客户端
+type Awareness struct {
+	core.Observable
+	Doc       *struts.Doc              // 关联的文档
+	ClientID  int                      // 本地客户端ID
+	States    map[int]interface{}      // 所有客户端的状态，可以是任意能编码为 JSON 的值
+	Meta      map[int]*MetaClientState // 所有客户端状态的元信息，包括已移除的客户端
+	mu        sync.Mutex               // 保护 States 与 Meta，定时检查在单独的协程中运行
+	stop      chan struct{}            // 关闭后停止定时检查
+	destroyed bool                     // 是否已销毁
+}
+
+// NewAwareness 创建文档的感知状态，本地状态初始化为空对象，文档销毁时感知状态也会销毁
+func NewAwareness(doc *struts.Doc) *Awareness {
+	a := &Awareness{
+		Doc:      doc,
+		ClientID: doc.ClientID,
+		States:   make(map[int]interface{}),
+		Meta:     make(map[int]*MetaClientState),
+		stop:     make(chan struct{}),
+	}
+	a.Observers = make(map[string][]*core.Observer)
+	go a.checkOutdatedLoop()
+	doc.On("destroy", func(args interface{}) {
+		a.Destroy()
+	})
+	a.SetLocalState(map[string]interface{}{})
+	return a
+}
+
+// checkOutdatedLoop 定时检查过期的客户端，直到感知状态被销毁
+func (a *Awareness) checkOutdatedLoop() {
+	ticker := time.NewTicker(OutdatedTimeout / 10)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.CheckOutdated()
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+// CheckOutdated 刷新即将过期的本地状态，并移除超时没有更新的其他客户端
+// 感知状态会定时调用此方法，一般不需要手动调用
+func (a *Awareness) CheckOutdated() {
+	now := time.Now()
+	a.mu.Lock()
+	localState := a.States[a.ClientID]
+	renew := localState != nil && now.Sub(a.Meta[a.ClientID].LastUpdated) >= OutdatedTimeout/2
+	removed := make([]int, 0)
+	for clientID, meta := range a.Meta {
+		if _, exists := a.States[clientID]; clientID != a.ClientID && exists && now.Sub(meta.LastUpdated) >= OutdatedTimeout {
+			removed = append(removed, clientID)
+		}
+	}
+	a.mu.Unlock()
+	if renew {
+		a.SetLocalState(localState)
+	}
+	if len(removed) > 0 {
+		RemoveAwarenessStates(a, removed, "timeout")
+	}
+}
+
+// Destroy 移除本地状态并停止定时检查
+func (a *Awareness) Destroy() {
+	a.mu.Lock()
+	if a.destroyed {
+		a.mu.Unlock()
+		return
+	}
+	a.destroyed = true
+	close(a.stop)
+	a.mu.Unlock()
+	a.Emit("destroy", a)
+	a.SetLocalState(nil)
+	a.Clear()
+}
+
+// GetLocalState 返回本地状态，本地状态已移除时返回 nil
+func (a *Awareness) GetLocalState() interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.States[a.ClientID]
+}
+
+// SetLocalState 设置本地状态并递增本地时钟，state 为 nil 时移除本地状态，表示客户端离线
+func (a *Awareness) SetLocalState(state interface{}) {
+	clientID := a.ClientID
+	a.mu.Lock()
+	clock := 0
+	if meta, exists := a.Meta[clientID]; exists {
+		clock = meta.Clock + 1
+	}
+	prevState := a.States[clientID]
+	if state == nil {
+		delete(a.States, clientID)
+	} else {
+		a.States[clientID] = state
+	}
+	a.Meta[clientID] = &MetaClientState{Clock: clock, LastUpdated: time.Now()}
+	a.mu.Unlock()
+
+	added, updated, filteredUpdated, removed := []int{}, []int{}, []int{}, []int{}
+	if state == nil {
+		removed = append(removed, clientID)
+	} else if prevState == nil {
+		added = append(added, clientID)
+	} else {
+		updated = append(updated, clientID)
+		if !reflect.DeepEqual(prevState, state) {
+			filteredUpdated = append(filteredUpdated, clientID)
+		}
+	}
+	if len(added) > 0 || len(filteredUpdated) > 0 || len(removed) > 0 {
+		a.Emit("change", &AwarenessChange{Added: added, Updated: filteredUpdated, Removed: removed, Origin: "local"})
+	}
+	a.Emit("update", &AwarenessChange{Added: added, Updated: updated, Removed: removed, Origin: "local"})
+}
+
+// SetLocalStateField 修改本地状态中的一个字段，本地状态已移除或不是对象时不做任何事
+func (a *Awareness) SetLocalStateField(field string, value interface{}) {
+	state, ok := a.GetLocalState().(map[string]interface{})
+	if !ok {
+		return
+	}
+	newState := make(map[string]interface{}, len(state)+1)
+	for k, v := range state {
+		newState[k] = v
+	}
+	newState[field] = value
+	a.SetLocalState(newState)
+}
+
+// GetStates 返回所有客户端状态的副本
+func (a *Awareness) GetStates() map[int]interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	states := make(map[int]interface{}, len(a.States))
+	for clientID, state := range a.States {
+		states[clientID] = state
+	}
+	return states
+}
+
+// RemoveAwarenessStates 移除给定客户端的状态，通常在客户端断开连接时调用
+func RemoveAwarenessStates(a *Awareness, clients []int, origin interface{}) {
+	removed := make([]int, 0)
+	a.mu.Lock()
+	for _, clientID := range clients {
+		if _, exists := a.States[clientID]; exists {
+			delete(a.States, clientID)
+			if clientID == a.ClientID {
+				curMeta := a.Meta[clientID]
+				a.Meta[clientID] = &MetaClientState{Clock: curMeta.Clock + 1, LastUpdated: time.Now()}
+			}
+			removed = append(removed, clientID)
+		}
+	}
+	a.mu.Unlock()
+	if len(removed) > 0 {
+		a.Emit("change", &AwarenessChange{Added: []int{}, Updated: []int{}, Removed: removed, Origin: origin})
+		a.Emit("update", &AwarenessChange{Added: []int{}, Updated: []int{}, Removed: removed, Origin: origin})
+	}
+}
+
+// EncodeAwarenessUpdate 编码给定客户端的状态，已移除的客户端编码为 null
+func EncodeAwarenessUpdate(a *Awareness, clients []int) []byte {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return encodeAwarenessStates(a, clients, a.States)
+}
+
+// EncodeAwarenessUpdateWithStates 使用给定的状态编码客户端，时钟仍然使用 a 中记录的时钟
+func EncodeAwarenessUpdateWithStates(a *Awareness, clients []int, states map[int]interface{}) []byte {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return encodeAwarenessStates(a, clients, states)
+}
+
+// awarenessEntry 感知更新中的一项，state 为 JSON 编码后的状态
+type awarenessEntry struct {
+	clientID int
+	clock    int
+	state    string
+}
+
+// writeAwarenessEntries 依次写入客户端数量，以及每个客户端的ID、时钟与 JSON 编码的状态
+func writeAwarenessEntries(entries []awarenessEntry) []byte {
+	encoder := core.CreateEncoder()
+	encoder.WriteVarUint(uint(len(entries)))
+	for _, entry := range entries {
+		encoder.WriteVarUint(uint(entry.clientID))
+		encoder.WriteVarUint(uint(entry.clock))
+		encoder.WriteString(entry.state)
+	}
+	return encoder.ToBytes()
+}
+
+// encodeAwarenessStates 编码客户端的时钟与状态，无法编码为 JSON 的状态会记录错误并跳过该客户端
+func encodeAwarenessStates(a *Awareness, clients []int, states map[int]interface{}) []byte {
+	entries := make([]awarenessEntry, 0, len(clients))
+	for _, clientID := range clients {
+		clock := 0
+		if meta, exists := a.Meta[clientID]; exists {
+			clock = meta.Clock
+		}
+		state, err := encodeState(states[clientID])
+		if err != nil {
+			log.Printf("[CollabEdit] 编码客户端 %d 的感知状态时出错: %v", clientID, err)
+			continue
+		}
+		entries = append(entries, awarenessEntry{clientID: clientID, clock: clock, state: state})
+	}
+	return writeAwarenessEntries(entries)
+}
+
+// encodeState 将状态编码为 JSON，nil 编码为 null 表示已移除
+func encodeState(state interface{}) (string, error) {
+	buf, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// decodeState 解码 JSON 编码的状态，null 解码为 nil
+func decodeState(str string) (interface{}, error) {
+	var state interface{}
+	if err := json.Unmarshal([]byte(str), &state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// ModifyAwarenessUpdate 修改编码后的感知更新中的每个状态，服务器可以用来过滤或补充客户端的状态
+// 无法解码或修改后无法编码的状态会记录错误并跳过该客户端
+func ModifyAwarenessUpdate(update []byte, modify func(state interface{}) interface{}) []byte {
+	decoder := core.CreateDecoder(update)
+	length := int(decoder.ReadVarUint())
+	entries := make([]awarenessEntry, 0, length)
+	for i := 0; i < length; i++ {
+		clientID := int(decoder.ReadVarUint())
+		clock := int(decoder.ReadVarUint())
+		state, err := decodeState(decoder.ReadVarString())
+		if err != nil {
+			log.Printf("[CollabEdit] 解码客户端 %d 的感知状态时出错: %v", clientID, err)
+			continue
+		}
+		encoded, err := encodeState(modify(state))
+		if err != nil {
+			log.Printf("[CollabEdit] 编码客户端 %d 的感知状态时出错: %v", clientID, err)
+			continue
+		}
+		entries = append(entries, awarenessEntry{clientID: clientID, clock: clock, state: encoded})
+	}
+	return writeAwarenessEntries(entries)
+}
+
+// ApplyAwarenessUpdate 应用其他客户端发送的感知更新，只接受时钟更大的状态
+// 其他客户端不能移除本地状态，收到移除本地状态的更新时递增本地时钟，之后广播的状态会覆盖该更新
+func ApplyAwarenessUpdate(a *Awareness, update []byte, origin interface{}) {
+	decoder := core.CreateDecoder(update)
+	timestamp := time.Now()
+	added, updated, filteredUpdated, removed := []int{}, []int{}, []int{}, []int{}
+	a.mu.Lock()
+	length := int(decoder.ReadVarUint())
+	for i := 0; i < length; i++ {
+		clientID := int(decoder.ReadVarUint())
+		clock := int(decoder.ReadVarUint())
+		state, err := decodeState(decoder.ReadVarString())
+		if err != nil {
+			// 无法解码的状态不能视为移除，忽略该客户端
+			log.Printf("[CollabEdit] 解码客户端 %d 的感知状态时出错: %v", clientID, err)
+			continue
+		}
+		clientMeta, known := a.Meta[clientID]
+		prevState, exists := a.States[clientID]
+		currClock := 0
+		if known {
+			currClock = clientMeta.Clock
+		}
+		if currClock >= clock && (currClock != clock || state != nil || !exists) {
+			continue
+		}
+		if state == nil {
+			if clientID == a.ClientID && a.States[a.ClientID] != nil {
+				// 其他客户端移除了本地状态，递增时钟表示本地客户端仍然在线
+				clock++
+			} else {
+				delete(a.States, clientID)
+			}
+		} else {
+			a.States[clientID] = state
+		}
+		a.Meta[clientID] = &MetaClientState{Clock: clock, LastUpdated: timestamp}
+		if !known && state != nil {
+			added = append(added, clientID)
+		} else if known && state == nil {
+			removed = append(removed, clientID)
+		} else if state != nil {
+			if !reflect.DeepEqual(state, prevState) {
+				filteredUpdated = append(filteredUpdated, clientID)
+			}
+			updated = append(updated, clientID)
+		}
+	}
+	a.mu.Unlock()
+	if len(added) > 0 || len(filteredUpdated) > 0 || len(removed) > 0 {
+		a.Emit("change", &AwarenessChange{Added: added, Updated: filteredUpdated, Removed: removed, Origin: origin})
+	}
+	if len(added) > 0 || len(updated) > 0 || len(removed) > 0 {
+		a.Emit("update", &AwarenessChange{Added: added, Updated: updated, Removed: removed, Origin: origin})
+	}
+}
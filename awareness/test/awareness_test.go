@@ -0,0 +1,167 @@
+package test
+
+import (
+	"CollabEdit/awareness"
+	"CollabEdit/struts"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newAwareness 创建客户端ID为 clientID 的文档的感知状态，测试结束时销毁
+func newAwareness(t *testing.T, clientID int) *awareness.Awareness {
+	doc := struts.NewDoc(nil)
+	doc.ClientID = clientID
+	a := awareness.NewAwareness(doc)
+	t.Cleanup(a.Destroy)
+	return a
+}
+
+func TestAwareness(t *testing.T) {
+	aw1 := newAwareness(t, 0)
+	aw2 := newAwareness(t, 1)
+	aw1.On("update", func(args interface{}) {
+		change := args.(*awareness.AwarenessChange)
+		clients := append(append(append([]int{}, change.Added...), change.Updated...), change.Removed...)
+		awareness.ApplyAwarenessUpdate(aw2, awareness.EncodeAwarenessUpdate(aw1, clients), "custom")
+	})
+	var lastChangeLocal, lastChange *awareness.AwarenessChange
+	aw1.On("change", func(args interface{}) { lastChangeLocal = args.(*awareness.AwarenessChange) })
+	aw2.On("change", func(args interface{}) { lastChange = args.(*awareness.AwarenessChange) })
+
+	aw1.SetLocalState(map[string]interface{}{"x": 3})
+	if got := aw2.GetStates()[0]; !reflect.DeepEqual(got, map[string]interface{}{"x": float64(3)}) {
+		t.Errorf("期望 {x: 3}, 但得到 %v", got)
+	}
+	if aw2.Meta[0].Clock != 1 || !reflect.DeepEqual(lastChange.Added, []int{0}) || lastChange.Origin != "custom" {
+		t.Errorf("对方应新增客户端 0, 但得到 %+v", lastChange)
+	}
+	// 创建感知状态时本地客户端已经存在，所以是更新
+	expected := &awareness.AwarenessChange{Added: []int{}, Updated: []int{0}, Removed: []int{}, Origin: "local"}
+	if !reflect.DeepEqual(lastChangeLocal, expected) {
+		t.Errorf("期望 %+v, 但得到 %+v", expected, lastChangeLocal)
+	}
+
+	lastChange, lastChangeLocal = nil, nil
+	aw1.SetLocalStateField("x", 4)
+	if got := aw2.GetStates()[0]; !reflect.DeepEqual(got, map[string]interface{}{"x": float64(4)}) {
+		t.Errorf("期望 {x: 4}, 但得到 %v", got)
+	}
+	if lastChange == nil || !reflect.DeepEqual(lastChange.Updated, []int{0}) {
+		t.Errorf("对方应更新客户端 0, 但得到 %+v", lastChange)
+	}
+
+	// 状态没有变化时只刷新时钟，不发出 change 事件
+	lastChange, lastChangeLocal = nil, nil
+	aw1.SetLocalState(map[string]interface{}{"x": 4})
+	if lastChange != nil || lastChangeLocal != nil || aw2.Meta[0].Clock != 3 {
+		t.Errorf("相同的状态不应发出 change 事件")
+	}
+
+	aw1.SetLocalState(nil)
+	if !reflect.DeepEqual(lastChange.Removed, []int{0}) {
+		t.Errorf("对方应移除客户端 0, 但得到 %+v", lastChange)
+	}
+	if _, exists := aw2.GetStates()[0]; exists {
+		t.Errorf("客户端 0 的状态应被移除")
+	}
+}
+
+// TestAwarenessWireFormat 编码格式与 y-protocols 一致：客户端数量，之后每个客户端依次为ID、时钟与 JSON 状态
+func TestAwarenessWireFormat(t *testing.T) {
+	aw := newAwareness(t, 5)
+	aw.SetLocalState(map[string]interface{}{"x": 3})
+	expected := append([]byte{1, 5, 1, 7}, `{"x":3}`...)
+	if got := awareness.EncodeAwarenessUpdate(aw, []int{5}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("期望 %v, 但得到 %v", expected, got)
+	}
+	// 来自 y-protocols 的更新：客户端 7 时钟 2 的状态 {"user":{"name":"a"}}
+	update := append([]byte{1, 7, 2, 21}, `{"user":{"name":"a"}}`...)
+	awareness.ApplyAwarenessUpdate(aw, update, nil)
+	if got := aw.GetStates()[7]; !reflect.DeepEqual(got, map[string]interface{}{"user": map[string]interface{}{"name": "a"}}) {
+		t.Errorf("期望客户端 7 的状态, 但得到 %v", got)
+	}
+	// 时钟更小的更新会被忽略
+	awareness.ApplyAwarenessUpdate(aw, append([]byte{1, 7, 1, 4}, "null"...), nil)
+	if _, exists := aw.GetStates()[7]; !exists {
+		t.Errorf("过时的更新不应移除状态")
+	}
+	modified := awareness.ModifyAwarenessUpdate(update, func(state interface{}) interface{} {
+		return map[string]interface{}{"ok": true}
+	})
+	if expected := append([]byte{1, 7, 2, 11}, `{"ok":true}`...); !reflect.DeepEqual(modified, expected) {
+		t.Errorf("期望 %v, 但得到 %v", expected, modified)
+	}
+}
+
+// TestAwarenessStateValues 状态可以是任意 JSON 值，无法编码的状态不会被当作移除发送
+func TestAwarenessStateValues(t *testing.T) {
+	aw1 := newAwareness(t, 1)
+	aw2 := newAwareness(t, 2)
+	for _, state := range []interface{}{map[string]interface{}{"x": "y"}, float64(3), []interface{}{"a", true}, "name"} {
+		aw1.SetLocalState(state)
+		awareness.ApplyAwarenessUpdate(aw2, awareness.EncodeAwarenessUpdate(aw1, []int{1}), nil)
+		if got := aw2.GetStates()[1]; !reflect.DeepEqual(got, state) {
+			t.Errorf("期望 %v, 但得到 %v", state, got)
+		}
+	}
+	// 不是对象的状态无法修改字段
+	aw1.SetLocalStateField("x", 1)
+	if got := aw1.GetLocalState(); got != "name" {
+		t.Errorf("期望 name, 但得到 %v", got)
+	}
+
+	aw1.SetLocalState(map[string]interface{}{"ch": make(chan int)})
+	if got := awareness.EncodeAwarenessUpdate(aw1, []int{1}); !reflect.DeepEqual(got, []byte{0}) {
+		t.Errorf("无法编码的状态应被跳过, 但得到 %v", got)
+	}
+	awareness.ApplyAwarenessUpdate(aw2, append([]byte{1, 1, 9, 1}, "{"...), nil)
+	if _, exists := aw2.GetStates()[1]; !exists {
+		t.Errorf("无法解码的状态不应移除客户端")
+	}
+}
+
+func TestAwarenessRemoveStates(t *testing.T) {
+	aw := newAwareness(t, 1)
+	var removed []int
+	aw.On("change", func(args interface{}) {
+		change := args.(*awareness.AwarenessChange)
+		removed = append(removed, change.Removed...)
+	})
+	awareness.ApplyAwarenessUpdate(aw, append([]byte{2, 2, 1, 2}, append([]byte(`{}`), append([]byte{3, 1, 2}, `{}`...)...)...), nil)
+	// 其他客户端不能移除本地状态，本地时钟递增
+	awareness.ApplyAwarenessUpdate(aw, append([]byte{1, 1, 1, 4}, "null"...), nil)
+	if aw.GetLocalState() == nil || aw.Meta[1].Clock != 2 {
+		t.Errorf("本地状态不应被其他客户端移除")
+	}
+	removed = nil
+	awareness.RemoveAwarenessStates(aw, []int{2}, "disconnect")
+	if !reflect.DeepEqual(removed, []int{2}) {
+		t.Errorf("期望移除客户端 2, 但得到 %v", removed)
+	}
+
+	// 超时没有更新的客户端会被移除
+	aw.Meta[3].LastUpdated = time.Now().Add(-awareness.OutdatedTimeout)
+	aw.CheckOutdated()
+	if !reflect.DeepEqual(removed, []int{2, 3}) {
+		t.Errorf("期望移除客户端 3, 但得到 %v", removed)
+	}
+	if _, exists := aw.GetStates()[1]; !exists {
+		t.Errorf("本地状态不应超时")
+	}
+}
+
+// TestAwarenessDestroy 销毁时与其他协程同时注册观察者不会产生数据竞争
+func TestAwarenessDestroy(t *testing.T) {
+	aw := newAwareness(t, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		aw.On("change", func(args interface{}) {})
+	}()
+	aw.Destroy()
+	<-done
+	if aw.GetLocalState() != nil {
+		t.Errorf("销毁后本地状态应被移除")
+	}
+}
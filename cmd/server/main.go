@@ -0,0 +1,31 @@
+// 与 y-websocket 兼容的协作编辑服务器，客户端连接到 ws://<addr>/<room>
+package main
+
+import (
+	"CollabEdit/server"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+)
+
+// envOr 返回环境变量的值，未设置时返回默认值
+func envOr(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+func main() {
+	host := flag.String("host", envOr("HOST", "localhost"), "监听的主机")
+	port := flag.String("port", envOr("PORT", "1234"), "监听的端口")
+	gc := flag.Bool("gc", envOr("GC", "true") != "false", "是否启用垃圾回收")
+	flag.Parse()
+
+	s := server.NewServer()
+	s.GC = *gc
+	addr := *host + ":" + *port
+	log.Printf("[CollabEdit] 服务器运行在 %s", addr)
+	log.Fatal(http.ListenAndServe(addr, s))
+}
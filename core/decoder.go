@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"math"
+	"unicode/utf16"
 )
 
 // 定义错误信息
@@ -133,15 +134,24 @@ func (d *Decoder) ReadVarUint() uint {
 
 // ReadVarInt 读取变长的有符号整数
 func (d *Decoder) ReadVarInt() int {
+	num, isNegative := d.readVarIntSign()
+	if isNegative {
+		return -num
+	}
+	return num
+}
+
+// readVarIntSign 读取变长整数的绝对值与符号，从而可以区分 -0
+func (d *Decoder) readVarIntSign() (int, bool) {
+	if d.pos >= len(d.arr) {
+		panic(ErrUnexpectedEndOfArray) // 如果超出数组长度，则抛出错误
+	}
 	r := d.arr[d.pos]
 	d.pos++
 	num := int(r & 0x3F)
-	sign := int((r & 0x40) >> 6)
+	isNegative := r&0x40 > 0
 	if r < 0x80 {
-		if sign == 1 {
-			return -num
-		}
-		return num
+		return num, isNegative
 	}
 
 	mult := 64
@@ -153,14 +163,11 @@ func (d *Decoder) ReadVarInt() int {
 		d.pos++
 		num += int(r&0x7F) * mult
 		if r < 0x80 {
-			if sign == 1 {
-				return -num
-			}
-			return num
+			return num, isNegative
 		}
 		mult *= 128
 
-		if num > math.MaxInt64 {
+		if num > math.MaxInt64/128 {
 			panic(ErrIntegerOutOfRange) // 如果值超出范围，则抛出错误
 		}
 	}
@@ -190,7 +197,7 @@ func (d *Decoder) ReadVarString() string {
 
 // ReadTerminatedUint8Array 读取一个以特殊字节序列结尾的 Uint8Array
 func (d *Decoder) ReadTerminatedUint8Array() []byte {
-	var encoder Encoder
+	encoder := CreateEncoder()
 	for {
 		b := d.ReadUint8()
 		if b == 0 {
@@ -210,28 +217,28 @@ func (d *Decoder) ReadTerminatedString() string {
 
 // ReadFloat32 读取一个 float32
 func (d *Decoder) ReadFloat32() float32 {
-	val := binary.LittleEndian.Uint32(d.arr[d.pos:])
+	val := binary.BigEndian.Uint32(d.arr[d.pos:])
 	d.pos += 4
 	return math.Float32frombits(val)
 }
 
 // ReadFloat64 读取一个 float64
 func (d *Decoder) ReadFloat64() float64 {
-	val := binary.LittleEndian.Uint64(d.arr[d.pos:])
+	val := binary.BigEndian.Uint64(d.arr[d.pos:])
 	d.pos += 8
 	return math.Float64frombits(val)
 }
 
 // ReadBigInt64 读取一个 int64
 func (d *Decoder) ReadBigInt64() int64 {
-	val := binary.LittleEndian.Uint64(d.arr[d.pos:])
+	val := binary.BigEndian.Uint64(d.arr[d.pos:])
 	d.pos += 8
 	return int64(val)
 }
 
 // ReadBigUint64 读取一个 uint64
 func (d *Decoder) ReadBigUint64() uint64 {
-	val := binary.LittleEndian.Uint64(d.arr[d.pos:])
+	val := binary.BigEndian.Uint64(d.arr[d.pos:])
 	d.pos += 8
 	return val
 }
@@ -247,7 +254,7 @@ func (d *Decoder) ReadAny() interface{} {
 	case 125:
 		return d.ReadVarInt() // integer
 	case 124:
-		return d.ReadFloat32() // float32
+		return float64(d.ReadFloat32()) // float32
 	case 123:
 		return d.ReadFloat64() // float64
 	case 122:
@@ -279,3 +286,209 @@ func (d *Decoder) ReadAny() interface{} {
 		panic(ErrUnexpectedEndOfArray) // 如果类型不匹配，抛出错误
 	}
 }
+
+// RleDecoder 与 RleEncoder 对应的解码器
+type RleDecoder struct {
+	*Decoder
+	reader func(decoder *Decoder) byte // reader 读取单个值的函数
+	s      byte                        // s 当前的值
+	count  int                         // count 当前值剩余的重复次数，-1 表示一直重复
+}
+
+// NewRleDecoder 创建一个新的 RleDecoder 实例
+func NewRleDecoder(uint8Array []byte, reader func(decoder *Decoder) byte) *RleDecoder {
+	return &RleDecoder{
+		Decoder: CreateDecoder(uint8Array),
+		reader:  reader,
+		s:       0,
+		count:   0,
+	}
+}
+
+// Read 读取下一个值
+func (d *RleDecoder) Read() byte {
+	if d.count == 0 {
+		d.s = d.reader(d.Decoder)
+		if d.HasContent() {
+			d.count = int(d.ReadVarUint()) + 1 // 参见编码器
+		} else {
+			d.count = -1 // 最后一个值一直重复
+		}
+	}
+	d.count--
+	return d.s
+}
+
+// IntDiffDecoder 与 IntDiffEncoder 对应的解码器
+type IntDiffDecoder struct {
+	*Decoder
+	s int // s 当前的值
+}
+
+// NewIntDiffDecoder 创建一个新的 IntDiffDecoder 实例，start 必须与编码器的初始值相同
+func NewIntDiffDecoder(uint8Array []byte, start int) *IntDiffDecoder {
+	return &IntDiffDecoder{
+		Decoder: CreateDecoder(uint8Array),
+		s:       start,
+	}
+}
+
+// Read 读取下一个值
+func (d *IntDiffDecoder) Read() int {
+	d.s += d.ReadVarInt()
+	return d.s
+}
+
+// RleIntDiffDecoder 与 RleIntDiffEncoder 对应的解码器
+type RleIntDiffDecoder struct {
+	*Decoder
+	s     int // s 当前的值
+	count int // count 当前值剩余的重复次数，-1 表示一直重复
+}
+
+// NewRleIntDiffDecoder 创建一个新的 RleIntDiffDecoder 实例，start 必须与编码器的初始值相同
+func NewRleIntDiffDecoder(uint8Array []byte, start int) *RleIntDiffDecoder {
+	return &RleIntDiffDecoder{
+		Decoder: CreateDecoder(uint8Array),
+		s:       start,
+		count:   0,
+	}
+}
+
+// Read 读取下一个值
+func (d *RleIntDiffDecoder) Read() int {
+	if d.count == 0 {
+		d.s += d.ReadVarInt()
+		if d.HasContent() {
+			d.count = int(d.ReadVarUint()) + 1 // 参见编码器
+		} else {
+			d.count = -1 // 最后一个值一直重复
+		}
+	}
+	d.count--
+	return d.s
+}
+
+// UintOptRleDecoder 与 UintOptRleEncoder 对应的解码器
+type UintOptRleDecoder struct {
+	*Decoder
+	s     int // s 当前的值
+	count int // count 当前值剩余的重复次数
+}
+
+// NewUintOptRleDecoder 创建一个新的 UintOptRleDecoder 实例
+func NewUintOptRleDecoder(uint8Array []byte) *UintOptRleDecoder {
+	return &UintOptRleDecoder{
+		Decoder: CreateDecoder(uint8Array),
+		s:       0,
+		count:   0,
+	}
+}
+
+// Read 读取下一个值
+func (d *UintOptRleDecoder) Read() int {
+	if d.count == 0 {
+		s, isNegative := d.readVarIntSign()
+		d.s = s
+		d.count = 1
+		// 符号为负时之后写入了重复的次数
+		if isNegative {
+			d.count = int(d.ReadVarUint()) + 2
+		}
+	}
+	d.count--
+	return d.s
+}
+
+// IncUintOptRleDecoder 与 IncUintOptRleEncoder 对应的解码器
+type IncUintOptRleDecoder struct {
+	*Decoder
+	s     int // s 当前的值
+	count int // count 剩余的递增次数
+}
+
+// NewIncUintOptRleDecoder 创建一个新的 IncUintOptRleDecoder 实例
+func NewIncUintOptRleDecoder(uint8Array []byte) *IncUintOptRleDecoder {
+	return &IncUintOptRleDecoder{
+		Decoder: CreateDecoder(uint8Array),
+		s:       0,
+		count:   0,
+	}
+}
+
+// Read 读取下一个值
+func (d *IncUintOptRleDecoder) Read() int {
+	if d.count == 0 {
+		s, isNegative := d.readVarIntSign()
+		d.s = s
+		d.count = 1
+		// 符号为负时之后写入了递增的次数
+		if isNegative {
+			d.count = int(d.ReadVarUint()) + 2
+		}
+	}
+	d.count--
+	res := d.s
+	d.s++
+	return res
+}
+
+// IntDiffOptRleDecoder 与 IntDiffOptRleEncoder 对应的解码器
+type IntDiffOptRleDecoder struct {
+	*Decoder
+	s     int // s 当前的值
+	count int // count 当前差值剩余的重复次数
+	diff  int // diff 当前的差值
+}
+
+// NewIntDiffOptRleDecoder 创建一个新的 IntDiffOptRleDecoder 实例
+func NewIntDiffOptRleDecoder(uint8Array []byte) *IntDiffOptRleDecoder {
+	return &IntDiffOptRleDecoder{
+		Decoder: CreateDecoder(uint8Array),
+		s:       0,
+		count:   0,
+		diff:    0,
+	}
+}
+
+// Read 读取下一个值
+func (d *IntDiffOptRleDecoder) Read() int {
+	if d.count == 0 {
+		diff := d.ReadVarInt()
+		// 最低位表示之后是否写入了重复的次数
+		hasCount := diff & 1
+		d.diff = diff >> 1
+		d.count = 1
+		if hasCount == 1 {
+			d.count = int(d.ReadVarUint()) + 2
+		}
+	}
+	d.s += d.diff
+	d.count--
+	return d.s
+}
+
+// StringDecoder 与 StringEncoder 对应的解码器，字符串的长度以 UTF-16 码元计算
+type StringDecoder struct {
+	decoder *UintOptRleDecoder // decoder 字符串长度的解码器
+	str     []uint16           // str 所有字符串拼接后的 UTF-16 编码
+	spos    int                // spos 下一个字符串的起始位置
+}
+
+// NewStringDecoder 创建一个新的 StringDecoder 实例
+func NewStringDecoder(uint8Array []byte) *StringDecoder {
+	decoder := NewUintOptRleDecoder(uint8Array)
+	return &StringDecoder{
+		decoder: decoder,
+		str:     utf16.Encode([]rune(decoder.ReadVarString())),
+		spos:    0,
+	}
+}
+
+// Read 读取下一个字符串
+func (d *StringDecoder) Read() string {
+	end := d.spos + d.decoder.Read()
+	res := string(utf16.Decode(d.str[d.spos:end]))
+	d.spos = end
+	return res
+}
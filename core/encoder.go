@@ -3,9 +3,19 @@ package core
 import (
 	"math"
 	"reflect"
+	"sort"
 	"strings"
 )
 
+const BIT1 = 1
+const BIT2 = 2
+const BIT3 = 4
+const BIT4 = 8
+const BIT5 = 16
+const BIT6 = 32
+const BIT7 = 64
+const BIT8 = 128
+
 const BITS0 = 0
 const BITS1 = 1
 const BITS2 = 3
@@ -23,6 +33,7 @@ const BITS13 = 8191
 const BITS14 = 16383
 const BITS15 = 32767
 const BITS16 = 65535
+const BITS31 = 0x7FFFFFFF
 
 type Encoder struct {
 	CPos  int      `json:"c_pos"` //当前写入的位置
@@ -101,8 +112,8 @@ func (e *Encoder) Set(pos int, num byte) {
 	buffer[pos] = num
 }
 
-// WriteByte 写一个字节
-func (e *Encoder) WriteByte(num byte) {
+// WriteUint8 写一个字节
+func (e *Encoder) WriteUint8(num byte) {
 	e.Write(num)
 }
 
@@ -149,7 +160,7 @@ func (e *Encoder) SetUint32(pos int, num uint32) {
 // WriteVarUint 写入一个变长无符号整数
 func (e *Encoder) WriteVarUint(num uint) {
 	for num > BITS7 {
-		e.Write(byte(BITS8 | (num & BITS8)))
+		e.Write(byte(BIT8 | (num & BITS7)))
 		num >>= 7
 	}
 	e.Write(byte(num & BITS7)) // 这里确保只写入低7位
@@ -162,20 +173,25 @@ func IsNegativeZero(num float64) bool {
 
 // WriteVarInt 写入一个变长整数
 func (e *Encoder) WriteVarInt(num int) {
-	isNegative := IsNegativeZero(float64(num))
-	if isNegative {
-		num = -num
+	if num < 0 {
+		e.writeVarIntSign(uint(-num), true)
+	} else {
+		e.writeVarIntSign(uint(num), false)
 	}
+}
 
+// writeVarIntSign 写入一个变长整数，符号单独给出，从而可以编码 -0
+func (e *Encoder) writeVarIntSign(num uint, isNegative bool) {
+	//  第一个字节：是否继续读取 | 是否为负数 | 低 6 位数值
 	var b byte
 	if num > BITS6 {
-		b = BITS8
+		b = BIT8
 	} else {
 		b = 0
 	}
 
 	if isNegative {
-		b |= BITS7
+		b |= BIT7
 	}
 
 	b |= byte(num & BITS6)
@@ -187,7 +203,7 @@ func (e *Encoder) WriteVarInt(num int) {
 	for num > 0 {
 		var nextByte byte
 		if num > BITS7 {
-			nextByte = BITS8
+			nextByte = BIT8
 		} else {
 			nextByte = 0
 		}
@@ -241,17 +257,16 @@ func (e *Encoder) WriteTerminatedUint8Array(buf []byte) {
 	e.Write(0)
 }
 
-// 定义一个缓存来临时存储字符串
-var strBuffer = make([]byte, 30000)
-var maxStrBSize = len(strBuffer) / 3
+// maxStrBSize 不超过此长度的字符串逐字节写入当前缓冲区，更长的字符串作为单独的字节数组写入
+var maxStrBSize = 10000
 
 // WriteString 写入一个字符串
+// 直接从字符串中读取字节，不使用共享的缓冲区，多个编码器可以在不同的协程中同时使用
 func (e *Encoder) WriteString(str string) {
 	if len(str) < maxStrBSize {
-		written := copy(strBuffer, str)
-		e.WriteVarUint(uint(written))
-		for i := 0; i < written; i++ {
-			e.Write(strBuffer[i])
+		e.WriteVarUint(uint(len(str)))
+		for i := 0; i < len(str); i++ {
+			e.Write(str[i])
 		}
 	} else {
 		byteArray := []byte(str)
@@ -297,8 +312,26 @@ func (e *Encoder) WriteBigUint64(num uint64) {
 	e.WriteOnDataView(8).SetBigUint64(0, num, false)
 }
 
+// isFloat32 判断数字能否无损地用 float32 表示
 func isFloat32(n float64) bool {
-	return n >= -math.MaxFloat32 && n <= math.MaxFloat32
+	return float64(float32(n)) == n
+}
+
+// writeNumber 按照 lib0 的规则编码数字：32 位以内的整数使用变长整数，否则使用浮点数
+func (e *Encoder) writeNumber(num float64) {
+	if num == math.Trunc(num) && math.Abs(num) <= BITS31 && !IsNegativeZero(num) {
+		// TYPE 125: INTEGER
+		e.Write(125)
+		e.WriteVarInt(int(num))
+	} else if isFloat32(num) {
+		// TYPE 124: FLOAT32
+		e.Write(124)
+		e.WriteFloat32(float32(num))
+	} else {
+		// TYPE 123: FLOAT64
+		e.Write(123)
+		e.WriteFloat64(num)
+	}
 }
 
 // WriteAny
@@ -349,29 +382,15 @@ func (e *Encoder) WriteAny(data interface{}) {
 		e.Write(119)
 		e.WriteString(val.String())
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
-		if val.Int() <= math.MaxInt32 && val.Int() >= math.MinInt32 {
-			// TYPE 125: INTEGER
-			e.Write(125)
-			e.WriteVarInt(int(val.Int()))
-		}
+		e.writeNumber(float64(val.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		e.writeNumber(float64(val.Uint()))
 	case reflect.Int64:
 		// TYPE 122: BigInt
 		e.Write(122)
 		e.WriteBigInt64(val.Int())
-	case reflect.Float32:
-		// TYPE 124: FLOAT32
-		e.Write(124)
-		e.WriteFloat32(float32(val.Float()))
-	case reflect.Float64:
-		if isFloat32(val.Float()) {
-			// TYPE 124: FLOAT32
-			e.Write(124)
-			e.WriteFloat32(float32(val.Float()))
-		} else {
-			// TYPE 123: FLOAT64
-			e.Write(123)
-			e.WriteFloat64(val.Float())
-		}
+	case reflect.Float32, reflect.Float64:
+		e.writeNumber(val.Float())
 	case reflect.Bool:
 		// TYPE 120/121: boolean (true/false)
 		if val.Bool() {
@@ -383,7 +402,7 @@ func (e *Encoder) WriteAny(data interface{}) {
 		if val.Type().Elem().Kind() == reflect.Uint8 {
 			// TYPE 116: ArrayBuffer
 			e.Write(116)
-			e.WriteByteArray(val.Bytes())
+			e.WriteVarByteArray(val.Bytes())
 		} else {
 			// TYPE 117: Array
 			e.Write(117)
@@ -396,6 +415,10 @@ func (e *Encoder) WriteAny(data interface{}) {
 		// TYPE 118: Object
 		e.Write(118)
 		keys := val.MapKeys()
+		// Go 的映射没有插入顺序，按键排序保证编码结果确定
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
 		e.WriteVarUint(uint(len(keys)))
 		for _, key := range keys {
 			e.WriteString(key.String())
@@ -411,6 +434,13 @@ func (e *Encoder) WriteAny(data interface{}) {
 			e.WriteString(field.Name)
 			e.WriteAny(val.Field(i).Interface())
 		}
+	case reflect.Ptr, reflect.Interface:
+		if val.IsNil() {
+			// TYPE 126: null
+			e.Write(126)
+		} else {
+			e.WriteAny(val.Elem().Interface())
+		}
 	default:
 		// TYPE 127: undefined
 		e.Write(127)
@@ -418,40 +448,34 @@ func (e *Encoder) WriteAny(data interface{}) {
 }
 
 // RleEncoder 结构体，继承自 Encoder
+// 连续相同的值只写入一次，之后写入重复的次数
 type RleEncoder struct {
 	*Encoder
-	w     interface{}
-	s     interface{}
-	count int
+	w     func(encoder *Encoder, v byte) // w 写入单个值的函数
+	s     byte                           // s 当前的值
+	count int                            // count 当前值重复的次数
 }
 
 // NewRleEncoder 创建一个新的 RleEncoder 实例
-func NewRleEncoder(writer interface{}) *RleEncoder {
+func NewRleEncoder(writer func(encoder *Encoder, v byte)) *RleEncoder {
 	return &RleEncoder{
 		Encoder: CreateEncoder(),
 		w:       writer,
-		s:       nil,
+		s:       0,
 		count:   0,
 	}
 }
 
-func CallFunc(fn interface{}, a *Encoder, b interface{}) {
-	// 使用反射调用函数
-	v := reflect.ValueOf(fn)
-	v.Call([]reflect.Value{reflect.ValueOf(a), reflect.ValueOf(b)})
-}
-
 // Write 向 RleEncoder 写入一个值
-func (e *RleEncoder) Write(v interface{}) {
-	if e.s == v {
+func (e *RleEncoder) Write(v byte) {
+	if e.count > 0 && e.s == v {
 		e.count++
 	} else {
 		if e.count > 0 {
 			e.WriteVarUint(uint(e.count - 1)) // 因为 count 总是 > 0，所以可以减去一个。非标准编码
 		}
 		e.count = 1
-		//e.w(e.Encoder, v)
-		CallFunc(e.w, e.Encoder, v)
+		e.w(e.Encoder, v)
 		e.s = v
 	}
 }
@@ -542,13 +566,8 @@ func (e *UintOptRleEncoder) ToBytes() []byte {
 // flushUintOptRleEncoder 刷新 UintOptRleEncoder 的状态
 func flushUintOptRleEncoder(e *UintOptRleEncoder) {
 	if e.count > 0 {
-		var s int
-		if e.count == 1 {
-			s = e.s
-		} else {
-			s = -e.s
-		}
-		e.WriteVarInt(s)
+		// 只有一个值时符号为正，多个值时符号为负，表示之后写入了重复的次数
+		e.writeVarIntSign(uint(e.s), e.count > 1)
 		if e.count > 1 {
 			e.WriteVarUint(uint(e.count - 2)) // 因为 count 总是 > 1，所以可以减去一个。非标准编码
 		}
@@ -574,13 +593,8 @@ func NewIncUintOptRleEncoder() *IncUintOptRleEncoder {
 // flushIncUintOptRleEncoder 刷新 IncUintOptRleEncoder 的状态
 func flushIncUintOptRleEncoder(e *IncUintOptRleEncoder) {
 	if e.count > 0 {
-		var s int
-		if e.count == 1 {
-			s = e.s
-		} else {
-			s = -e.s
-		}
-		e.WriteVarInt(s)
+		// 只有一个值时符号为正，多个值时符号为负，表示之后写入了重复的次数
+		e.writeVarIntSign(uint(e.s), e.count > 1)
 		if e.count > 1 {
 			e.WriteVarUint(uint(e.count - 2)) // 因为 count 总是 > 1，所以可以减去一个。非标准编码
 		}
@@ -678,7 +692,20 @@ func (e *StringEncoder) Write(str string) {
 		e.sarr = append(e.sarr, e.s)
 		e.s = ""
 	}
-	e.lensE.Write(len(str))
+	e.lensE.Write(utf16Length(str))
+}
+
+// utf16Length 返回字符串的 UTF-16 长度，与 JavaScript 的 string.length 一致
+func utf16Length(str string) int {
+	length := 0
+	for _, r := range str {
+		if r >= 0x10000 {
+			length += 2
+		} else {
+			length++
+		}
+	}
+	return length
 }
 
 // ToBytes 将 StringEncoder 的内容转换为 Uint8Array
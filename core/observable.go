@@ -5,34 +5,56 @@ import (
 	"sync"
 )
 
+// Observer 一个已注册的观察者，可以通过 OffObserver 准确地注销
+type Observer struct {
+	f func(args interface{})
+}
+
 // Observable 观察者结构
 type Observable struct {
-	Observers map[string][]func(args interface{})
+	Observers map[string][]*Observer
 	mu        sync.Mutex
 }
 
 // NewObservable 初始化新观察者
 func NewObservable() *Observable {
 	return &Observable{
-		Observers: make(map[string][]func(args interface{})),
+		Observers: make(map[string][]*Observer),
 	}
 }
 
-// On 注册观察者
-func (o *Observable) On(eventName string, f func(args interface{})) {
+// On 注册观察者，返回的观察者可以用于注销
+func (o *Observable) On(eventName string, f func(args interface{})) *Observer {
 	//上锁
 	o.mu.Lock()
 	defer o.mu.Unlock()
-	o.Observers[eventName] = append(o.Observers[eventName], f)
+	observer := &Observer{f: f}
+	o.Observers[eventName] = append(o.Observers[eventName], observer)
+	return observer
 }
 
 // Off 注销观察者
+// 函数按代码地址比较，同一个函数字面量创建的闭包无法区分，此时应使用 OffObserver
 func (o *Observable) Off(eventName string, f func(args interface{})) {
+	o.removeObserver(eventName, func(observer *Observer) bool {
+		return funcEqual(observer.f, f)
+	})
+}
+
+// OffObserver 注销 On 返回的观察者
+func (o *Observable) OffObserver(eventName string, observer *Observer) {
+	o.removeObserver(eventName, func(other *Observer) bool {
+		return other == observer
+	})
+}
+
+// removeObserver 注销第一个满足 match 的观察者
+func (o *Observable) removeObserver(eventName string, match func(observer *Observer) bool) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 	observers := o.Observers[eventName]
 	for i, observer := range observers {
-		if funcEqual(observer, f) {
+		if match(observer) {
 			o.Observers[eventName] = append(observers[:i], observers[i+1:]...)
 			break
 		}
@@ -42,13 +64,27 @@ func (o *Observable) Off(eventName string, f func(args interface{})) {
 	}
 }
 
+// Clear 注销所有观察者
+func (o *Observable) Clear() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.Observers = make(map[string][]*Observer)
+}
+
+// HasObservers 判断事件是否有观察者，没有观察者时可以跳过准备事件参数
+func (o *Observable) HasObservers(eventName string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.Observers[eventName]) > 0
+}
+
 // Emit 事件触发，通知所有注册的观察者
 func (o *Observable) Emit(eventName string, args interface{}) {
 	o.mu.Lock()
-	observers := append([]func(args interface{}){}, o.Observers[eventName]...)
+	observers := append([]*Observer{}, o.Observers[eventName]...)
 	o.mu.Unlock()
 	for _, observer := range observers {
-		observer(args)
+		observer.f(args)
 	}
 }
 
@@ -49,3 +49,19 @@ func TestObservable(t *testing.T) {
 		t.Errorf("期望通知信息为 '通知:Data has been updated again.', 但得到 '%s'", notifyMessage)
 	}
 }
+
+// TestObservableOffObserver 同一个函数字面量创建的闭包按注册时返回的观察者注销
+func TestObservableOffObserver(t *testing.T) {
+	eventBus := core.NewObservable()
+	calls := make([]int, 2)
+	observers := make([]*core.Observer, 2)
+	for i := range observers {
+		i := i
+		observers[i] = eventBus.On("event", func(args interface{}) { calls[i]++ })
+	}
+	eventBus.OffObserver("event", observers[1])
+	eventBus.Emit("event", nil)
+	if calls[0] != 1 || calls[1] != 0 {
+		t.Errorf("期望只有第一个观察者被调用, 但得到 %v", calls)
+	}
+}
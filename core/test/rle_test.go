@@ -0,0 +1,169 @@
+package test
+
+import (
+	"CollabEdit/core"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// 以下期望的字节与 lib0 对相同输入编码的结果一致
+
+func TestRleEncoding(t *testing.T) {
+	encoder := core.NewRleEncoder((*core.Encoder).WriteUint8)
+	for _, v := range []byte{4, 4, 7} {
+		encoder.Write(v)
+	}
+	buf := encoder.ToBytes()
+	if !reflect.DeepEqual(buf, []byte{4, 1, 7}) {
+		t.Fatalf("期望 [4 1 7], 但得到 %v", buf)
+	}
+	decoder := core.NewRleDecoder(buf, (*core.Decoder).ReadUint8)
+	for _, v := range []byte{4, 4, 7, 7} {
+		if got := decoder.Read(); got != v {
+			t.Fatalf("期望 %d, 但得到 %d", v, got)
+		}
+	}
+}
+
+func TestUintOptRleEncoding(t *testing.T) {
+	cases := []struct {
+		values   []int
+		expected []byte
+	}{
+		{[]int{1, 1, 1, 2, 3, 3}, []byte{65, 1, 2, 67, 0}},
+		{[]int{0, 0, 0}, []byte{64, 1}}, // 0 的重复使用 -0 表示
+	}
+	for _, c := range cases {
+		encoder := core.NewUintOptRleEncoder()
+		for _, v := range c.values {
+			encoder.Write(v)
+		}
+		buf := encoder.ToBytes()
+		if !reflect.DeepEqual(buf, c.expected) {
+			t.Fatalf("期望 %v, 但得到 %v", c.expected, buf)
+		}
+		decoder := core.NewUintOptRleDecoder(buf)
+		for _, v := range c.values {
+			if got := decoder.Read(); got != v {
+				t.Fatalf("期望 %d, 但得到 %d", v, got)
+			}
+		}
+	}
+}
+
+func TestIncUintOptRleEncoding(t *testing.T) {
+	cases := []struct {
+		values   []int
+		expected []byte
+	}{
+		{[]int{3, 4, 5, 9}, []byte{67, 1, 9}},
+		{[]int{0, 1, 2}, []byte{64, 1}},
+	}
+	for _, c := range cases {
+		encoder := core.NewIncUintOptRleEncoder()
+		for _, v := range c.values {
+			encoder.Write(v)
+		}
+		buf := encoder.ToBytes()
+		if !reflect.DeepEqual(buf, c.expected) {
+			t.Fatalf("期望 %v, 但得到 %v", c.expected, buf)
+		}
+		decoder := core.NewIncUintOptRleDecoder(buf)
+		for _, v := range c.values {
+			if got := decoder.Read(); got != v {
+				t.Fatalf("期望 %d, 但得到 %d", v, got)
+			}
+		}
+	}
+}
+
+func TestIntDiffEncoding(t *testing.T) {
+	intDiff := core.NewIntDiffEncoder(10)
+	intDiff.Write(11)
+	intDiff.Write(9)
+	if buf := intDiff.ToBytes(); !reflect.DeepEqual(buf, []byte{1, 66}) {
+		t.Errorf("期望 [1 66], 但得到 %v", buf)
+	}
+
+	rleIntDiff := core.NewRleIntDiffEncoder(0)
+	for _, v := range []int{5, 5, 5, 7} {
+		rleIntDiff.Write(v)
+	}
+	if buf := rleIntDiff.ToBytes(); !reflect.DeepEqual(buf, []byte{5, 2, 2}) {
+		t.Errorf("期望 [5 2 2], 但得到 %v", buf)
+	}
+
+	intDiffOptRle := core.NewIntDiffOptRleEncoder()
+	for _, v := range []int{1, 2, 3, 10} {
+		intDiffOptRle.Write(v)
+	}
+	if buf := intDiffOptRle.ToBytes(); !reflect.DeepEqual(buf, []byte{3, 1, 14}) {
+		t.Errorf("期望 [3 1 14], 但得到 %v", buf)
+	}
+}
+
+func TestStringEncoding(t *testing.T) {
+	encoder := core.NewStringEncoder()
+	values := []string{"a", "😀", "bc"}
+	for _, s := range values {
+		encoder.Write(s)
+	}
+	buf := encoder.ToBytes()
+	// 长度以 UTF-16 码元计算
+	expected := []byte{7, 'a', 0xf0, 0x9f, 0x98, 0x80, 'b', 'c', 1, 66, 0}
+	if !reflect.DeepEqual(buf, expected) {
+		t.Fatalf("期望 %v, 但得到 %v", expected, buf)
+	}
+	decoder := core.NewStringDecoder(buf)
+	for _, s := range values {
+		if got := decoder.Read(); got != s {
+			t.Fatalf("期望 %q, 但得到 %q", s, got)
+		}
+	}
+}
+
+// TestIntEncodersRandom 随机数据经过编码与解码后保持不变
+func TestIntEncodersRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	values := make([]int, 1000)
+	for i := range values {
+		switch r.Intn(3) {
+		case 0:
+			values[i] = r.Intn(5)
+		case 1:
+			if i > 0 {
+				values[i] = values[i-1] + 1
+			}
+		default:
+			values[i] = r.Intn(1 << 20)
+		}
+	}
+
+	intDiff := core.NewIntDiffEncoder(3)
+	rleIntDiff := core.NewRleIntDiffEncoder(3)
+	uintOptRle := core.NewUintOptRleEncoder()
+	incUintOptRle := core.NewIncUintOptRleEncoder()
+	intDiffOptRle := core.NewIntDiffOptRleEncoder()
+	for _, v := range values {
+		intDiff.Write(v)
+		rleIntDiff.Write(v)
+		uintOptRle.Write(v)
+		incUintOptRle.Write(v)
+		intDiffOptRle.Write(v)
+	}
+	decoders := map[string]func() int{
+		"IntDiff":       core.NewIntDiffDecoder(intDiff.ToBytes(), 3).Read,
+		"RleIntDiff":    core.NewRleIntDiffDecoder(rleIntDiff.ToBytes(), 3).Read,
+		"UintOptRle":    core.NewUintOptRleDecoder(uintOptRle.ToBytes()).Read,
+		"IncUintOptRle": core.NewIncUintOptRleDecoder(incUintOptRle.ToBytes()).Read,
+		"IntDiffOptRle": core.NewIntDiffOptRleDecoder(intDiffOptRle.ToBytes()).Read,
+	}
+	for name, read := range decoders {
+		for i, v := range values {
+			if got := read(); got != v {
+				t.Fatalf("%s: 第 %d 个值期望 %d, 但得到 %d", name, i, v, got)
+			}
+		}
+	}
+}
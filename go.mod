@@ -4,5 +4,5 @@ go 1.22.2
 
 require (
 	github.com/google/uuid v1.6.0
-	github.com/mitchellh/mapstructure v1.5.0
+	github.com/gorilla/websocket v1.5.3
 )
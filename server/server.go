@@ -0,0 +1,374 @@
+// Package server 实现与 y-websocket 兼容的 WebSocket 服务器
+//
+// 客户端连接到 /<room>，同一房间的客户端共享服务器上的一份文档。
+// 每条二进制消息以消息类型开头：同步消息之后是 y-protocols 同步协议的内容，感知消息之后是编码后的感知更新
+package server
+
+import (
+	"CollabEdit/awareness"
+	"CollabEdit/core"
+	"CollabEdit/struts"
+	ysync "CollabEdit/sync"
+	"github.com/gorilla/websocket"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 消息类型，与 y-websocket 一致
+const (
+	MessageSync      = 0 // 同步协议消息
+	MessageAwareness = 1 // 感知更新消息
+)
+
+// DefaultPingInterval 默认的心跳间隔，一个间隔内没有收到 pong 的连接会被关闭
+const DefaultPingInterval = 30 * time.Second
+
+// sendBufferSize 每个连接待发送消息的缓冲区大小，缓冲区满时认为连接已失效
+const sendBufferSize = 256
+
+// writeWait 写入一条消息的超时时间
+const writeWait = 10 * time.Second
+
+// Server WebSocket 服务器，实现 http.Handler
+type Server struct {
+	GC           bool          // 房间中的文档是否启用垃圾回收
+	PingInterval time.Duration // 心跳间隔，为 0 时使用 DefaultPingInterval
+	upgrader     websocket.Upgrader
+	mu           sync.Mutex
+	rooms        map[string]*Room
+}
+
+// NewServer 创建服务器，文档默认启用垃圾回收
+func NewServer() *Server {
+	return &Server{
+		GC: true,
+		upgrader: websocket.Upgrader{
+			// 与 y-websocket 一致，接受任何来源的连接
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		rooms: make(map[string]*Room),
+	}
+}
+
+// GetRoom 返回名为 name 的房间，不存在时创建
+// 最后一个连接离开时房间会被移除并销毁，之后再次获取会创建新的房间
+func (s *Server) GetRoom(name string) *Room {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.getRoom(name)
+}
+
+// HasRoom 判断名为 name 的房间是否存在
+func (s *Server) HasRoom(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, exists := s.rooms[name]
+	return exists
+}
+
+// getRoom 返回名为 name 的房间，不存在时创建，调用方需要持有 s.mu
+func (s *Server) getRoom(name string) *Room {
+	room, exists := s.rooms[name]
+	if !exists {
+		room = newRoom(s, name)
+		s.rooms[name] = room
+	}
+	return room
+}
+
+// join 在名为 name 的房间中注册新的连接
+// 持有 s.mu 注册，避免房间在连接加入之前因最后一个连接离开而被移除
+func (s *Server) join(name string, ws *websocket.Conn) *Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	room := s.getRoom(name)
+	conn := &Conn{
+		ws:   ws,
+		room: room,
+		peer: ysync.NewPeer(ws.RemoteAddr().String()),
+		send: make(chan []byte, sendBufferSize),
+		done: make(chan struct{}),
+	}
+	room.connsMu.Lock()
+	room.conns[conn] = make(map[int]struct{})
+	room.connsMu.Unlock()
+	return conn
+}
+
+// removeRoom 房间中已没有连接时移除房间，并销毁房间的感知状态与文档
+// 调用方可能持有文档锁，所以在单独的协程中等待文档锁并销毁
+func (s *Server) removeRoom(room *Room) {
+	s.mu.Lock()
+	if s.rooms[room.Name] != room || room.ConnCount() > 0 {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.rooms, room.Name)
+	s.mu.Unlock()
+	go func() {
+		room.Awareness.Destroy()
+		room.Transact(func(doc *struts.Doc) {
+			doc.Destroy()
+		})
+	}()
+}
+
+// ServeHTTP 将请求升级为 WebSocket 连接，房间名为去掉开头的 / 之后的路径
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade 已经向客户端返回了错误
+		return
+	}
+	pingInterval := s.PingInterval
+	if pingInterval == 0 {
+		pingInterval = DefaultPingInterval
+	}
+	conn := s.join(strings.TrimPrefix(r.URL.Path, "/"), ws)
+	conn.room.setupConn(conn, pingInterval)
+}
+
+// Room 一个房间，包含共享的文档、感知状态以及所有连接
+// 文档不是并发安全的，所有对文档的访问都需要持有 docMu
+type Room struct {
+	Name      string
+	Doc       *struts.Doc
+	Awareness *awareness.Awareness
+	server    *Server // 所属的服务器，最后一个连接离开时从中移除
+	docMu     sync.Mutex
+	connsMu   sync.Mutex
+	conns     map[*Conn]map[int]struct{} // 连接到该连接控制的感知客户端的映射
+}
+
+// newRoom 创建房间，服务器自身没有感知状态
+func newRoom(s *Server, name string) *Room {
+	room := &Room{
+		server: s,
+		Name:   name,
+		Doc:    struts.NewDoc(&struts.DocOpts{GC: s.GC}),
+		conns:  make(map[*Conn]map[int]struct{}),
+	}
+	room.Awareness = awareness.NewAwareness(room.Doc)
+	room.Awareness.SetLocalState(nil)
+	room.Awareness.On("update", room.awarenessUpdateHandler)
+	room.Doc.On("update", room.updateHandler)
+	return room
+}
+
+// Transact 在持有文档锁时修改房间中的文档，修改会广播给所有连接
+func (room *Room) Transact(f func(doc *struts.Doc)) {
+	room.docMu.Lock()
+	defer room.docMu.Unlock()
+	f(room.Doc)
+}
+
+// ConnCount 返回房间中的连接数
+func (room *Room) ConnCount() int {
+	room.connsMu.Lock()
+	defer room.connsMu.Unlock()
+	return len(room.conns)
+}
+
+// updateHandler 将文档的更新广播给除来源之外的所有连接
+func (room *Room) updateHandler(args interface{}) {
+	event := args.(*struts.UpdateEvent)
+	encoder := core.CreateEncoder()
+	encoder.WriteVarUint(MessageSync)
+	ysync.WriteUpdate(encoder, event.Update)
+	message := encoder.ToBytes()
+	for _, conn := range room.connections() {
+		if !conn.peer.IsOriginOf(event) {
+			conn.Send(message)
+		}
+	}
+}
+
+// awarenessUpdateHandler 记录连接控制的感知客户端，并将感知更新广播给所有连接
+func (room *Room) awarenessUpdateHandler(args interface{}) {
+	change := args.(*awareness.AwarenessChange)
+	changedClients := append(append(append([]int{}, change.Added...), change.Updated...), change.Removed...)
+	if conn, ok := change.Origin.(*Conn); ok {
+		room.connsMu.Lock()
+		if controlledIDs, exists := room.conns[conn]; exists {
+			for _, clientID := range change.Added {
+				controlledIDs[clientID] = struct{}{}
+			}
+			for _, clientID := range change.Removed {
+				delete(controlledIDs, clientID)
+			}
+		}
+		room.connsMu.Unlock()
+	}
+	encoder := core.CreateEncoder()
+	encoder.WriteVarUint(MessageAwareness)
+	encoder.WriteVarByteArray(awareness.EncodeAwarenessUpdate(room.Awareness, changedClients))
+	message := encoder.ToBytes()
+	for _, conn := range room.connections() {
+		conn.Send(message)
+	}
+}
+
+// connections 返回当前所有连接的快照
+func (room *Room) connections() []*Conn {
+	room.connsMu.Lock()
+	defer room.connsMu.Unlock()
+	conns := make([]*Conn, 0, len(room.conns))
+	for conn := range room.conns {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// setupConn 为已注册的连接发送 SyncStep1 与当前的感知状态，然后开始读写消息
+func (room *Room) setupConn(conn *Conn, pingInterval time.Duration) {
+	conn.pongReceived.Store(true)
+	conn.ws.SetPongHandler(func(string) error {
+		conn.pongReceived.Store(true)
+		return nil
+	})
+	go conn.writeLoop(pingInterval)
+
+	encoder := core.CreateEncoder()
+	encoder.WriteVarUint(MessageSync)
+	room.Transact(func(doc *struts.Doc) {
+		ysync.WriteSyncStep1(encoder, doc)
+	})
+	conn.Send(encoder.ToBytes())
+	states := room.Awareness.GetStates()
+	if len(states) > 0 {
+		clients := make([]int, 0, len(states))
+		for clientID := range states {
+			clients = append(clients, clientID)
+		}
+		encoder = core.CreateEncoder()
+		encoder.WriteVarUint(MessageAwareness)
+		encoder.WriteVarByteArray(awareness.EncodeAwarenessUpdate(room.Awareness, clients))
+		conn.Send(encoder.ToBytes())
+	}
+	go conn.readLoop()
+}
+
+// closeConn 移除连接并移除该连接控制的感知状态，最后一个连接离开时移除房间
+func (room *Room) closeConn(conn *Conn) {
+	room.connsMu.Lock()
+	controlledIDs, exists := room.conns[conn]
+	delete(room.conns, conn)
+	room.connsMu.Unlock()
+	if !exists {
+		return
+	}
+	clients := make([]int, 0, len(controlledIDs))
+	for clientID := range controlledIDs {
+		clients = append(clients, clientID)
+	}
+	awareness.RemoveAwarenessStates(room.Awareness, clients, nil)
+	room.server.removeRoom(room)
+}
+
+// handleMessage 处理客户端发送的一条消息，需要回复时发送给该连接
+func (room *Room) handleMessage(conn *Conn, message []byte) {
+	decoder := core.CreateDecoder(message)
+	switch decoder.ReadVarUint() {
+	case MessageSync:
+		encoder := core.CreateEncoder()
+		encoder.WriteVarUint(MessageSync)
+		room.Transact(func(doc *struts.Doc) {
+			ysync.ReadSyncMessage(decoder, encoder, doc, conn.peer)
+		})
+		// 只有消息类型时不需要回复
+		if encoder.Length() > 1 {
+			conn.Send(encoder.ToBytes())
+		}
+	case MessageAwareness:
+		awareness.ApplyAwarenessUpdate(room.Awareness, decoder.ReadVarUint8Array(), conn)
+	}
+}
+
+// Conn 一个客户端连接，写入只在单独的协程中进行
+type Conn struct {
+	ws           *websocket.Conn
+	room         *Room
+	peer         *ysync.Peer   // 应用该连接发送的更新时的事务来源
+	send         chan []byte   // 待发送的消息
+	done         chan struct{} // 关闭后停止写入
+	closeOnce    sync.Once
+	pongReceived atomic.Bool // 上次发送 ping 之后是否收到了 pong
+}
+
+// Send 将消息加入发送队列，队列已满说明连接已失效，此时关闭连接
+// 调用方可能持有文档锁，而关闭连接可能需要获取文档锁，所以在单独的协程中关闭
+func (c *Conn) Send(message []byte) {
+	select {
+	case <-c.done:
+	case c.send <- message:
+	default:
+		go c.Close()
+	}
+}
+
+// Close 关闭连接，可以多次调用
+func (c *Conn) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.ws.Close()
+		c.room.closeConn(c)
+	})
+}
+
+// readLoop 读取并处理客户端的消息，直到连接关闭
+func (c *Conn) readLoop() {
+	defer c.Close()
+	for {
+		messageType, message, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		if messageType != websocket.BinaryMessage {
+			continue
+		}
+		if !c.handleMessage(message) {
+			return
+		}
+	}
+}
+
+// handleMessage 处理一条消息，消息无法解析时返回 false
+func (c *Conn) handleMessage(message []byte) (ok bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Printf("[CollabEdit] 房间 %q 处理消息时出错: %v", c.room.Name, err)
+			ok = false
+		}
+	}()
+	c.room.handleMessage(c, message)
+	return true
+}
+
+// writeLoop 发送队列中的消息并定时发送 ping，上一个 ping 没有收到 pong 时关闭连接
+func (c *Conn) writeLoop(pingInterval time.Duration) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	defer c.Close()
+	for {
+		select {
+		case <-c.done:
+			return
+		case message := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.ws.WriteMessage(websocket.BinaryMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if !c.pongReceived.Swap(false) {
+				return
+			}
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package test
+
+import (
+	"CollabEdit/awareness"
+	"CollabEdit/core"
+	"CollabEdit/server"
+	"CollabEdit/struts"
+	ysync "CollabEdit/sync"
+	"CollabEdit/types"
+	"github.com/gorilla/websocket"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// client 与 y-websocket 客户端行为一致的测试客户端
+type client struct {
+	ws        *websocket.Conn
+	doc       *struts.Doc
+	awareness *awareness.Awareness
+	mu        sync.Mutex // 保护文档以及写入
+	updates   int        // 收到的 Update 消息数量
+}
+
+// connect 连接到服务器的 room 房间，并发送 SyncStep1 与本地的感知状态
+func connect(t *testing.T, s *httptest.Server, room string) *client {
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/"+room, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &client{ws: ws, doc: struts.NewDoc(nil)}
+	c.awareness = awareness.NewAwareness(c.doc)
+	t.Cleanup(func() {
+		ws.Close()
+		c.awareness.Destroy()
+	})
+	c.doc.On("update", func(args interface{}) {
+		event := args.(*struts.UpdateEvent)
+		if event.Origin != c {
+			encoder := core.CreateEncoder()
+			encoder.WriteVarUint(server.MessageSync)
+			ysync.WriteUpdate(encoder, event.Update)
+			c.write(encoder.ToBytes())
+		}
+	})
+	c.awareness.On("update", func(args interface{}) {
+		change := args.(*awareness.AwarenessChange)
+		if change.Origin == "local" {
+			c.sendAwareness(append(change.Added, append(change.Updated, change.Removed...)...))
+		}
+	})
+	go c.readLoop()
+	c.transact(func() {
+		encoder := core.CreateEncoder()
+		encoder.WriteVarUint(server.MessageSync)
+		ysync.WriteSyncStep1(encoder, c.doc)
+		c.write(encoder.ToBytes())
+	})
+	return c
+}
+
+// write 发送一条二进制消息，调用方需要持有 c.mu
+func (c *client) write(message []byte) {
+	c.ws.WriteMessage(websocket.BinaryMessage, message)
+}
+
+// transact 在持有锁时访问文档
+func (c *client) transact(f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f()
+}
+
+// sendAwareness 发送给定客户端的感知状态
+func (c *client) sendAwareness(clients []int) {
+	encoder := core.CreateEncoder()
+	encoder.WriteVarUint(server.MessageAwareness)
+	encoder.WriteVarByteArray(awareness.EncodeAwarenessUpdate(c.awareness, clients))
+	c.transact(func() { c.write(encoder.ToBytes()) })
+}
+
+// readLoop 处理服务器发送的消息，直到连接关闭
+func (c *client) readLoop() {
+	for {
+		_, message, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		decoder := core.CreateDecoder(message)
+		switch decoder.ReadVarUint() {
+		case server.MessageSync:
+			c.transact(func() {
+				encoder := core.CreateEncoder()
+				encoder.WriteVarUint(server.MessageSync)
+				if ysync.ReadSyncMessage(decoder, encoder, c.doc, c) == ysync.MessageYjsUpdate {
+					c.updates++
+				}
+				if encoder.Length() > 1 {
+					c.write(encoder.ToBytes())
+				}
+			})
+		case server.MessageAwareness:
+			awareness.ApplyAwarenessUpdate(c.awareness, decoder.ReadVarUint8Array(), c)
+		}
+	}
+}
+
+// text 返回客户端文档中的文本
+func (c *client) text() string {
+	var str string
+	c.transact(func() { str = types.GetText(c.doc, "text").ToString() })
+	return str
+}
+
+// insert 在客户端文档的文本中插入内容
+func (c *client) insert(index int, str string) {
+	c.transact(func() { types.GetText(c.doc, "text").Insert(index, str, nil) })
+}
+
+// eventually 等待条件成立
+func eventually(t *testing.T, message string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(message)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestServerSync(t *testing.T) {
+	s := httptest.NewServer(server.NewServer())
+	defer s.Close()
+	c1 := connect(t, s, "room")
+	c1.insert(0, "hello")
+	c2 := connect(t, s, "room")
+	eventually(t, "新的连接应同步到已有的内容", func() bool { return c2.text() == "hello" })
+	c2.insert(5, " world")
+	eventually(t, "修改应广播给其他连接", func() bool { return c1.text() == "hello world" })
+
+	// 更新不会发回给它的来源
+	c1.transact(func() { c1.updates = 0 })
+	c1.insert(0, ">")
+	eventually(t, "修改应广播给其他连接", func() bool { return c2.text() == ">hello world" })
+	time.Sleep(50 * time.Millisecond)
+	c1.transact(func() {
+		if c1.updates != 0 {
+			t.Errorf("更新不应发回给它的来源, 但收到了 %d 条", c1.updates)
+		}
+	})
+
+	// 不同房间的文档互不影响
+	c3 := connect(t, s, "other")
+	c3.insert(0, "other")
+	time.Sleep(50 * time.Millisecond)
+	if got := c1.text(); got != ">hello world" {
+		t.Errorf("其他房间的修改不应影响文档, 但得到 %q", got)
+	}
+}
+
+func TestServerRoomTransact(t *testing.T) {
+	srv := server.NewServer()
+	s := httptest.NewServer(srv)
+	defer s.Close()
+	c := connect(t, s, "room")
+	srv.GetRoom("room").Transact(func(doc *struts.Doc) {
+		types.GetText(doc, "text").Insert(0, "server", nil)
+	})
+	eventually(t, "服务器上的修改应广播给所有连接", func() bool { return c.text() == "server" })
+}
+
+func TestServerAwareness(t *testing.T) {
+	s := httptest.NewServer(server.NewServer())
+	defer s.Close()
+	c1 := connect(t, s, "room")
+	c1.awareness.SetLocalStateField("name", "a")
+	c2 := connect(t, s, "room")
+	state := func() interface{} {
+		state, _ := c2.awareness.GetStates()[c1.doc.ClientID].(map[string]interface{})
+		return state["name"]
+	}
+	eventually(t, "新的连接应收到已有的感知状态", func() bool { return state() == "a" })
+	c1.awareness.SetLocalStateField("name", "b")
+	eventually(t, "感知状态的变化应广播给其他连接", func() bool { return state() == "b" })
+
+	c1.ws.Close()
+	eventually(t, "连接关闭后应移除它控制的感知状态", func() bool {
+		_, exists := c2.awareness.GetStates()[c1.doc.ClientID]
+		return !exists
+	})
+}
+
+func TestServerPing(t *testing.T) {
+	srv := server.NewServer()
+	srv.PingInterval = 100 * time.Millisecond
+	s := httptest.NewServer(srv)
+	defer s.Close()
+	// 读取消息的客户端会自动回复 pong
+	connect(t, s, "alive")
+	// 不读取消息的客户端不会回复 pong
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/dead", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	eventually(t, "连接应加入房间", func() bool { return srv.HasRoom("dead") })
+	// 关闭最后一个连接会移除房间
+	eventually(t, "没有回复 pong 的连接应被关闭", func() bool { return !srv.HasRoom("dead") })
+	if srv.GetRoom("alive").ConnCount() != 1 {
+		t.Errorf("回复 pong 的连接不应被关闭")
+	}
+}
+
+func TestServerRoomRemoved(t *testing.T) {
+	srv := server.NewServer()
+	s := httptest.NewServer(srv)
+	defer s.Close()
+	c1 := connect(t, s, "room")
+	c2 := connect(t, s, "room")
+	c1.insert(0, "hello")
+	eventually(t, "两个连接都应加入房间", func() bool { return srv.HasRoom("room") && srv.GetRoom("room").ConnCount() == 2 })
+	room := srv.GetRoom("room")
+
+	c1.ws.Close()
+	eventually(t, "连接应离开房间", func() bool { return room.ConnCount() == 1 })
+	if !srv.HasRoom("room") {
+		t.Fatalf("房间中仍有连接时不应被移除")
+	}
+	c2.ws.Close()
+	eventually(t, "最后一个连接离开后应移除房间", func() bool { return !srv.HasRoom("room") })
+	eventually(t, "移除的房间中的文档应被销毁", func() bool {
+		destroyed := false
+		room.Transact(func(doc *struts.Doc) { destroyed = doc.IsDestroyed })
+		return destroyed
+	})
+
+	// 再次连接会创建新的房间
+	connect(t, s, "room")
+	eventually(t, "新的连接应加入新的房间", func() bool { return srv.HasRoom("room") })
+	if srv.GetRoom("room") == room {
+		t.Errorf("应创建新的房间")
+	}
+}
+
+// TestServerSlowReader 不读取消息的连接在发送缓冲区满时被关闭，修改文档不会因此死锁
+func TestServerSlowReader(t *testing.T) {
+	srv := server.NewServer()
+	s := httptest.NewServer(srv)
+	defer s.Close()
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/slow", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	eventually(t, "连接应加入房间", func() bool { return srv.HasRoom("slow") })
+	room := srv.GetRoom("slow")
+	chunk := strings.Repeat("x", 64*1024)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for room.ConnCount() > 0 {
+			room.Transact(func(doc *struts.Doc) {
+				types.GetText(doc, "text").Insert(0, chunk, nil)
+			})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("发送缓冲区已满时修改文档不应死锁")
+	}
+	eventually(t, "最后一个连接关闭后应移除房间", func() bool { return !srv.HasRoom("slow") })
+}
@@ -1,9 +1,9 @@
 package struts
 
 import (
-	"CollabEdit/types"
+	"CollabEdit/core"
 	"CollabEdit/util"
-	"fmt"
+	"reflect"
 )
 
 // 使用位移操作定义 BIT1
@@ -17,20 +17,22 @@ const BIT3 = 1 << 2
 
 type Item struct {
 	*AbstractStruct
-	Origin      *util.ID                    //最开始的元素
-	Left        *Item                       //左节点
-	Right       *Item                       //右节点
-	RightOrigin *util.ID                    //最右节点
-	Parent      types.AbstractTypeInterface //父节点
-	Marker      bool                        //是否标记
-	ParentSub   string                      //父子关系
-	Redone      *util.ID                    //重做
-	Content     AbstractContentInterface    //内容
-	Info        byte                        //信息
+	Origin      *util.ID                 //最开始的元素
+	Left        *Item                    //左节点
+	Right       *Item                    //右节点
+	RightOrigin *util.ID                 //最右节点
+	Parent      AbstractTypeInterface    //父节点
+	ParentID    *util.ID                 //解码后尚未解析的父类型项目ID
+	ParentKey   string                   //不解析父类型读取的顶层类型名称
+	Marker      bool                     //是否标记
+	ParentSub   string                   //父子关系
+	Redone      *util.ID                 //重做
+	Content     AbstractContentInterface //内容
+	Info        byte                     //信息
 }
 
 func NewItem(id *util.ID, left *Item, origin *util.ID,
-	right *Item, rightOrigin *util.ID, parent types.AbstractTypeInterface,
+	right *Item, rightOrigin *util.ID, parent AbstractTypeInterface,
 	parentSub string, content AbstractContentInterface) *Item {
 	info := 0
 	if content.IsCountable() {
@@ -60,6 +62,24 @@ func (i *Item) Countable() bool {
 	return (i.Info & BIT2) > 0
 }
 
+// Next 返回右侧第一个未删除的项目
+func (i *Item) Next() *Item {
+	n := i.Right
+	for n != nil && n.GetDeleted() {
+		n = n.Right
+	}
+	return n
+}
+
+// Prev 返回左侧第一个未删除的项目
+func (i *Item) Prev() *Item {
+	n := i.Left
+	for n != nil && n.GetDeleted() {
+		n = n.Left
+	}
+	return n
+}
+
 // LastId 方法返回此项的最后一个ID
 func (i *Item) LastId() *util.ID {
 	var id *util.ID
@@ -87,7 +107,15 @@ func (i *Item) SetKeep(doKeep bool) {
 	}
 }
 
-func (i *Item) Delete(transaction *util.Transaction) {
+// KeepItem 设置项目及其所有父类型项目的 keep 属性，keep 为 true 的项目不会被垃圾回收
+func KeepItem(item *Item, keep bool) {
+	for item != nil && item.Keep() != keep {
+		item.SetKeep(keep)
+		item = item.Parent.GetItem()
+	}
+}
+
+func (i *Item) Delete(transaction *Transaction) {
 	if !i.GetDeleted() {
 		parent := i.Parent
 		if i.Countable() && i.ParentSub == "" {
@@ -96,31 +124,164 @@ func (i *Item) Delete(transaction *util.Transaction) {
 			parent.SetLength(newLength)
 		}
 		i.MarkDeleted()
-		//TODO 需要完DeleteSet-addToDeleteSet
-		//util.AddToDeleteSet(transaction.DeleteSet,i.ID.Clock,i.Length)
-		//TODO 需要完Transaction-addChangedTypeToTransaction
-		//util.AddChangedTypeToTransaction(transaction, parent, i.ParentSub)
+		util.AddToDeleteSet(transaction.DeleteSet, i.ID.Client, i.ID.Clock, i.Length)
+		AddChangedTypeToTransaction(transaction, parent, i.ParentSub)
 		i.Content.Delete(transaction)
 	}
 }
 
+// parentItemOf 返回项目所在类型的项目，顶层类型返回 nil
+func parentItemOf(item *Item) *Item {
+	return item.Parent.GetItem()
+}
+
+// redoItem 重做被删除的项目，在原位置插入一个内容相同的新项目
+// 父类型也被删除时会先重做父类型，无法重做时返回 nil
+func redoItem(transaction *Transaction, item *Item, redoItems map[*Item]struct{}, itemsToDelete *util.DeleteSet, ignoreRemoteMapChanges bool) *Item {
+	doc := transaction.Doc
+	store := doc.Store
+	ownClientID := doc.ClientID
+	if item.Redone != nil {
+		return GetItemCleanStart(transaction, item.Redone)
+	}
+	parentItem := parentItemOf(item)
+	var left, right *Item
+	// 确保父类型已经被重做
+	if parentItem != nil && parentItem.GetDeleted() {
+		// 父类型也会被撤销时，先重做父类型
+		if parentItem.Redone == nil {
+			if _, ok := redoItems[parentItem]; !ok || redoItem(transaction, parentItem, redoItems, itemsToDelete, ignoreRemoteMapChanges) == nil {
+				return nil
+			}
+		}
+		for parentItem.Redone != nil {
+			parentItem = GetItemCleanStart(transaction, parentItem.Redone)
+		}
+	}
+	parentType := item.Parent
+	if parentItem != nil {
+		parentType = parentItem.Content.(*ContentType).Type
+	}
+
+	if item.ParentSub == "" {
+		// 数组中的项目，插入到原来的位置
+		left = item.Left
+		right = item
+		// 找到左侧第一个父类型相同的项目，必要时沿着 Redone 查找
+		for left != nil {
+			leftTrace := left
+			for leftTrace != nil && parentItemOf(leftTrace) != parentItem {
+				if leftTrace.Redone == nil {
+					leftTrace = nil
+				} else {
+					leftTrace = GetItemCleanStart(transaction, leftTrace.Redone)
+				}
+			}
+			if leftTrace != nil {
+				left = leftTrace
+				break
+			}
+			left = left.Left
+		}
+		for right != nil {
+			rightTrace := right
+			for rightTrace != nil && parentItemOf(rightTrace) != parentItem {
+				if rightTrace.Redone == nil {
+					rightTrace = nil
+				} else {
+					rightTrace = GetItemCleanStart(transaction, rightTrace.Redone)
+				}
+			}
+			if rightTrace != nil {
+				right = rightTrace
+				break
+			}
+			right = right.Right
+		}
+	} else {
+		right = nil
+		if item.Right != nil && !ignoreRemoteMapChanges {
+			left = item
+			// 右侧的值也会被删除或已经被重做时，当前项目可以替换它
+			for left != nil && left.Right != nil && (left.Right.Redone != nil || itemsToDelete.IsDeleted(left.Right.ID)) {
+				left = left.Right
+				for left.Redone != nil {
+					left = GetItemCleanStart(transaction, left.Redone)
+				}
+			}
+			if left != nil && left.Right != nil {
+				// 与其他客户端的修改冲突，无法重做
+				return nil
+			}
+		} else {
+			left = parentType.GetDataMap()[item.ParentSub]
+		}
+	}
+	nextID := util.NewID(ownClientID, GetState(store, ownClientID))
+	var origin, rightOrigin *util.ID
+	if left != nil {
+		origin = left.LastId()
+	}
+	if right != nil {
+		rightOrigin = right.ID
+	}
+	redoneItem := NewItem(nextID, left, origin, right, rightOrigin, parentType, item.ParentSub, item.Content.Copy())
+	item.Redone = nextID
+	KeepItem(redoneItem, true)
+	redoneItem.Integrate(transaction, 0)
+	return redoneItem
+}
+
 // GC 垃圾回收
-func (i *Item) GC(store *util.StructStore, parentGCd bool) {
+func (i *Item) GC(store *StructStore, parentGCd bool) {
 	if !i.GetDeleted() {
 		panic(util.ErrUnexpectedCase)
 	}
 	i.Content.Gc(store)
 	if parentGCd {
-		//TODO 需要完StructStore-replaceStruct
-		//ReplaceStruct(store, i,NewGC(i.ID, i.Length))
+		// 父类型已被回收，整个项目替换为 GC 结构
+		ReplaceStruct(store, i, NewGC(i.ID, i.Length))
 	} else {
-		//TODO 需要完ContentDeleted
-		//i.Content = NewContentDeleted(i.Length)
+		i.Content = NewContentDeleted(i.Length)
+	}
+}
+
+// MergeWith 尝试将右侧相邻的项目合并到当前项目中
+// 只有 ID 连续、左右关系一致并且内容类型相同的项目才能合并
+func (i *Item) MergeWith(right AbstractStructInterface) bool {
+	r, ok := right.(*Item)
+	if !ok {
+		return false
+	}
+	if util.CompareIDs(r.Origin, i.LastId()) &&
+		i.Right == r &&
+		util.CompareIDs(i.RightOrigin, r.RightOrigin) &&
+		i.ID.Client == r.ID.Client &&
+		i.ID.Clock+i.Length == r.ID.Clock &&
+		i.GetDeleted() == r.GetDeleted() &&
+		i.Redone == nil &&
+		r.Redone == nil &&
+		reflect.TypeOf(i.Content) == reflect.TypeOf(r.Content) &&
+		i.Content.MergeWith(r.Content) {
+		// right 将被丢弃，需要更新指向它的搜索标记
+		if owner, ok := i.Parent.(SearchMarkerOwner); ok && r.Marker {
+			owner.MoveSearchMarkers(i, r)
+		}
+		if r.Keep() {
+			i.SetKeep(true)
+		}
+		i.Right = r.Right
+		if i.Right != nil {
+			i.Right.Left = i
+		}
+		i.Length += r.Length
+		return true
 	}
+	return false
 }
 
 // SplitItem 将 leftItem 分割为两个项目
-func SplitItem(transaction *util.Transaction, leftItem *Item, diff int) *Item {
+func SplitItem(transaction *Transaction, leftItem *Item, diff int) *Item {
 	// 创建 rightItem
 	client := leftItem.ID.Client // 获取客户端标识符
 	clock := leftItem.ID.Clock   // 获取时钟标识符
@@ -159,113 +320,262 @@ func SplitItem(transaction *util.Transaction, leftItem *Item, diff int) *Item {
 	// 更新 parent._map
 	if rightItem.ParentSub != "" && rightItem.Right == nil {
 		// 如果 rightItem 有父子项且右侧项目为空
-		abstractStruct, ok := rightItem.Parent.(*types.AbstractType)
-		if !ok {
-			panic(fmt.Sprintf("parent is not AbstractStruct: %v", rightItem.Parent))
-		}
-		abstractStruct.DataMap[rightItem.ParentSub] = rightItem // 将 rightItem 设置到父类型的子项映射中
+		rightItem.Parent.GetDataMap()[rightItem.ParentSub] = rightItem // 将 rightItem 设置到父类型的子项映射中
 	}
 
 	leftItem.Length = diff // 更新 leftItem 的长度
 	return rightItem       // 返回 rightItem
 }
 
-// Integrate 整合项目
-//func (item *Item) Integrate(transaction *util.Transaction, offset int) {
-//	if offset > 0 { // 如果偏移量大于0
-//		item.ID.Clock += offset                                                                                      // 更新时钟
-//		item.Left = getItemCleanEnd(transaction, transaction.Doc.Store, util.NewID(item.ID.Client, item.ID.Clock-1)) // 获取左侧项目
-//		item.Origin = item.Left.ID                                                                                   // 更新起始标识符
-//		item.Content = item.Content.Splice(offset)                                                                   // 分割内容
-//		item.Length -= offset                                                                                        // 更新长度
-//	}
-//
-//	if item.Parent != nil { // 如果有父类型
-//		if (item.Left == nil && (item.Right == nil || item.Right.Left != nil)) || (item.Left != nil && item.Left.Right != item.Right) {
-//			// 判断左侧项目和右侧项目是否一致
-//			var left *Item = item.Left // 初始化左侧项目
-//			var o *Item                // 初始化中间项目
-//
-//			if left != nil { // 如果左侧项目不为空
-//				o = left.Right // 获取右侧项目
-//			} else if item.ParentSub != "" { // 如果有父子项
-//				o = item.Parent._map[item.ParentSub] // 从父类型中获取子项
-//				for o != nil && o.Left != nil {      // 如果中间项目不为空且有左侧项目
-//					o = o.Left // 获取左侧项目
-//				}
-//			} else {
-//				o = item.Parent._start // 获取父类型的起始项目
-//			}
-//
-//			conflictingItems := make(map[*Item]struct{})  // 初始化冲突项集合
-//			itemsBeforeOrigin := make(map[*Item]struct{}) // 初始化起始项前的项集合
-//
-//			for o != nil && o != item.Right { // 遍历右侧项目
-//				itemsBeforeOrigin[o] = struct{}{}           // 添加到起始项前的项集合
-//				conflictingItems[o] = struct{}{}            // 添加到冲突项集合
-//				if util.CompareIDs(item.Origin, o.Origin) { // 比较起始标识符
-//					if o.ID.Client < item.ID.Client { // 如果客户端ID小于当前项的客户端ID
-//						left = o                                    // 更新左侧项目
-//						conflictingItems = make(map[*Item]struct{}) // 清空冲突项集合
-//					} else if util.CompareIDs(item.RightOrigin, o.RightOrigin) { // 比较右侧起始标识符
-//						break // 终止循环
-//					}
-//				} else if o.Origin != nil {
-//					if _, ok := itemsBeforeOrigin[getItem(transaction.Doc.Store, o.Origin)]; ok { // 如果起始项前的项集合包含当前项的起始项
-//						if _, ok := conflictingItems[getItem(transaction.Doc.Store, o.Origin)]; !ok { // 如果冲突项集合不包含当前项的起始项
-//							left = o                                    // 更新左侧项目
-//							conflictingItems = make(map[*Item]struct{}) // 清空冲突项集合
-//						}
-//					}
-//				} else {
-//					break // 终止循环
-//				}
-//				o = o.Right // 更新右侧项目
-//			}
-//			item.Left = left // 更新左侧项目
-//		}
-//
-//		if item.Left != nil { // 如果左侧项目不为空
-//			right := item.Left.Right // 获取右侧项目
-//			item.Right = right       // 更新右侧项目
-//			item.Left.Right = item   // 更新左侧项目的右侧项目
-//		} else {
-//			var r *Item               // 初始化中间项目
-//			if item.ParentSub != "" { // 如果有父子项
-//				r = item.Parent._map[item.ParentSub] // 从父类型中获取子项
-//				for r != nil && r.Left != nil {      // 如果中间项目不为空且有左侧项目
-//					r = r.Left // 获取左侧项目
-//				}
-//			} else {
-//				r = item.Parent._start    // 获取父类型的起始项目
-//				item.Parent._start = item // 更新父类型的起始项目
-//			}
-//			item.Right = r // 更新右侧项目
-//		}
-//
-//		if item.Right != nil { // 如果右侧项目不为空
-//			item.Right.Left = item // 更新右侧项目的左侧项目
-//		} else if item.ParentSub != "" { // 如果有父子项
-//			item.Parent._map[item.ParentSub] = item // 更新父类型的子项
-//			if item.Left != nil {                   // 如果左侧项目不为空
-//				item.Left.Delete(transaction) // 删除左侧项目
-//			}
-//		}
-//
-//		if item.ParentSub == "" && item.Countable() && !item.GetDeleted() { // 如果没有父子项且可计数且未删除
-//			item.Parent._length += item.Length // 更新父类型的长度
-//		}
-//		addStruct(transaction.Doc.Store, item)                                                                      // 向存储中添加结构
-//		item.Content.Integrate(transaction, item)                                                                   // 整合内容
-//		addChangedTypeToTransaction(transaction, item.Parent, item.ParentSub)                                       // 将改变的类型添加到事务
-//		if (item.Parent._item != nil && item.Parent._item.deleted) || (item.ParentSub != "" && item.Right != nil) { // 如果父类型的项被删除或者有父子项且右侧项目不为空
-//			item.Delete(transaction) // 删除项目
-//		}
-//	} else {
-//		gc := &GC{id: item.Id, length: item.length} // 初始化垃圾回收
-//		gc.Integrate(transaction, 0)                // 整合垃圾回收
-//	}
-//}
+// Integrate 将项目整合到文档中
+// 使用 YATA 算法在左右 origin 之间寻找插入位置，保证各副本以任意顺序应用相同的项目后收敛
+func (i *Item) Integrate(transaction *Transaction, offset int) {
+	if offset > 0 { // 如果偏移量大于0，只整合项目的后半部分
+		i.ID.Clock += offset // 更新时钟
+		leftID := util.NewID(i.ID.Client, i.ID.Clock-1)
+		if _, ok := Find(transaction.Doc.Store, leftID).(*GC); ok {
+			// 左侧结构已被回收，项目的剩余部分也整合为 GC 结构
+			i.Left, i.Right, i.Parent = nil, nil, nil
+			i.Origin = leftID
+		} else {
+			i.Left = GetItemCleanEnd(transaction, transaction.Doc.Store, leftID) // 获取左侧项目
+			i.Origin = i.Left.LastId()                                           // 更新起始标识符
+		}
+		i.Content = i.Content.Splice(offset) // 分割内容
+		i.Length -= offset                   // 更新长度
+	}
+
+	if i.Parent != nil { // 如果有父类型
+		if (i.Left == nil && (i.Right == nil || i.Right.Left != nil)) || (i.Left != nil && i.Left.Right != i.Right) {
+			// 左右项目之间存在其他项目，需要解决冲突
+			left := i.Left // 初始化左侧项目
+			var o *Item    // 第一个冲突的项目
+
+			if left != nil { // 如果左侧项目不为空
+				o = left.Right
+			} else if i.ParentSub != "" { // 如果有父子项，从映射中最左侧的项目开始
+				o = i.Parent.GetDataMap()[i.ParentSub]
+				for o != nil && o.Left != nil {
+					o = o.Left
+				}
+			} else {
+				o = i.Parent.GetStart() // 获取父类型的起始项目
+			}
+
+			conflictingItems := make(map[*Item]struct{})  // 冲突项集合
+			itemsBeforeOrigin := make(map[*Item]struct{}) // 起始项前的项集合
+
+			for o != nil && o != i.Right {
+				itemsBeforeOrigin[o] = struct{}{}
+				conflictingItems[o] = struct{}{}
+				if util.CompareIDs(i.Origin, o.Origin) {
+					// 情况1：两者的左侧起始项相同，按客户端ID排序
+					if o.ID.Client < i.ID.Client {
+						left = o
+						conflictingItems = make(map[*Item]struct{})
+					} else if util.CompareIDs(i.RightOrigin, o.RightOrigin) {
+						break
+					} // 否则 o 可能被整合在与当前项冲突的项之前，在后续迭代中处理
+				} else if o.Origin != nil {
+					// 情况2：o 的起始项在当前项的起始项之后
+					// 使用 GetItem 而不是 GetItemCleanEnd，因为这里不需要分割项目
+					originItem, _ := GetItem(transaction.Doc.Store, o.Origin).(*Item)
+					if _, ok := itemsBeforeOrigin[originItem]; !ok {
+						break
+					}
+					if _, ok := conflictingItems[originItem]; !ok {
+						left = o
+						conflictingItems = make(map[*Item]struct{})
+					}
+				} else {
+					break
+				}
+				o = o.Right
+			}
+			i.Left = left
+		}
+
+		// 重新连接左右项目，必要时更新父类型的映射和起始项目
+		if i.Left != nil {
+			right := i.Left.Right
+			i.Right = right
+			i.Left.Right = i
+		} else {
+			var r *Item
+			if i.ParentSub != "" {
+				r = i.Parent.GetDataMap()[i.ParentSub]
+				for r != nil && r.Left != nil {
+					r = r.Left
+				}
+			} else {
+				r = i.Parent.GetStart()
+				i.Parent.SetStart(i)
+			}
+			i.Right = r
+		}
+
+		if i.Right != nil {
+			i.Right.Left = i
+		} else if i.ParentSub != "" {
+			// 右侧没有项目时，当前项目就是父类型映射中的最新值
+			i.Parent.GetDataMap()[i.ParentSub] = i
+			if i.Left != nil {
+				// 删除被覆盖的旧值
+				i.Left.Delete(transaction)
+			}
+		}
+
+		// 调整父类型的长度
+		if i.ParentSub == "" && i.Countable() && !i.GetDeleted() {
+			i.Parent.SetLength(i.Parent.GetLength() + i.Length)
+		}
+		AddStruct(transaction.Doc.Store, i)
+		i.Content.Integrate(transaction, i)
+		AddChangedTypeToTransaction(transaction, i.Parent, i.ParentSub)
+		parentItem := i.Parent.GetItem()
+		if (parentItem != nil && parentItem.GetDeleted()) || (i.ParentSub != "" && i.Right != nil) {
+			// 父类型已被删除，或当前项目不是映射中的最新值，则删除当前项目
+			i.Delete(transaction)
+		}
+	} else {
+		// 父类型不存在，改为整合 GC 结构
+		NewGC(i.ID, i.Length).Integrate(transaction, 0)
+	}
+}
+
+// GetMissing 检查项目依赖的结构是否都已存在，缺失时返回缺失结构的客户端ID
+// 依赖齐全时解析左右项目与父类型，返回 -1
+func (i *Item) GetMissing(transaction *Transaction, store *StructStore) int {
+	if i.Origin != nil && i.Origin.Client != i.ID.Client && i.Origin.Clock >= GetState(store, i.Origin.Client) {
+		return i.Origin.Client
+	}
+	if i.RightOrigin != nil && i.RightOrigin.Client != i.ID.Client && i.RightOrigin.Clock >= GetState(store, i.RightOrigin.Client) {
+		return i.RightOrigin.Client
+	}
+	if i.ParentID != nil && i.ID.Client != i.ParentID.Client && i.ParentID.Clock >= GetState(store, i.ParentID.Client) {
+		return i.ParentID.Client
+	}
+	// 依赖齐全，查找左右项目
+	gcNeighbour := false
+	if i.Origin != nil {
+		if _, ok := Find(store, i.Origin).(*GC); ok {
+			gcNeighbour = true
+		} else {
+			i.Left = GetItemCleanEnd(transaction, store, i.Origin)
+			i.Origin = i.Left.LastId()
+		}
+	}
+	if i.RightOrigin != nil {
+		if _, ok := Find(store, i.RightOrigin).(*GC); ok {
+			gcNeighbour = true
+		} else {
+			i.Right = GetItemCleanStart(transaction, i.RightOrigin)
+			i.RightOrigin = i.Right.ID
+		}
+	}
+	if gcNeighbour {
+		// 相邻的结构已被回收，此项目也会被整合为 GC 结构
+		i.Parent = nil
+		i.Left = nil
+		i.Right = nil
+	} else if i.Parent == nil && i.ParentID == nil {
+		// 只有不会被回收的项目才需要设置父类型
+		if i.Left != nil {
+			i.Parent = i.Left.Parent
+			i.ParentSub = i.Left.ParentSub
+		} else if i.Right != nil {
+			i.Parent = i.Right.Parent
+			i.ParentSub = i.Right.ParentSub
+		}
+	} else if i.ParentID != nil {
+		if parentItem, ok := GetItem(store, i.ParentID).(*Item); ok {
+			if content, ok := parentItem.Content.(*ContentType); ok {
+				i.Parent = content.Type
+			}
+		}
+	}
+	i.ParentID = nil
+	return -1
+}
+
+// Write 将项目写入编码器，offset 大于 0 时只写入项目的后半部分
+func (i *Item) Write(encoder util.UpdateEncoderInterface, offset int) {
+	origin := i.Origin
+	if offset > 0 {
+		origin = util.NewID(i.ID.Client, i.ID.Clock+offset-1)
+	}
+	info := i.Content.GetRef() & core.BITS5
+	if origin != nil {
+		info |= core.BIT8
+	}
+	if i.RightOrigin != nil {
+		info |= core.BIT7
+	}
+	if i.ParentSub != "" {
+		info |= core.BIT6
+	}
+	encoder.WriteInfo(byte(info))
+	if origin != nil {
+		encoder.WriteLeftID(*origin)
+	}
+	if i.RightOrigin != nil {
+		encoder.WriteRightID(*i.RightOrigin)
+	}
+	if origin == nil && i.RightOrigin == nil {
+		// 没有左右依赖时需要写入父类型信息
+		if i.Parent != nil {
+			if parentItem := i.Parent.GetItem(); parentItem == nil {
+				encoder.WriteParentInfo(true)
+				encoder.WriteString(findRootTypeKey(i.Parent))
+			} else {
+				encoder.WriteParentInfo(false)
+				encoder.WriteLeftID(*parentItem.ID)
+			}
+		} else if i.ParentKey != "" {
+			encoder.WriteParentInfo(true)
+			encoder.WriteString(i.ParentKey)
+		} else if i.ParentID != nil {
+			encoder.WriteParentInfo(false)
+			encoder.WriteLeftID(*i.ParentID)
+		} else {
+			panic(util.ErrUnexpectedCase)
+		}
+		if i.ParentSub != "" {
+			encoder.WriteString(i.ParentSub)
+		}
+	}
+	i.Content.Write(encoder, offset)
+}
+
+// findRootTypeKey 查找顶层类型在文档中的名称
+func findRootTypeKey(t AbstractTypeInterface) string {
+	for key, value := range t.GetDoc().Share {
+		if value == t {
+			return key
+		}
+	}
+	panic(util.ErrUnexpectedCase)
+}
+
+// contentRefs 内容引用编号到读取函数的映射，编号 0 保留给 GC，10 保留给 Skip
+var contentRefs = []func(decoder util.UpdateDecoderInterface) AbstractContentInterface{
+	func(decoder util.UpdateDecoderInterface) AbstractContentInterface { panic(util.ErrUnexpectedCase) },
+	ReadContentDeleted,
+	ReadContentJSON,
+	ReadContentBinary,
+	ReadContentString,
+	ReadContentEmbed,
+	ReadContentFormat,
+	ReadContentType,
+	ReadContentAny,
+	ReadContentDoc,
+	func(decoder util.UpdateDecoderInterface) AbstractContentInterface { panic(util.ErrUnexpectedCase) },
+}
+
+// ReadItemContent 根据信息位中的内容引用读取项目内容
+func ReadItemContent(decoder util.UpdateDecoderInterface, info byte) AbstractContentInterface {
+	return contentRefs[info&core.BITS5](decoder)
+}
 
 type AbstractContentInterface interface {
 	GetLength() int
@@ -274,9 +584,9 @@ type AbstractContentInterface interface {
 	Copy() AbstractContentInterface
 	Splice(offset int) AbstractContentInterface
 	MergeWith(right AbstractContentInterface) bool
-	Integrate(transaction *util.Transaction, item *Item)
-	Delete(transaction *util.Transaction)
-	Gc(store *util.StructStore)
+	Integrate(transaction *Transaction, item *Item)
+	Delete(transaction *Transaction)
+	Gc(store *StructStore)
 	Write(encoder util.EncoderInterface, offset int)
 	GetRef() int
 }
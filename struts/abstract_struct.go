@@ -5,12 +5,12 @@ import (
 )
 
 type AbstractStructInterface interface {
-	GetID() *util.ID                                                  //获取ID
-	GetLength() int                                                   //获取长度
-	GetDeleted() bool                                                 //删除
-	MergeWith(right *AbstractStruct) bool                             //合并
-	Write(encoder util.EncoderInterface, offset int, encodingRef int) //写入
-	Integrate(transaction *util.Transaction, offset int)              //整合
+	GetID() *util.ID                                       //获取ID
+	GetLength() int                                        //获取长度
+	GetDeleted() bool                                      //删除
+	MergeWith(right AbstractStructInterface) bool          //合并
+	Write(encoder util.UpdateEncoderInterface, offset int) //写入
+	Integrate(transaction *Transaction, offset int)        //整合
 }
 
 type AbstractStruct struct {
@@ -44,16 +44,16 @@ func (a *AbstractStruct) GetDeleted() bool {
 // MergeWith 将当前结构与右侧的项合并
 // 该方法假设`this.Id.Clock + this.Length === right.Id.Clock`
 // 该方法不会从StructStore中移除right!
-func (a *AbstractStruct) MergeWith(right *AbstractStruct) bool {
+func (a *AbstractStruct) MergeWith(right AbstractStructInterface) bool {
 	return false
 }
 
 // Write 将数据写入编码器
-func (a *AbstractStruct) Write(encoder util.EncoderInterface, offset int, encodingRef int) {
+func (a *AbstractStruct) Write(encoder util.UpdateEncoderInterface, offset int) {
 	panic(util.ErrMethodUnimplemented)
 }
 
 // Integrate 将结构整合到事务中
-func (a *AbstractStruct) Integrate(transaction *util.Transaction, offset int) {
+func (a *AbstractStruct) Integrate(transaction *Transaction, offset int) {
 	panic(util.ErrMethodUnimplemented)
 }
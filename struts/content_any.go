@@ -15,6 +15,16 @@ func NewContentAny(arr []interface{}) *ContentAny {
 	}
 }
 
+// ReadContentAny 从解码器读取任意内容
+func ReadContentAny(decoder util.UpdateDecoderInterface) AbstractContentInterface {
+	length := decoder.ReadLen()
+	cs := make([]interface{}, 0, length)
+	for i := 0; i < length; i++ {
+		cs = append(cs, decoder.ReadAny())
+	}
+	return NewContentAny(cs)
+}
+
 func (c *ContentAny) GetLength() int {
 	return len(c.Arr)
 }
@@ -45,15 +55,15 @@ func (c *ContentAny) MergeWith(right AbstractContentInterface) bool {
 	return false
 }
 
-func (c *ContentAny) Integrate(transaction *util.Transaction, item *Item) {
+func (c *ContentAny) Integrate(transaction *Transaction, item *Item) {
 	// 实现逻辑
 }
 
-func (c *ContentAny) Delete(transaction *util.Transaction) {
+func (c *ContentAny) Delete(transaction *Transaction) {
 	// 实现逻辑
 }
 
-func (c *ContentAny) Gc(store *util.StructStore) {
+func (c *ContentAny) Gc(store *StructStore) {
 	// 实现逻辑
 }
 
@@ -15,16 +15,17 @@ func NewContentBinary(arr []byte) *ContentBinary {
 	}
 }
 
+// ReadContentBinary 从解码器读取二进制内容
+func ReadContentBinary(decoder util.UpdateDecoderInterface) AbstractContentInterface {
+	return NewContentBinary(decoder.ReadBuf())
+}
+
 func (c *ContentBinary) GetLength() int {
 	return 1
 }
 
 func (c *ContentBinary) GetContent() []interface{} {
-	result := make([]interface{}, len(c.Arr))
-	for i, v := range c.Arr {
-		result[i] = v
-	}
-	return result
+	return []interface{}{c.Arr}
 }
 
 func (c *ContentBinary) IsCountable() bool {
@@ -43,15 +44,15 @@ func (c *ContentBinary) MergeWith(right AbstractContentInterface) bool {
 	return false
 }
 
-func (c *ContentBinary) Integrate(transaction *util.Transaction, item *Item) {
+func (c *ContentBinary) Integrate(transaction *Transaction, item *Item) {
 	// 实现逻辑
 }
 
-func (c *ContentBinary) Delete(transaction *util.Transaction) {
+func (c *ContentBinary) Delete(transaction *Transaction) {
 	// 实现逻辑
 }
 
-func (c *ContentBinary) Gc(store *util.StructStore) {
+func (c *ContentBinary) Gc(store *StructStore) {
 	// 实现逻辑
 }
 
@@ -0,0 +1,73 @@
+package struts
+
+import (
+	"CollabEdit/util"
+)
+
+type ContentDeleted struct {
+	AbstractContentInterface
+	Len int
+}
+
+// NewContentDeleted 创建已删除内容，只记录被删除内容的长度
+func NewContentDeleted(length int) *ContentDeleted {
+	return &ContentDeleted{
+		Len: length,
+	}
+}
+
+// ReadContentDeleted 从解码器读取已删除内容
+func ReadContentDeleted(decoder util.UpdateDecoderInterface) AbstractContentInterface {
+	return NewContentDeleted(decoder.ReadLen())
+}
+
+func (c *ContentDeleted) GetLength() int {
+	return c.Len
+}
+
+func (c *ContentDeleted) GetContent() []interface{} {
+	return []interface{}{}
+}
+
+func (c *ContentDeleted) IsCountable() bool {
+	return false
+}
+
+func (c *ContentDeleted) Copy() AbstractContentInterface {
+	return NewContentDeleted(c.Len)
+}
+
+func (c *ContentDeleted) Splice(offset int) AbstractContentInterface {
+	right := NewContentDeleted(c.Len - offset)
+	c.Len = offset
+	return right
+}
+
+func (c *ContentDeleted) MergeWith(right AbstractContentInterface) bool {
+	if r, ok := right.(*ContentDeleted); ok {
+		c.Len += r.Len
+		return true
+	}
+	return false
+}
+
+func (c *ContentDeleted) Integrate(transaction *Transaction, item *Item) {
+	util.AddToDeleteSet(transaction.DeleteSet, item.ID.Client, item.ID.Clock, c.Len)
+	item.MarkDeleted()
+}
+
+func (c *ContentDeleted) Delete(transaction *Transaction) {
+	// 实现逻辑
+}
+
+func (c *ContentDeleted) Gc(store *StructStore) {
+	// 实现逻辑
+}
+
+func (c *ContentDeleted) Write(encoder util.EncoderInterface, offset int) {
+	encoder.WriteLen(c.Len - offset)
+}
+
+func (c *ContentDeleted) GetRef() int {
+	return 1
+}
@@ -4,20 +4,23 @@ import (
 	"CollabEdit/util"
 )
 
-func createDocFromOpt(guid string, opt *util.DocOpts) *util.Doc {
-	var op util.DocOpts = *opt
+// createDocFromOpt 根据子文档的选项创建文档，自动加载的子文档会被立即加载
+func createDocFromOpt(guid string, opt *DocOpts) *Doc {
+	var op DocOpts = *opt
 	op.Guid = guid
 	op.ShouldLoad = (op.ShouldLoad || op.AutoLoad || false)
-	return util.NewDoc(&op)
+	return NewDoc(&op)
 }
 
+// ContentDoc 子文档内容
 type ContentDoc struct {
 	AbstractContentInterface
-	Doc  *util.Doc
-	Opts *util.DocOpts
+	Doc  *Doc
+	Opts *DocOpts
 }
 
-func NewContentDoc(doc *util.Doc) *ContentDoc {
+// NewContentDoc 创建子文档内容，一份文档只能作为一个子文档
+func NewContentDoc(doc *Doc) *ContentDoc {
 	if doc == nil {
 		panic(util.ErrParamUnimplemented)
 	}
@@ -25,10 +28,8 @@ func NewContentDoc(doc *util.Doc) *ContentDoc {
 		panic("这份文档已经被合并为子文档。您应该创建第二个实例，而不是使用相同的 GUID。")
 	}
 
-	var ops = &util.DocOpts{}
-
-	if !doc.Gc {
-		ops.GC = false
+	var ops = &DocOpts{
+		GC: doc.Gc,
 	}
 
 	if doc.AutoLoad {
@@ -45,6 +46,37 @@ func NewContentDoc(doc *util.Doc) *ContentDoc {
 	}
 }
 
+// ReadContentDoc 从解码器读取子文档内容
+func ReadContentDoc(decoder util.UpdateDecoderInterface) AbstractContentInterface {
+	guid := decoder.ReadString()
+	opts := &DocOpts{GC: true}
+	if m, ok := decoder.ReadAny().(map[string]interface{}); ok {
+		if gc, ok := m["gc"].(bool); ok {
+			opts.GC = gc
+		}
+		if autoLoad, ok := m["autoLoad"].(bool); ok {
+			opts.AutoLoad = autoLoad
+		}
+		opts.Meta = m["meta"]
+	}
+	return NewContentDoc(createDocFromOpt(guid, opts))
+}
+
+// encodeOpts 将选项转换为与 Yjs 一致的对象，只包含非默认值
+func (c *ContentDoc) encodeOpts() map[string]interface{} {
+	opts := make(map[string]interface{})
+	if !c.Opts.GC {
+		opts["gc"] = false
+	}
+	if c.Opts.AutoLoad {
+		opts["autoLoad"] = true
+	}
+	if c.Opts.Meta != nil {
+		opts["meta"] = c.Opts.Meta
+	}
+	return opts
+}
+
 func (c *ContentDoc) GetLength() int {
 	return 1
 }
@@ -72,8 +104,8 @@ func (c *ContentDoc) MergeWith(right AbstractContentInterface) bool {
 	return false
 }
 
-func (c *ContentDoc) Integrate(transaction *util.Transaction, item *Item) {
-	// 实现逻辑
+// Integrate 将子文档关联到项目，并在事务中记录新增的子文档
+func (c *ContentDoc) Integrate(transaction *Transaction, item *Item) {
 	c.Doc.Item = item
 	transaction.SubDocsAdded[c.Doc] = struct{}{}
 	if c.Doc.ShouldLoad {
@@ -81,8 +113,8 @@ func (c *ContentDoc) Integrate(transaction *util.Transaction, item *Item) {
 	}
 }
 
-func (c *ContentDoc) Delete(transaction *util.Transaction) {
-	// 实现逻辑
+// Delete 在事务中记录删除的子文档，同一事务中新增的子文档直接撤销记录
+func (c *ContentDoc) Delete(transaction *Transaction) {
 	doc := c.Doc
 	_, exists := transaction.SubDocsAdded[doc]
 	if exists {
@@ -92,14 +124,11 @@ func (c *ContentDoc) Delete(transaction *util.Transaction) {
 	}
 }
 
-func (c *ContentDoc) Gc(store *util.StructStore) {
-	// 实现逻辑
-}
+func (c *ContentDoc) Gc(store *StructStore) {}
 
 func (c *ContentDoc) Write(encoder util.EncoderInterface, offset int) {
-	// 实现逻辑
 	encoder.WriteString(c.Doc.Guid)
-	encoder.WriteAny(c.Opts)
+	encoder.WriteAny(c.encodeOpts())
 }
 
 func (c *ContentDoc) GetRef() int {
@@ -0,0 +1,66 @@
+package struts
+
+import (
+	"CollabEdit/util"
+)
+
+// ContentEmbed 文本中嵌入的对象
+type ContentEmbed struct {
+	AbstractContentInterface
+	Embed interface{}
+}
+
+func NewContentEmbed(embed interface{}) *ContentEmbed {
+	return &ContentEmbed{
+		Embed: embed,
+	}
+}
+
+// ReadContentEmbed 从解码器读取嵌入内容
+func ReadContentEmbed(decoder util.UpdateDecoderInterface) AbstractContentInterface {
+	return NewContentEmbed(decoder.ReadJSON())
+}
+
+func (c *ContentEmbed) GetLength() int {
+	return 1
+}
+
+func (c *ContentEmbed) GetContent() []interface{} {
+	return []interface{}{c.Embed}
+}
+
+func (c *ContentEmbed) IsCountable() bool {
+	return true
+}
+
+func (c *ContentEmbed) Copy() AbstractContentInterface {
+	return NewContentEmbed(c.Embed)
+}
+
+func (c *ContentEmbed) Splice(offset int) AbstractContentInterface {
+	panic(util.ErrMethodUnimplemented)
+}
+
+func (c *ContentEmbed) MergeWith(right AbstractContentInterface) bool {
+	return false
+}
+
+func (c *ContentEmbed) Integrate(transaction *Transaction, item *Item) {
+	// 实现逻辑
+}
+
+func (c *ContentEmbed) Delete(transaction *Transaction) {
+	// 实现逻辑
+}
+
+func (c *ContentEmbed) Gc(store *StructStore) {
+	// 实现逻辑
+}
+
+func (c *ContentEmbed) Write(encoder util.EncoderInterface, offset int) {
+	encoder.WriteJSON(c.Embed)
+}
+
+func (c *ContentEmbed) GetRef() int {
+	return 5
+}
@@ -0,0 +1,76 @@
+package struts
+
+import (
+	"CollabEdit/util"
+)
+
+// ContentFormat 富文本的格式化属性，不计入类型长度
+type ContentFormat struct {
+	AbstractContentInterface
+	Key   string
+	Value interface{}
+}
+
+func NewContentFormat(key string, value interface{}) *ContentFormat {
+	return &ContentFormat{
+		Key:   key,
+		Value: value,
+	}
+}
+
+// ReadContentFormat 从解码器读取格式化内容
+func ReadContentFormat(decoder util.UpdateDecoderInterface) AbstractContentInterface {
+	key := decoder.ReadKey()
+	return NewContentFormat(key, decoder.ReadJSON())
+}
+
+func (c *ContentFormat) GetLength() int {
+	return 1
+}
+
+func (c *ContentFormat) GetContent() []interface{} {
+	return []interface{}{}
+}
+
+func (c *ContentFormat) IsCountable() bool {
+	return false
+}
+
+func (c *ContentFormat) Copy() AbstractContentInterface {
+	return NewContentFormat(c.Key, c.Value)
+}
+
+func (c *ContentFormat) Splice(offset int) AbstractContentInterface {
+	panic(util.ErrMethodUnimplemented)
+}
+
+func (c *ContentFormat) MergeWith(right AbstractContentInterface) bool {
+	return false
+}
+
+func (c *ContentFormat) Integrate(transaction *Transaction, item *Item) {
+	// 格式化属性会影响搜索标记所记录的位置，需要清空
+	if owner, ok := item.Parent.(SearchMarkerOwner); ok {
+		owner.ClearSearchMarkers()
+	}
+	if owner, ok := item.Parent.(FormattingOwner); ok {
+		owner.SetHasFormatting(true)
+	}
+}
+
+func (c *ContentFormat) Delete(transaction *Transaction) {
+	// 实现逻辑
+}
+
+func (c *ContentFormat) Gc(store *StructStore) {
+	// 实现逻辑
+}
+
+func (c *ContentFormat) Write(encoder util.EncoderInterface, offset int) {
+	encoder.WriteKey(c.Key)
+	encoder.WriteJSON(c.Value)
+}
+
+func (c *ContentFormat) GetRef() int {
+	return 6
+}
@@ -0,0 +1,99 @@
+package struts
+
+import (
+	"CollabEdit/util"
+	"encoding/json"
+)
+
+// ContentJSON 旧版本的 JSON 内容，新的数据应使用 ContentAny
+type ContentJSON struct {
+	AbstractContentInterface
+	Arr []interface{}
+}
+
+func NewContentJSON(arr []interface{}) *ContentJSON {
+	return &ContentJSON{
+		Arr: arr,
+	}
+}
+
+// ReadContentJSON 从解码器读取 JSON 内容，"undefined" 被解码为 nil
+func ReadContentJSON(decoder util.UpdateDecoderInterface) AbstractContentInterface {
+	length := decoder.ReadLen()
+	cs := make([]interface{}, 0, length)
+	for i := 0; i < length; i++ {
+		c := decoder.ReadString()
+		if c == "undefined" {
+			cs = append(cs, nil)
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(c), &v); err != nil {
+			panic(err)
+		}
+		cs = append(cs, v)
+	}
+	return NewContentJSON(cs)
+}
+
+func (c *ContentJSON) GetLength() int {
+	return len(c.Arr)
+}
+
+func (c *ContentJSON) GetContent() []interface{} {
+	return c.Arr
+}
+
+func (c *ContentJSON) IsCountable() bool {
+	return true
+}
+
+func (c *ContentJSON) Copy() AbstractContentInterface {
+	return NewContentJSON(c.Arr)
+}
+
+func (c *ContentJSON) Splice(offset int) AbstractContentInterface {
+	right := NewContentJSON(c.Arr[offset:])
+	c.Arr = c.Arr[:offset]
+	return right
+}
+
+func (c *ContentJSON) MergeWith(right AbstractContentInterface) bool {
+	if r, ok := right.(*ContentJSON); ok {
+		c.Arr = append(c.Arr, r.Arr...)
+		return true
+	}
+	return false
+}
+
+func (c *ContentJSON) Integrate(transaction *Transaction, item *Item) {
+	// 实现逻辑
+}
+
+func (c *ContentJSON) Delete(transaction *Transaction) {
+	// 实现逻辑
+}
+
+func (c *ContentJSON) Gc(store *StructStore) {
+	// 实现逻辑
+}
+
+func (c *ContentJSON) Write(encoder util.EncoderInterface, offset int) {
+	length := len(c.Arr)
+	encoder.WriteLen(length - offset)
+	for i := offset; i < length; i++ {
+		if c.Arr[i] == nil {
+			encoder.WriteString("undefined")
+			continue
+		}
+		data, err := json.Marshal(c.Arr[i])
+		if err != nil {
+			panic(err)
+		}
+		encoder.WriteString(string(data))
+	}
+}
+
+func (c *ContentJSON) GetRef() int {
+	return 2
+}
@@ -0,0 +1,105 @@
+package struts
+
+import (
+	"CollabEdit/util"
+	"unicode/utf16"
+)
+
+// ContentString 文本内容，长度与偏移量均以 UTF-16 码元计算，与 Yjs 保持一致
+type ContentString struct {
+	AbstractContentInterface
+	Str string
+}
+
+func NewContentString(str string) *ContentString {
+	return &ContentString{
+		Str: str,
+	}
+}
+
+// ReadContentString 从解码器读取文本内容
+func ReadContentString(decoder util.UpdateDecoderInterface) AbstractContentInterface {
+	return NewContentString(decoder.ReadString())
+}
+
+// StringLength 计算字符串的 UTF-16 长度
+func StringLength(str string) int {
+	length := 0
+	for _, r := range str {
+		if r >= 0x10000 {
+			length += 2
+		} else {
+			length++
+		}
+	}
+	return length
+}
+
+// SliceString 按 UTF-16 偏移量截取字符串，被截断的代理对会被替换为 U+FFFD
+func SliceString(str string, start int, end int) string {
+	units := utf16.Encode([]rune(str))
+	return string(utf16.Decode(units[start:end]))
+}
+
+func (c *ContentString) GetLength() int {
+	return StringLength(c.Str)
+}
+
+// GetContent 每个 UTF-16 码元对应一个元素，代理对的完整字符放在第一个位置，第二个位置为空字符串
+func (c *ContentString) GetContent() []interface{} {
+	result := make([]interface{}, 0, len(c.Str))
+	for _, r := range c.Str {
+		result = append(result, string(r))
+		if r >= 0x10000 {
+			result = append(result, "")
+		}
+	}
+	return result
+}
+
+func (c *ContentString) IsCountable() bool {
+	return true
+}
+
+func (c *ContentString) Copy() AbstractContentInterface {
+	return NewContentString(c.Str)
+}
+
+func (c *ContentString) Splice(offset int) AbstractContentInterface {
+	units := utf16.Encode([]rune(c.Str))
+	right := NewContentString(string(utf16.Decode(units[offset:])))
+	c.Str = string(utf16.Decode(units[:offset]))
+	return right
+}
+
+func (c *ContentString) MergeWith(right AbstractContentInterface) bool {
+	if r, ok := right.(*ContentString); ok {
+		c.Str += r.Str
+		return true
+	}
+	return false
+}
+
+func (c *ContentString) Integrate(transaction *Transaction, item *Item) {
+	// 实现逻辑
+}
+
+func (c *ContentString) Delete(transaction *Transaction) {
+	// 实现逻辑
+}
+
+func (c *ContentString) Gc(store *StructStore) {
+	// 实现逻辑
+}
+
+func (c *ContentString) Write(encoder util.EncoderInterface, offset int) {
+	if offset == 0 {
+		encoder.WriteString(c.Str)
+	} else {
+		encoder.WriteString(SliceString(c.Str, offset, c.GetLength()))
+	}
+}
+
+func (c *ContentString) GetRef() int {
+	return 4
+}
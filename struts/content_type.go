@@ -1,16 +1,43 @@
 package struts
 
 import (
-	"CollabEdit/types"
 	"CollabEdit/util"
 )
 
 type ContentType struct {
 	AbstractContentInterface
-	Type types.AbstractTypeInterface
+	Type AbstractTypeInterface
 }
 
-func NewContentType(t types.AbstractTypeInterface) *ContentType {
+// 类型引用编号，与 Yjs 保持一致
+const (
+	YArrayRefID       = 0
+	YMapRefID         = 1
+	YTextRefID        = 2
+	YXmlElementRefID  = 3
+	YXmlFragmentRefID = 4
+	YXmlHookRefID     = 5
+	YXmlTextRefID     = 6
+)
+
+// typeRefs 类型引用编号到读取函数的映射，由具体类型所在的包注册
+var typeRefs = make(map[int]func(decoder util.UpdateDecoderInterface) AbstractTypeInterface)
+
+// RegisterTypeRef 注册类型引用对应的读取函数
+func RegisterTypeRef(ref int, reader func(decoder util.UpdateDecoderInterface) AbstractTypeInterface) {
+	typeRefs[ref] = reader
+}
+
+// ReadContentType 从解码器读取类型内容
+func ReadContentType(decoder util.UpdateDecoderInterface) AbstractContentInterface {
+	reader, ok := typeRefs[decoder.ReadTypeRef()]
+	if !ok {
+		panic(util.ErrUnexpectedCase)
+	}
+	return NewContentType(reader(decoder))
+}
+
+func NewContentType(t AbstractTypeInterface) *ContentType {
 	return &ContentType{
 		Type: t,
 	}
@@ -42,12 +69,12 @@ func (c *ContentType) MergeWith(right AbstractContentInterface) bool {
 	return false
 }
 
-func (c *ContentType) Integrate(transaction *util.Transaction, item *Item) {
+func (c *ContentType) Integrate(transaction *Transaction, item *Item) {
 	// 实现逻辑
 	c.Type.Integrate(transaction.Doc, item)
 }
 
-func (c *ContentType) Delete(transaction *util.Transaction) {
+func (c *ContentType) Delete(transaction *Transaction) {
 	// 实现逻辑
 	item := c.Type.GetStart()
 	for item != nil {
@@ -69,19 +96,15 @@ func (c *ContentType) Delete(transaction *util.Transaction) {
 	delete(transaction.Changed, c.Type)
 }
 
-func (c *ContentType) Gc(store *util.StructStore) {
-	// 实现逻辑
-	item := c.Type.GetStart()
-	for item != nil {
+func (c *ContentType) Gc(store *StructStore) {
+	// 类型被回收时，其中的所有项目都替换为 GC 结构
+	for item := c.Type.GetStart(); item != nil; item = item.Right {
 		item.GC(store, true)
-		item = item.Right
 	}
 	c.Type.SetStart(nil)
-	dataMap := c.Type.GetDataMap()
-	for _, item := range dataMap {
-		for item != nil {
+	for _, item := range c.Type.GetDataMap() {
+		for ; item != nil; item = item.Left {
 			item.GC(store, true)
-			item = item.Right
 		}
 	}
 	c.Type.SetDataMap(make(map[string]*Item))
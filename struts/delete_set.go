@@ -0,0 +1,117 @@
+package struts
+
+import "CollabEdit/util"
+
+// IterateStructs 遍历 [clockStart, clockStart+length) 范围内的结构，必要时在范围边界处分割项目
+func IterateStructs(transaction *Transaction, structs *[]AbstractStructInterface, clockStart int, length int, f func(s AbstractStructInterface)) {
+	if length == 0 {
+		return
+	}
+	clockEnd := clockStart + length
+	index := FindIndexCleanStart(transaction, structs, clockStart)
+	for {
+		s := (*structs)[index]
+		index++
+		if clockEnd < s.GetID().Clock+s.GetLength() {
+			FindIndexCleanStart(transaction, structs, clockEnd)
+		}
+		f(s)
+		if index >= len(*structs) || (*structs)[index].GetID().Clock >= clockEnd {
+			break
+		}
+	}
+}
+
+// IterateDeletedStructs 遍历删除集合中所有被删除的结构
+func IterateDeletedStructs(transaction *Transaction, ds *util.DeleteSet, f func(s AbstractStructInterface)) {
+	for client, deletes := range ds.Clients {
+		structs, exists := transaction.Doc.Store.Clients[client]
+		if !exists {
+			continue
+		}
+		for _, del := range *deletes {
+			IterateStructs(transaction, structs, del.Clock, del.Len, f)
+		}
+	}
+}
+
+// CreateDeleteSetFromStructStore 根据结构存储中已删除的结构创建删除集合，相邻的删除会被合并
+func CreateDeleteSetFromStructStore(store *StructStore) *util.DeleteSet {
+	ds := util.NewDeleteSet()
+	for client, structs := range store.Clients {
+		dsItems := make([]util.DeleteItem, 0)
+		for i := 0; i < len(*structs); i++ {
+			s := (*structs)[i]
+			if s.GetDeleted() {
+				clock := s.GetID().Clock
+				length := s.GetLength()
+				for i+1 < len(*structs) && (*structs)[i+1].GetDeleted() {
+					length += (*structs)[i+1].GetLength()
+					i++
+				}
+				dsItems = append(dsItems, util.DeleteItem{Clock: clock, Len: length})
+			}
+		}
+		if len(dsItems) > 0 {
+			ds.Clients[client] = &dsItems
+		}
+	}
+	return ds
+}
+
+// ReadAndApplyDeleteSet 读取删除集合并将其应用到结构存储中
+// 尚未收到的结构无法删除，返回这部分未应用的删除集合，全部应用时返回 nil
+func ReadAndApplyDeleteSet(decoder util.DSDecoderInterface, transaction *Transaction, store *StructStore) *util.DeleteSet {
+	unappliedDS := util.NewDeleteSet()
+	restDecoder := decoder.RestDecoder()
+	numClients := int(restDecoder.ReadVarUint())
+	for i := 0; i < numClients; i++ {
+		decoder.ResetDsCurVal()
+		client := int(restDecoder.ReadVarUint())
+		numberOfDeletes := int(restDecoder.ReadVarUint())
+		structs, exists := store.Clients[client]
+		if !exists {
+			structs = &[]AbstractStructInterface{}
+		}
+		state := GetState(store, client)
+		for j := 0; j < numberOfDeletes; j++ {
+			clock := decoder.ReadDsClock()
+			clockEnd := clock + decoder.ReadDsLen()
+			if clock < state {
+				if state < clockEnd {
+					util.AddToDeleteSet(unappliedDS, client, state, clockEnd-state)
+				}
+				index := FindIndexSS(*structs, clock)
+				// 我们只能删除已知的结构，所以需要先在 clock 处分割
+				s := (*structs)[index]
+				if item, ok := s.(*Item); ok && !item.GetDeleted() && item.ID.Clock < clock {
+					rightItem := SplitItem(transaction, item, clock-item.ID.Clock)
+					*structs = append((*structs)[:index+1], append([]AbstractStructInterface{rightItem}, (*structs)[index+1:]...)...)
+					index++ // 只需要删除右侧部分
+				}
+				for index < len(*structs) {
+					s = (*structs)[index]
+					index++
+					if s.GetID().Clock >= clockEnd {
+						break
+					}
+					item, ok := s.(*Item)
+					if !ok || item.GetDeleted() {
+						continue
+					}
+					if clockEnd < item.ID.Clock+item.Length {
+						rightItem := SplitItem(transaction, item, clockEnd-item.ID.Clock)
+						*structs = append((*structs)[:index], append([]AbstractStructInterface{rightItem}, (*structs)[index:]...)...)
+					}
+					item.Delete(transaction)
+				}
+			} else {
+				util.AddToDeleteSet(unappliedDS, client, clock, clockEnd-clock)
+			}
+		}
+	}
+	if len(unappliedDS.Clients) > 0 {
+		return unappliedDS
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package struts
+
+import (
+	"CollabEdit/core"
+	"fmt"
+	"github.com/google/uuid"
+	"math/rand"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// 生成新的客户端ID
+func generateNewClientId() uint32 {
+	return rand.Uint32()
+}
+
+// DocOpts 定义了文档的选项
+type DocOpts struct {
+	GC           bool                  // 是否启用垃圾回收
+	GCFilter     func(item *Item) bool // 垃圾回收过滤器函数
+	Guid         string                // 全局唯一标识符
+	CollectionID string                // 文档关联的集合ID
+	Meta         interface{}           // 文档的元信息
+	AutoLoad     bool                  // 是否自动加载文档
+	ShouldLoad   bool                  // 文档是否应立即同步
+}
+
+// Doc 定义Doc结构体
+type Doc struct {
+	core.Observable                                      //继承观察者
+	Gc                  bool                             //是否可以被GC
+	GcFilter            func(item *Item) bool            //GC过滤
+	ClientID            int                              //客户端ID
+	Guid                string                           //全局唯一标识
+	CollectionID        string                           //文档集合ID
+	Share               map[string]AbstractTypeInterface //共享文档
+	Store               *StructStore                     //结构体存储
+	Transaction         *Transaction                     //事务
+	TransactionCleanups []*Transaction                   //事务清理
+	SubDocs             map[*Doc]struct{}                //子文档集合
+	Item                *Item                            //子文档集成项目
+	AutoLoad            bool                             //是否自动加载
+	ShouldLoad          bool                             //是否应立刻同步文档
+	Meta                interface{}                      //元数据
+	IsLoaded            bool                             //是否已加载
+	IsSynced            bool                             //是否已同步
+	IsDestroyed         bool                             //是否已销毁
+	WhenLoaded          *sync.Cond                       //文档加载完成的条件
+	WhenSynced          *sync.Cond                       //文档同步完成的条件
+}
+
+// NewDoc 创建Doc
+func NewDoc(opts *DocOpts) *Doc {
+	if opts == nil {
+		opts = &DocOpts{
+			GC:           true,
+			GCFilter:     func(item *Item) bool { return true },
+			Guid:         uuid.NewString(),
+			CollectionID: "",
+			Meta:         nil,
+			AutoLoad:     false,
+			ShouldLoad:   true,
+		}
+	}
+	if opts.GCFilter == nil {
+		opts.GCFilter = func(item *Item) bool { return true }
+	}
+	if opts.Guid == "" {
+		opts.Guid = uuid.NewString()
+	}
+
+	doc := &Doc{
+		Gc:                  opts.GC,
+		GcFilter:            opts.GCFilter,
+		ClientID:            int(generateNewClientId()),
+		Guid:                opts.Guid,
+		CollectionID:        opts.CollectionID,
+		Share:               make(map[string]AbstractTypeInterface),
+		Store:               NewStructStore(),
+		Transaction:         nil,
+		TransactionCleanups: make([]*Transaction, 0),
+		SubDocs:             make(map[*Doc]struct{}),
+		Item:                nil,
+		AutoLoad:            opts.AutoLoad,
+		ShouldLoad:          opts.ShouldLoad,
+		Meta:                opts.Meta,
+		IsLoaded:            false,
+		IsSynced:            false,
+		IsDestroyed:         false,
+		WhenLoaded:          sync.NewCond(&sync.Mutex{}),
+		WhenSynced:          sync.NewCond(&sync.Mutex{}),
+	}
+	doc.Observers = make(map[string][]*core.Observer)
+	//TODO: 完成线程同步
+
+	return doc
+}
+
+// Transact 在事务中执行 f，所有的修改都应该在事务中完成
+// 嵌套调用时复用外层事务，只有最外层的调用结束后才会清理事务并触发事件
+func (doc *Doc) Transact(f func(transaction *Transaction), origin interface{}, local bool) {
+	initialCall := false
+	if doc.Transaction == nil {
+		initialCall = true
+		doc.Transaction = NewTransaction(doc, origin, local)
+		doc.TransactionCleanups = append(doc.TransactionCleanups, doc.Transaction)
+		if len(doc.TransactionCleanups) == 1 {
+			doc.Emit("beforeAllTransactions", doc)
+		}
+		doc.Emit("beforeTransaction", doc.Transaction)
+	}
+	defer func() {
+		if initialCall {
+			finishCleanup := doc.Transaction == doc.TransactionCleanups[0]
+			doc.Transaction = nil
+			if finishCleanup {
+				cleanupTransactions(doc, 0)
+			}
+		}
+	}()
+	f(doc.Transaction)
+}
+
+// UpdateEvent 事务结束后通过 update（V1 格式）或 updateV2（V2 格式）事件发出的增量更新
+type UpdateEvent struct {
+	Update      []byte       // 事务产生的更新
+	Origin      interface{}  // 事务来源，可以用来避免将更新发回给它的来源
+	Doc         *Doc         // 产生更新的文档
+	Transaction *Transaction // 产生更新的事务
+}
+
+// NewUpdateEvent 创建事务的更新事件
+func NewUpdateEvent(update []byte, transaction *Transaction) *UpdateEvent {
+	return &UpdateEvent{
+		Update:      update,
+		Origin:      transaction.Origin,
+		Doc:         transaction.Doc,
+		Transaction: transaction,
+	}
+}
+
+// SubdocsEvent 事务结束后通过 subdocs 事件报告的子文档变化
+type SubdocsEvent struct {
+	Added       map[*Doc]struct{} // 新增的子文档
+	Removed     map[*Doc]struct{} // 删除的子文档
+	Loaded      map[*Doc]struct{} // 请求加载的子文档
+	Transaction *Transaction      // 产生变化的事务
+}
+
+// Load 请求加载子文档，父文档会在 subdocs 事件中报告该子文档，由提供者负责同步其内容
+func (doc *Doc) Load() {
+	if item := doc.Item; item != nil && !doc.ShouldLoad {
+		item.Parent.GetDoc().Transact(func(transaction *Transaction) {
+			transaction.SubDocsLoaded[doc] = struct{}{}
+		}, nil, true)
+	}
+	doc.ShouldLoad = true
+}
+
+// GetSubdocs 返回文档中的所有子文档
+func (doc *Doc) GetSubdocs() map[*Doc]struct{} {
+	return doc.SubDocs
+}
+
+// GetSubdocGuids 返回文档中所有子文档的全局唯一标识
+func (doc *Doc) GetSubdocGuids() map[string]struct{} {
+	guids := make(map[string]struct{}, len(doc.SubDocs))
+	for subdoc := range doc.SubDocs {
+		guids[subdoc.Guid] = struct{}{}
+	}
+	return guids
+}
+
+// Destroy 销毁文档及其所有子文档
+// 作为子文档时，父文档中的项目改为引用一份未加载的新文档，之后仍然可以重新加载
+func (doc *Doc) Destroy() {
+	doc.IsDestroyed = true
+	for subdoc := range doc.SubDocs {
+		subdoc.Destroy()
+	}
+	if item := doc.Item; item != nil {
+		doc.Item = nil
+		// 被回收的项目中已经没有子文档内容，无需替换
+		content, ok := item.Content.(*ContentDoc)
+		if ok {
+			opts := *content.Opts
+			opts.Guid = doc.Guid
+			opts.ShouldLoad = false
+			content.Doc = NewDoc(&opts)
+			content.Doc.Item = item
+		}
+		item.Parent.GetDoc().Transact(func(transaction *Transaction) {
+			if ok && !item.GetDeleted() {
+				transaction.SubDocsAdded[content.Doc] = struct{}{}
+			}
+			transaction.SubDocsRemoved[doc] = struct{}{}
+		}, nil, true)
+	}
+	doc.Emit("destroyed", true)
+	doc.Emit("destroy", doc)
+	doc.Observers = make(map[string][]*core.Observer)
+}
+
+// newAbstractType 创建不带具体类型的共享类型，由类型所在的包注册
+var newAbstractType func() AbstractTypeInterface
+
+// RegisterAbstractType 注册不带具体类型的共享类型的构造函数
+func RegisterAbstractType(constructor func() AbstractTypeInterface) {
+	newAbstractType = constructor
+}
+
+// Get 获取名为 name 的顶层共享类型，不存在时使用 typeConstructor 创建并整合到文档中
+// typeConstructor 为 nil 时创建不带具体类型的共享类型，解码更新时顶层类型的具体类型未知，
+// 之后使用具体的构造函数获取时会转换为该类型
+func (doc *Doc) Get(name string, typeConstructor func() AbstractTypeInterface) AbstractTypeInterface {
+	if typeConstructor == nil {
+		typeConstructor = newAbstractType
+	}
+	t, exists := doc.Share[name]
+	if !exists {
+		t = typeConstructor()
+		t.Integrate(doc, nil)
+		doc.Share[name] = t
+		return t
+	}
+	baseType := reflect.TypeOf(newAbstractType())
+	constructed := typeConstructor()
+	if reflect.TypeOf(constructed) != baseType && reflect.TypeOf(t) == baseType {
+		// 将不带具体类型的共享类型转换为具体类型
+		constructed.SetDataMap(t.GetDataMap())
+		for _, n := range t.GetDataMap() {
+			for ; n != nil; n = n.Left {
+				n.Parent = constructed
+			}
+		}
+		constructed.SetStart(t.GetStart())
+		for n := t.GetStart(); n != nil; n = n.Right {
+			n.Parent = constructed
+		}
+		constructed.SetLength(t.GetLength())
+		doc.Share[name] = constructed
+		constructed.Integrate(doc, nil)
+		return constructed
+	}
+	if reflect.TypeOf(constructed) != baseType && reflect.TypeOf(t) != reflect.TypeOf(constructed) {
+		panic(fmt.Sprintf("名为 %q 的类型已经使用不同的构造函数定义", name))
+	}
+	return t
+}
+
+// MissingRange 暂存的更新等待的结构范围，需要收到客户端 Client 时钟 From 到 To 的结构后才能继续整合
+type MissingRange struct {
+	Client int // 客户端ID
+	From   int // 第一个缺失的时钟
+	To     int // 至少需要收到的时钟
+}
+
+// Missing 返回文档中暂存的更新所等待的结构范围，按客户端 ID 升序排列
+func (doc *Doc) Missing() []MissingRange {
+	ranges := make([]MissingRange, 0)
+	pending := doc.Store.PendingStructs
+	if pending == nil {
+		return ranges
+	}
+	for client, clock := range pending.Missing {
+		from := GetState(doc.Store, client)
+		ranges = append(ranges, MissingRange{Client: client, From: from, To: max(from, clock)})
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].Client < ranges[j].Client
+	})
+	return ranges
+}
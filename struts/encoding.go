@@ -0,0 +1,433 @@
+package struts
+
+import (
+	"CollabEdit/core"
+	"CollabEdit/util"
+	"reflect"
+	"sort"
+)
+
+// clientStructRefs 一个客户端的待整合结构及下一个待整合结构的索引
+type clientStructRefs struct {
+	i    int
+	refs []AbstractStructInterface
+}
+
+// writeStructs 写入一个客户端从 clock 开始的所有结构，第一个结构从 clock 处开始写入
+func writeStructs(encoder util.UpdateEncoderInterface, structs []AbstractStructInterface, client int, clock int) {
+	clock = max(clock, structs[0].GetID().Clock)
+	startNewStructs := FindIndexSS(structs, clock)
+	encoder.RestEncoder().WriteVarUint(uint(len(structs) - startNewStructs))
+	encoder.WriteClient(client)
+	encoder.RestEncoder().WriteVarUint(uint(clock))
+	firstStruct := structs[startNewStructs]
+	firstStruct.Write(encoder, clock-firstStruct.GetID().Clock)
+	for i := startNewStructs + 1; i < len(structs); i++ {
+		structs[i].Write(encoder, 0)
+	}
+}
+
+// writeClientsStructs 写入存储中对方缺少的结构，sm 为对方的状态向量
+// 客户端按 ID 降序写入，与 Yjs 保持一致
+func writeClientsStructs(encoder util.UpdateEncoderInterface, store *StructStore, sm map[int]int) {
+	clocks := make(map[int]int)
+	for client, clock := range sm {
+		if GetState(store, client) > clock {
+			clocks[client] = clock
+		}
+	}
+	for client := range GetStateVector(store) {
+		if _, exists := sm[client]; !exists {
+			clocks[client] = 0
+		}
+	}
+	clients := make([]int, 0, len(clocks))
+	for client := range clocks {
+		clients = append(clients, client)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(clients)))
+	encoder.RestEncoder().WriteVarUint(uint(len(clients)))
+	for _, client := range clients {
+		writeStructs(encoder, *store.Clients[client], client, clocks[client])
+	}
+}
+
+// readClientsStructRefs 读取更新中所有客户端的结构，项目的左右项目与父类型在整合时才会解析
+func readClientsStructRefs(decoder util.UpdateDecoderInterface, doc *Doc) map[int]*clientStructRefs {
+	clientRefs := make(map[int]*clientStructRefs)
+	numOfStateUpdates := int(decoder.RestDecoder().ReadVarUint())
+	for i := 0; i < numOfStateUpdates; i++ {
+		numberOfStructs := int(decoder.RestDecoder().ReadVarUint())
+		refs := make([]AbstractStructInterface, numberOfStructs)
+		client := decoder.ReadClient()
+		clock := int(decoder.RestDecoder().ReadVarUint())
+		clientRefs[client] = &clientStructRefs{i: 0, refs: refs}
+		for j := 0; j < numberOfStructs; j++ {
+			info := decoder.ReadInfo()
+			switch core.BITS5 & info {
+			case structGCRefNumber:
+				length := decoder.ReadLen()
+				refs[j] = NewGC(util.NewID(client, clock), length)
+				clock += length
+			case structSkipRefNumber:
+				length := int(decoder.RestDecoder().ReadVarUint())
+				refs[j] = NewSkip(util.NewID(client, clock), length)
+				clock += length
+			default:
+				// 左右依赖都不存在时，父类型信息写在项目中
+				cantCopyParentInfo := info&(core.BIT7|core.BIT8) == 0
+				var origin, rightOrigin *util.ID
+				if info&core.BIT8 == core.BIT8 {
+					origin = decoder.ReadLeftID()
+				}
+				if info&core.BIT7 == core.BIT7 {
+					rightOrigin = decoder.ReadRightID()
+				}
+				var parent AbstractTypeInterface
+				var parentID *util.ID
+				parentSub := ""
+				if cantCopyParentInfo {
+					if decoder.ReadParentInfo() {
+						parent = doc.Get(decoder.ReadString(), nil)
+					} else {
+						parentID = decoder.ReadLeftID()
+					}
+					if info&core.BIT6 == core.BIT6 {
+						parentSub = decoder.ReadString()
+					}
+				}
+				item := NewItem(util.NewID(client, clock), nil, origin, nil, rightOrigin, parent, parentSub, ReadItemContent(decoder, info))
+				item.ParentID = parentID
+				refs[j] = item
+				clock += item.Length
+			}
+		}
+	}
+	return clientRefs
+}
+
+// integrateStructs 按因果顺序整合读取的结构
+// 依赖缺失而无法整合的结构保存在返回的存储中，同时返回每个客户端缺失的最小时钟
+func integrateStructs(transaction *Transaction, store *StructStore, clientsStructRefs map[int]*clientStructRefs) (*StructStore, map[int]int) {
+	var stack []AbstractStructInterface
+	clientsStructRefsIds := make([]int, 0, len(clientsStructRefs))
+	for client := range clientsStructRefs {
+		clientsStructRefsIds = append(clientsStructRefsIds, client)
+	}
+	sort.Ints(clientsStructRefsIds)
+	if len(clientsStructRefsIds) == 0 {
+		return nil, nil
+	}
+	// getNextStructTarget 返回客户端 ID 最大且还有结构未整合的客户端
+	getNextStructTarget := func() *clientStructRefs {
+		for len(clientsStructRefsIds) > 0 {
+			target := clientsStructRefs[clientsStructRefsIds[len(clientsStructRefsIds)-1]]
+			if target.i < len(target.refs) {
+				return target
+			}
+			clientsStructRefsIds = clientsStructRefsIds[:len(clientsStructRefsIds)-1]
+		}
+		return nil
+	}
+	curStructsTarget := getNextStructTarget()
+	if curStructsTarget == nil {
+		return nil, nil
+	}
+
+	restStructs := NewStructStore()
+	missingSV := make(map[int]int)
+	updateMissingSv := func(client, clock int) {
+		if mclock, exists := missingSV[client]; !exists || mclock > clock {
+			missingSV[client] = clock
+		}
+	}
+	stackHead := curStructsTarget.refs[curStructsTarget.i]
+	curStructsTarget.i++
+	state := make(map[int]int)
+
+	// addStackToRestSS 将栈中的结构及其客户端之后的所有结构移入 restStructs
+	addStackToRestSS := func() {
+		for _, item := range stack {
+			client := item.GetID().Client
+			if unapplicableItems, exists := clientsStructRefs[client]; exists {
+				// 栈中的结构已经从 refs 中取出，需要放回
+				unapplicableItems.i--
+				rest := append([]AbstractStructInterface{}, unapplicableItems.refs[unapplicableItems.i:]...)
+				restStructs.Clients[client] = &rest
+				delete(clientsStructRefs, client)
+				unapplicableItems.i = 0
+				unapplicableItems.refs = nil
+			} else {
+				// 此客户端已经被移入 restStructs
+				restStructs.Clients[client] = &[]AbstractStructInterface{item}
+			}
+			// 移除此客户端，不再尝试整合
+			ids := clientsStructRefsIds[:0]
+			for _, c := range clientsStructRefsIds {
+				if c != client {
+					ids = append(ids, c)
+				}
+			}
+			clientsStructRefsIds = ids
+		}
+		stack = stack[:0]
+	}
+
+	for {
+		// Skip 结构表示更新中缺失的部分，不需要整合
+		if _, ok := stackHead.(*Skip); !ok {
+			client := stackHead.GetID().Client
+			localClock, exists := state[client]
+			if !exists {
+				localClock = GetState(store, client)
+				state[client] = localClock
+			}
+			offset := localClock - stackHead.GetID().Clock
+			if offset < 0 {
+				// 之前的结构缺失
+				stack = append(stack, stackHead)
+				updateMissingSv(client, stackHead.GetID().Clock-1)
+				addStackToRestSS()
+			} else {
+				missing := -1
+				if item, ok := stackHead.(*Item); ok {
+					missing = item.GetMissing(transaction, store)
+				}
+				if missing != -1 {
+					stack = append(stack, stackHead)
+					// 先尝试整合依赖的客户端的结构
+					structRefs, exists := clientsStructRefs[missing]
+					if !exists || structRefs.i == len(structRefs.refs) {
+						// 依赖的结构不在此更新中
+						updateMissingSv(missing, GetState(store, missing))
+						addStackToRestSS()
+					} else {
+						stackHead = structRefs.refs[structRefs.i]
+						structRefs.i++
+						continue
+					}
+				} else if offset == 0 || offset < stackHead.GetLength() {
+					// 整合尚未存在的部分
+					stackHead.Integrate(transaction, offset)
+					state[client] = stackHead.GetID().Clock + stackHead.GetLength()
+				}
+			}
+		}
+		// 选择下一个要整合的结构
+		if len(stack) > 0 {
+			stackHead = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		} else if curStructsTarget != nil && curStructsTarget.i < len(curStructsTarget.refs) {
+			stackHead = curStructsTarget.refs[curStructsTarget.i]
+			curStructsTarget.i++
+		} else {
+			curStructsTarget = getNextStructTarget()
+			if curStructsTarget == nil {
+				break
+			}
+			stackHead = curStructsTarget.refs[curStructsTarget.i]
+			curStructsTarget.i++
+		}
+	}
+	if len(restStructs.Clients) > 0 {
+		return restStructs, missingSV
+	}
+	return nil, nil
+}
+
+// ReadUpdateWithDecoder 使用给定的解码器读取更新并应用到文档中
+// 依赖缺失的结构与删除信息会暂存在存储中，与之后暂存的更新合并，并在缺失的结构到达后重新应用
+func ReadUpdateWithDecoder(decoder util.UpdateDecoderInterface, doc *Doc, transactionOrigin interface{}) {
+	doc.Transact(func(transaction *Transaction) {
+		store := doc.Store
+		retry := false
+		ss := readClientsStructRefs(decoder, doc)
+		restStructs, missing := integrateStructs(transaction, store, ss)
+		var restUpdate []byte
+		if restStructs != nil {
+			encoder := util.NewUpdateEncoderV2()
+			writeClientsStructs(encoder, restStructs, map[int]int{})
+			encoder.RestEncoder().WriteVarUint(0) // 不包含删除集合
+			restUpdate = encoder.ToBytes()
+		}
+		if pending := store.PendingStructs; pending != nil {
+			// 检查暂存的结构是否已经可以整合
+			for client, clock := range pending.Missing {
+				if clock < GetState(store, client) {
+					retry = true
+					break
+				}
+			}
+			if restUpdate != nil {
+				// 将新的暂存结构合并到 store.PendingStructs
+				for client, clock := range missing {
+					if mclock, exists := pending.Missing[client]; !exists || mclock > clock {
+						pending.Missing[client] = clock
+					}
+				}
+				pending.Update = MergeUpdatesV2([][]byte{pending.Update, restUpdate})
+			}
+		} else if restUpdate != nil {
+			store.PendingStructs = &PendingStructs{Missing: missing, Update: restUpdate}
+		}
+
+		dsRest := encodePendingDeleteSet(ReadAndApplyDeleteSet(decoder, transaction, store))
+		if store.PendingDs != nil {
+			// 重新应用暂存的删除集合
+			pendingDSUpdate := util.NewUpdateDecoderV2(core.CreateDecoder(store.PendingDs))
+			pendingDSUpdate.RestDecoder().ReadVarUint() // 暂存的删除集合不包含结构
+			dsRest2 := encodePendingDeleteSet(ReadAndApplyDeleteSet(pendingDSUpdate, transaction, store))
+			if dsRest != nil && dsRest2 != nil {
+				store.PendingDs = MergeUpdatesV2([][]byte{dsRest, dsRest2})
+			} else if dsRest != nil {
+				store.PendingDs = dsRest
+			} else {
+				store.PendingDs = dsRest2
+			}
+		} else {
+			store.PendingDs = dsRest
+		}
+		if retry {
+			update := store.PendingStructs.Update
+			store.PendingStructs = nil
+			ApplyUpdateV2(transaction.Doc, update, transactionOrigin)
+		}
+	}, transactionOrigin, false)
+}
+
+// encodePendingDeleteSet 将未应用的删除集合编码为不包含结构的 V2 更新，ds 为 nil 时返回 nil
+func encodePendingDeleteSet(ds *util.DeleteSet) []byte {
+	if ds == nil {
+		return nil
+	}
+	encoder := util.NewUpdateEncoderV2()
+	encoder.RestEncoder().WriteVarUint(0) // 不包含结构
+	util.WriteDeleteSet(encoder, ds)
+	return encoder.ToBytes()
+}
+
+// ApplyUpdate 将 V1 格式的更新应用到文档中
+func ApplyUpdate(doc *Doc, update []byte, transactionOrigin interface{}) {
+	ReadUpdateWithDecoder(util.NewUpdateDecoderV1(core.CreateDecoder(update)), doc, transactionOrigin)
+}
+
+// ApplyUpdateV2 将 V2 格式的更新应用到文档中
+func ApplyUpdateV2(doc *Doc, update []byte, transactionOrigin interface{}) {
+	ReadUpdateWithDecoder(util.NewUpdateDecoderV2(core.CreateDecoder(update)), doc, transactionOrigin)
+}
+
+// writeUpdateMessageFromTransaction 将事务中新增的结构与删除集合写入编码器，事务没有产生修改时返回 false
+func writeUpdateMessageFromTransaction(encoder util.UpdateEncoderInterface, transaction *Transaction) bool {
+	if len(transaction.DeleteSet.Clients) == 0 && reflect.DeepEqual(transaction.BeforeState, transaction.AfterState) {
+		return false
+	}
+	util.SortAndMergeDeleteSet(transaction.DeleteSet)
+	writeClientsStructs(encoder, transaction.Doc.Store, transaction.BeforeState)
+	util.WriteDeleteSet(encoder, transaction.DeleteSet)
+	return true
+}
+
+// WriteStateAsUpdate 将文档中对方缺少的结构与完整的删除集合写入编码器
+func WriteStateAsUpdate(encoder util.UpdateEncoderInterface, doc *Doc, targetStateVector map[int]int) {
+	writeClientsStructs(encoder, doc.Store, targetStateVector)
+	util.WriteDeleteSet(encoder, CreateDeleteSetFromStructStore(doc.Store))
+}
+
+// WriteStateVector 写入状态向量，客户端按 ID 降序写入
+func WriteStateVector(encoder util.DSEncoderInterface, sv map[int]int) {
+	clients := sortedClientsDesc(sv)
+	encoder.RestEncoder().WriteVarUint(uint(len(clients)))
+	for _, client := range clients {
+		encoder.RestEncoder().WriteVarUint(uint(client))
+		encoder.RestEncoder().WriteVarUint(uint(sv[client]))
+	}
+}
+
+// sortedClientsDesc 返回状态向量中按 ID 降序排列的客户端
+func sortedClientsDesc(sv map[int]int) []int {
+	clients := make([]int, 0, len(sv))
+	for client := range sv {
+		clients = append(clients, client)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(clients)))
+	return clients
+}
+
+// ReadStateVector 读取状态向量
+func ReadStateVector(decoder util.DSDecoderInterface) map[int]int {
+	ss := make(map[int]int)
+	ssLength := int(decoder.RestDecoder().ReadVarUint())
+	for i := 0; i < ssLength; i++ {
+		client := int(decoder.RestDecoder().ReadVarUint())
+		clock := int(decoder.RestDecoder().ReadVarUint())
+		ss[client] = clock
+	}
+	return ss
+}
+
+// DecodeStateVector 解码状态向量，状态向量总是使用 V1 格式编码
+func DecodeStateVector(decodedState []byte) map[int]int {
+	return ReadStateVector(util.NewDSDecoderV1(core.CreateDecoder(decodedState)))
+}
+
+// EncodeStateVectorFromMap 编码给定的状态向量
+func EncodeStateVectorFromMap(sv map[int]int) []byte {
+	encoder := util.NewDSEncoderV1()
+	WriteStateVector(encoder, sv)
+	return encoder.ToBytes()
+}
+
+// EncodeStateVector 编码文档的状态向量
+func EncodeStateVector(doc *Doc) []byte {
+	return EncodeStateVectorFromMap(GetStateVector(doc.Store))
+}
+
+// EncodeStateVectorV2 使用 V2 删除集合编码器编码文档的状态向量
+func EncodeStateVectorV2(doc *Doc) []byte {
+	encoder := util.NewDSEncoderV2()
+	WriteStateVector(encoder, GetStateVector(doc.Store))
+	return encoder.ToBytes()
+}
+
+// EncodeStateAsUpdate 将文档编码为 V1 格式的更新
+// encodedTargetStateVector 为对方编码后的状态向量，只编码对方缺少的结构，为空时编码整个文档
+// 文档中暂存的结构与删除集合也会包含在更新中
+func EncodeStateAsUpdate(doc *Doc, encodedTargetStateVector []byte) []byte {
+	updates := [][]byte{encodeStateAsUpdateWithEncoder(doc, encodedTargetStateVector, util.NewUpdateEncoderV1())}
+	for _, update := range pendingUpdates(doc, encodedTargetStateVector) {
+		updates = append(updates, ConvertUpdateFormatV2ToV1(update))
+	}
+	return MergeUpdates(updates)
+}
+
+// EncodeStateAsUpdateV2 将文档编码为 V2 格式的更新
+func EncodeStateAsUpdateV2(doc *Doc, encodedTargetStateVector []byte) []byte {
+	updates := [][]byte{encodeStateAsUpdateWithEncoder(doc, encodedTargetStateVector, util.NewUpdateEncoderV2())}
+	updates = append(updates, pendingUpdates(doc, encodedTargetStateVector)...)
+	return MergeUpdatesV2(updates)
+}
+
+// encodeStateAsUpdateWithEncoder 使用给定的编码器编码文档
+func encodeStateAsUpdateWithEncoder(doc *Doc, encodedTargetStateVector []byte, encoder util.UpdateEncoderInterface) []byte {
+	targetStateVector := make(map[int]int)
+	if len(encodedTargetStateVector) > 0 {
+		targetStateVector = DecodeStateVector(encodedTargetStateVector)
+	}
+	WriteStateAsUpdate(encoder, doc, targetStateVector)
+	return encoder.ToBytes()
+}
+
+// pendingUpdates 返回文档中暂存的删除集合以及对方缺少的暂存结构，均为 V2 格式
+func pendingUpdates(doc *Doc, encodedTargetStateVector []byte) [][]byte {
+	var updates [][]byte
+	if doc.Store.PendingDs != nil {
+		updates = append(updates, doc.Store.PendingDs)
+	}
+	if doc.Store.PendingStructs != nil {
+		if len(encodedTargetStateVector) == 0 {
+			encodedTargetStateVector = EncodeStateVectorFromMap(map[int]int{})
+		}
+		updates = append(updates, DiffUpdateV2(doc.Store.PendingStructs.Update, encodedTargetStateVector))
+	}
+	return updates
+}
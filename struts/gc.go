@@ -2,6 +2,9 @@ package struts
 
 import "CollabEdit/util"
 
+// structGCRefNumber GC 结构在编码中的引用编号
+const structGCRefNumber = 0
+
 type GC struct {
 	*AbstractStruct
 }
@@ -11,3 +14,33 @@ func NewGC(id *util.ID, length int) *GC {
 		AbstractStruct: NewAbstractStruct(id, length),
 	}
 }
+
+// GetDeleted GC 结构总是处于删除状态
+func (g *GC) GetDeleted() bool {
+	return true
+}
+
+// MergeWith 合并右侧相邻的 GC 结构
+func (g *GC) MergeWith(right AbstractStructInterface) bool {
+	r, ok := right.(*GC)
+	if !ok {
+		return false
+	}
+	g.Length += r.Length
+	return true
+}
+
+// Integrate 将 GC 结构添加到结构体存储中
+func (g *GC) Integrate(transaction *Transaction, offset int) {
+	if offset > 0 {
+		g.ID.Clock += offset
+		g.Length -= offset
+	}
+	AddStruct(transaction.Doc.Store, g)
+}
+
+// Write 将 GC 结构写入编码器
+func (g *GC) Write(encoder util.UpdateEncoderInterface, offset int) {
+	encoder.WriteInfo(structGCRefNumber)
+	encoder.WriteLen(g.Length - offset)
+}
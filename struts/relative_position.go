@@ -0,0 +1,212 @@
+package struts
+
+import (
+	"CollabEdit/core"
+	"CollabEdit/util"
+)
+
+// RelativePosition 相对位置，通过项目ID而不是索引描述类型中的位置，并发修改后仍然指向同一处
+//
+// Item 为位置右侧字符的ID，Item 为 nil 时位置在类型的末尾，此时通过 Tname（顶层类型的名称）
+// 或 Type（类型所在项目的ID）找到类型。Assoc 小于 0 时位置与左侧的字符关联，否则与右侧的字符关联
+type RelativePosition struct {
+	Type  *util.ID `json:"type,omitempty"`  // 类型所在项目的ID
+	Tname string   `json:"tname,omitempty"` // 顶层类型的名称
+	Item  *util.ID `json:"item,omitempty"`  // 位置右侧（Assoc 小于 0 时为左侧）字符的ID
+	Assoc int      `json:"assoc"`           // 位置关联的方向
+}
+
+// NewRelativePosition 创建相对位置
+func NewRelativePosition(t *util.ID, tname string, item *util.ID, assoc int) *RelativePosition {
+	return &RelativePosition{
+		Type:  t,
+		Tname: tname,
+		Item:  item,
+		Assoc: assoc,
+	}
+}
+
+// AbsolutePosition 绝对位置，即类型与其中的索引
+type AbsolutePosition struct {
+	Type  AbstractTypeInterface
+	Index int
+	Assoc int
+}
+
+// NewAbsolutePosition 创建绝对位置
+func NewAbsolutePosition(t AbstractTypeInterface, index int, assoc int) *AbsolutePosition {
+	return &AbsolutePosition{
+		Type:  t,
+		Index: index,
+		Assoc: assoc,
+	}
+}
+
+// createRelativePosition 创建指向类型中 item 处的相对位置，item 为 nil 时位置在类型的末尾
+// 与 Yjs 一致，总是记录类型ID或顶层类型名称，二进制编码在 item 不为 nil 时只写入 item
+func createRelativePosition(t AbstractTypeInterface, item *util.ID, assoc int) *RelativePosition {
+	var typeID *util.ID
+	tname := ""
+	if typeItem := t.GetItem(); typeItem != nil {
+		typeID = util.NewID(typeItem.ID.Client, typeItem.ID.Clock)
+	} else {
+		tname = findRootTypeKey(t)
+	}
+	return NewRelativePosition(typeID, tname, item, assoc)
+}
+
+// CreateRelativePositionFromTypeIndex 根据类型中的索引创建相对位置
+// assoc 大于等于 0 时位置与索引处的字符关联，小于 0 时与索引之前的字符关联
+func CreateRelativePositionFromTypeIndex(t AbstractTypeInterface, index int, assoc int) *RelativePosition {
+	if assoc < 0 {
+		// 与左侧的字符关联
+		if index == 0 {
+			return createRelativePosition(t, nil, assoc)
+		}
+		index--
+	}
+	for n := t.GetStart(); n != nil; n = n.Right {
+		if !n.GetDeleted() && n.Countable() {
+			if n.Length > index {
+				// 位置在此项目中
+				return createRelativePosition(t, util.NewID(n.ID.Client, n.ID.Clock+index), assoc)
+			}
+			index -= n.Length
+		}
+		if n.Right == nil && assoc < 0 {
+			// 与最后一个字符关联
+			return createRelativePosition(t, n.LastId(), assoc)
+		}
+	}
+	return createRelativePosition(t, nil, assoc)
+}
+
+// CreateAbsolutePositionFromRelativePosition 将相对位置转换为文档中的绝对位置
+// 指向的结构尚未收到或已被回收时返回 nil
+func CreateAbsolutePositionFromRelativePosition(rpos *RelativePosition, doc *Doc) *AbsolutePosition {
+	return createAbsolutePositionFromRelativePosition(rpos, doc, true)
+}
+
+// createAbsolutePositionFromRelativePosition followUndoneDeletions 为 true 时沿着撤销管理器重做的项目查找位置
+func createAbsolutePositionFromRelativePosition(rpos *RelativePosition, doc *Doc, followUndoneDeletions bool) *AbsolutePosition {
+	store := doc.Store
+	var t AbstractTypeInterface
+	index := 0
+	if rightID := rpos.Item; rightID != nil {
+		if GetState(store, rightID.Client) <= rightID.Clock {
+			return nil
+		}
+		var s AbstractStructInterface
+		diff := 0
+		if followUndoneDeletions {
+			s, diff = followRedone(store, rightID)
+		} else {
+			s = GetItem(store, rightID)
+			diff = rightID.Clock - s.GetID().Clock
+		}
+		right, ok := s.(*Item)
+		if !ok {
+			return nil
+		}
+		t = right.Parent
+		if typeItem := t.GetItem(); typeItem == nil || !typeItem.GetDeleted() {
+			if !right.GetDeleted() && right.Countable() {
+				index = diff
+				if rpos.Assoc < 0 {
+					index++
+				}
+			}
+			for n := right.Left; n != nil; n = n.Left {
+				if !n.GetDeleted() && n.Countable() {
+					index += n.Length
+				}
+			}
+		}
+	} else {
+		if rpos.Tname != "" {
+			t = doc.Get(rpos.Tname, nil)
+		} else if typeID := rpos.Type; typeID != nil {
+			if GetState(store, typeID.Client) <= typeID.Clock {
+				// 类型尚未收到
+				return nil
+			}
+			var s AbstractStructInterface
+			if followUndoneDeletions {
+				s, _ = followRedone(store, typeID)
+			} else {
+				s = GetItem(store, typeID)
+			}
+			item, ok := s.(*Item)
+			if !ok {
+				return nil
+			}
+			content, ok := item.Content.(*ContentType)
+			if !ok {
+				return nil
+			}
+			t = content.Type
+		} else {
+			panic(util.ErrUnexpectedCase)
+		}
+		if rpos.Assoc >= 0 {
+			index = t.GetLength()
+		}
+	}
+	return NewAbsolutePosition(t, index, rpos.Assoc)
+}
+
+// CompareRelativePositions 判断两个相对位置是否相同
+func CompareRelativePositions(a, b *RelativePosition) bool {
+	return a == b || (a != nil && b != nil && a.Tname == b.Tname && util.CompareIDs(a.Item, b.Item) && util.CompareIDs(a.Type, b.Type) && a.Assoc == b.Assoc)
+}
+
+// WriteRelativePosition 将相对位置写入编码器
+func WriteRelativePosition(encoder *core.Encoder, rpos *RelativePosition) {
+	switch {
+	case rpos.Item != nil:
+		// 情况1：位置在某个字符处
+		encoder.WriteUint8(0)
+		rpos.Item.WriteID(encoder)
+	case rpos.Tname != "":
+		// 情况2：位置在顶层类型的末尾
+		encoder.WriteUint8(1)
+		encoder.WriteString(rpos.Tname)
+	case rpos.Type != nil:
+		// 情况3：位置在嵌套类型的末尾
+		encoder.WriteUint8(2)
+		rpos.Type.WriteID(encoder)
+	default:
+		panic(util.ErrUnexpectedCase)
+	}
+	encoder.WriteVarInt(rpos.Assoc)
+}
+
+// EncodeRelativePosition 将相对位置编码为字节数组
+func EncodeRelativePosition(rpos *RelativePosition) []byte {
+	encoder := core.CreateEncoder()
+	WriteRelativePosition(encoder, rpos)
+	return encoder.ToBytes()
+}
+
+// ReadRelativePosition 从解码器读取相对位置
+func ReadRelativePosition(decoder *core.Decoder) *RelativePosition {
+	rpos := NewRelativePosition(nil, "", nil, 0)
+	switch decoder.ReadVarUint() {
+	case 0:
+		rpos.Item = util.ReadID(decoder)
+	case 1:
+		rpos.Tname = decoder.ReadVarString()
+	case 2:
+		rpos.Type = util.ReadID(decoder)
+	}
+	// 旧版本的编码中没有 assoc
+	if decoder.HasContent() {
+		rpos.Assoc = decoder.ReadVarInt()
+	}
+	return rpos
+}
+
+// DecodeRelativePosition 从字节数组解码相对位置
+func DecodeRelativePosition(buf []byte) *RelativePosition {
+	return ReadRelativePosition(core.CreateDecoder(buf))
+}
@@ -0,0 +1,43 @@
+package struts
+
+import "CollabEdit/util"
+
+// structSkipRefNumber Skip 结构在编码中的引用编号
+const structSkipRefNumber = 10
+
+// Skip 表示合并更新时跳过的一段缺失结构，它不会被整合到文档中
+type Skip struct {
+	*AbstractStruct
+}
+
+func NewSkip(id *util.ID, length int) *Skip {
+	return &Skip{
+		AbstractStruct: NewAbstractStruct(id, length),
+	}
+}
+
+// GetDeleted Skip 结构总是处于删除状态
+func (s *Skip) GetDeleted() bool {
+	return true
+}
+
+// MergeWith 合并右侧相邻的 Skip 结构
+func (s *Skip) MergeWith(right AbstractStructInterface) bool {
+	r, ok := right.(*Skip)
+	if !ok {
+		return false
+	}
+	s.Length += r.Length
+	return true
+}
+
+// Integrate Skip 结构不能被整合
+func (s *Skip) Integrate(transaction *Transaction, offset int) {
+	panic(util.ErrUnexpectedCase)
+}
+
+// Write 将 Skip 结构写入编码器
+func (s *Skip) Write(encoder util.UpdateEncoderInterface, offset int) {
+	encoder.WriteInfo(structSkipRefNumber)
+	encoder.RestEncoder().WriteVarUint(uint(s.Length - offset))
+}
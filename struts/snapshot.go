@@ -0,0 +1,109 @@
+package struts
+
+import (
+	"CollabEdit/core"
+	"CollabEdit/util"
+)
+
+// CreateSnapshot 创建文档当前状态的快照
+func CreateSnapshot(doc *Doc) *util.Snapshot {
+	return util.NewSnapshot(CreateDeleteSetFromStructStore(doc.Store), GetStateVector(doc.Store))
+}
+
+// encodeSnapshotWithEncoder 使用给定的编码器编码快照
+func encodeSnapshotWithEncoder(snapshot *util.Snapshot, encoder util.DSEncoderInterface) []byte {
+	util.WriteDeleteSet(encoder, snapshot.Ds)
+	WriteStateVector(encoder, snapshot.Sv)
+	return encoder.ToBytes()
+}
+
+// EncodeSnapshot 使用 V1 格式编码快照
+func EncodeSnapshot(snapshot *util.Snapshot) []byte {
+	return encodeSnapshotWithEncoder(snapshot, util.NewDSEncoderV1())
+}
+
+// EncodeSnapshotV2 使用 V2 格式编码快照
+func EncodeSnapshotV2(snapshot *util.Snapshot) []byte {
+	return encodeSnapshotWithEncoder(snapshot, util.NewDSEncoderV2())
+}
+
+// decodeSnapshotWithDecoder 使用给定的解码器解码快照
+func decodeSnapshotWithDecoder(decoder util.DSDecoderInterface) *util.Snapshot {
+	return util.NewSnapshot(util.ReadDeleteSet(decoder), ReadStateVector(decoder))
+}
+
+// DecodeSnapshot 解码 V1 格式的快照
+func DecodeSnapshot(buf []byte) *util.Snapshot {
+	return decodeSnapshotWithDecoder(util.NewDSDecoderV1(core.CreateDecoder(buf)))
+}
+
+// DecodeSnapshotV2 解码 V2 格式的快照
+func DecodeSnapshotV2(buf []byte) *util.Snapshot {
+	return decodeSnapshotWithDecoder(util.NewDSDecoderV2(core.CreateDecoder(buf)))
+}
+
+// snapshotContainsUpdateWith 判断快照是否已经包含更新中的所有结构与删除
+func snapshotContainsUpdateWith(snapshot *util.Snapshot, decoder util.UpdateDecoderInterface) bool {
+	lazyDecoder := NewLazyStructReader(decoder, false)
+	for curr := lazyDecoder.Curr; curr != nil; curr = lazyDecoder.Next() {
+		if snapshot.Sv[curr.GetID().Client] < curr.GetID().Clock+curr.GetLength() {
+			return false
+		}
+	}
+	mergedDS := util.MergeDeleteSets([]*util.DeleteSet{snapshot.Ds, util.ReadDeleteSet(decoder)})
+	return util.EqualDeleteSets(snapshot.Ds, mergedDS)
+}
+
+// SnapshotContainsUpdate 判断快照是否已经包含 V1 格式的更新
+func SnapshotContainsUpdate(snapshot *util.Snapshot, update []byte) bool {
+	return snapshotContainsUpdateWith(snapshot, util.NewUpdateDecoderV1(core.CreateDecoder(update)))
+}
+
+// SnapshotContainsUpdateV2 判断快照是否已经包含 V2 格式的更新
+func SnapshotContainsUpdateV2(snapshot *util.Snapshot, update []byte) bool {
+	return snapshotContainsUpdateWith(snapshot, util.NewUpdateDecoderV2(core.CreateDecoder(update)))
+}
+
+// CreateDocFromSnapshot 创建一个新文档，其内容为原文档在快照时的状态
+// 启用垃圾回收的文档无法保留被删除的内容，原文档必须禁用垃圾回收
+func CreateDocFromSnapshot(originDoc *Doc, snapshot *util.Snapshot) *Doc {
+	if originDoc.Gc {
+		panic(util.ErrGCEnabled)
+	}
+	encoder := util.NewUpdateEncoderV2()
+	originDoc.Transact(func(transaction *Transaction) {
+		size := 0
+		for _, clock := range snapshot.Sv {
+			if clock > 0 {
+				size++
+			}
+		}
+		encoder.RestEncoder().WriteVarUint(uint(size))
+		// 客户端按 ID 降序写入，与状态向量的编码保持一致
+		for _, client := range sortedClientsDesc(snapshot.Sv) {
+			clock := snapshot.Sv[client]
+			if clock == 0 {
+				continue
+			}
+			if clock < GetState(originDoc.Store, client) {
+				GetItemCleanStart(transaction, util.NewID(client, clock))
+			}
+			var structs []AbstractStructInterface
+			if s, exists := originDoc.Store.Clients[client]; exists {
+				structs = *s
+			}
+			lastStructIndex := FindIndexSS(structs, clock-1)
+			// 写入结构数量、客户端与第一个时钟
+			encoder.RestEncoder().WriteVarUint(uint(lastStructIndex + 1))
+			encoder.WriteClient(client)
+			encoder.RestEncoder().WriteVarUint(0)
+			for i := 0; i <= lastStructIndex; i++ {
+				structs[i].Write(encoder, 0)
+			}
+		}
+		util.WriteDeleteSet(encoder, snapshot.Ds)
+	}, nil, true)
+	newDoc := NewDoc(nil)
+	ApplyUpdateV2(newDoc, encoder.ToBytes(), "snapshot")
+	return newDoc
+}
@@ -0,0 +1,196 @@
+package struts
+
+import (
+	"CollabEdit/util"
+	"fmt"
+	"math"
+)
+
+// PendingStructs 定义了 missing 和 update 字段
+type PendingStructs struct {
+	Missing map[int]int // 使用 map[int]int 表示 JavaScript 中的 Map<number, number>
+	Update  []byte      // 使用 []byte 表示 JavaScript 中的 Uint8Array
+}
+
+type StructStore struct {
+	Clients        map[int]*[]AbstractStructInterface
+	PendingStructs *PendingStructs
+	PendingDs      []byte
+}
+
+func NewStructStore() *StructStore {
+	return &StructStore{
+		Clients:        make(map[int]*[]AbstractStructInterface),
+		PendingStructs: nil,
+		PendingDs:      nil,
+	}
+}
+
+// GetState 获取给定客户端在存储中的当前状态
+func GetState(store *StructStore, client int) int {
+	// 获取客户端的结构体数组
+	structs, exists := store.Clients[client]
+	if !exists {
+		// 如果客户端不存在，返回0
+		return 0
+	}
+	// 获取最后一个结构体
+	lastStruct := (*structs)[len(*structs)-1]
+	// 返回最后一个结构体的时钟值加上其长度
+	return lastStruct.GetID().Clock + lastStruct.GetLength()
+}
+
+// GetStateVector 获取存储中所有客户端的状态向量
+func GetStateVector(store *StructStore) map[int]int {
+	sm := make(map[int]int, len(store.Clients))
+	for client, structs := range store.Clients {
+		lastStruct := (*structs)[len(*structs)-1]
+		sm[client] = lastStruct.GetID().Clock + lastStruct.GetLength()
+	}
+	return sm
+}
+
+// FindIndexSS 在排序数组上执行二分查找
+func FindIndexSS(structs []AbstractStructInterface, clock int) int {
+	left := 0                 // 左边界
+	right := len(structs) - 1 // 右边界
+
+	// 获取右边界对应的项目
+	mid := structs[right]
+	midclock := mid.GetID().Clock
+
+	// 如果右边界的时钟值等于给定时钟值，直接返回右边界索引
+	if midclock == clock {
+		return right
+	}
+
+	// 计算初始中间索引，使用时钟值比例来进行搜索枢轴
+	midindex := int(math.Floor(float64(clock) / float64(midclock+mid.GetLength()-1) * float64(right)))
+
+	// 执行二分查找
+	for left <= right {
+		mid = structs[midindex]      // 获取中间项目
+		midclock = mid.GetID().Clock // 获取中间项目的时钟值
+
+		// 检查中间项目的时钟值范围
+		if midclock <= clock {
+			if clock < midclock+mid.GetLength() {
+				return midindex // 找到对应索引
+			}
+			left = midindex + 1 // 调整左边界
+		} else {
+			right = midindex - 1 // 调整右边界
+		}
+		midindex = (left + right) / 2 // 计算新的中间索引
+	}
+
+	// 未找到对应的项目，抛出错误
+	panic(util.ErrUnexpectedCase)
+}
+
+// GetItemCleanEnd 获取项目并确保其结束时清理
+func GetItemCleanEnd(transaction *Transaction, store *StructStore, id *util.ID) *Item {
+	// 获取给定客户端ID的项目列表
+	structs, ok := store.Clients[id.Client]
+	if !ok {
+		panic(fmt.Sprintf("Client ID %d 在内存中不存在", id.Client))
+	}
+
+	// 查找给定时钟位置的项目索引
+	index := FindIndexSS(*structs, id.Clock)
+	structItem := (*structs)[index]
+	//类型转换
+	item, ok := structItem.(*Item)
+	if !ok {
+		panic(util.ErrUnexpectedCase)
+	}
+
+	// 检查并确保项目的结束时间符合要求
+	if id.Clock != structItem.GetID().Clock+structItem.GetLength()-1 {
+		// 分割项目并插入到列表中
+		newItem := SplitItem(transaction, item, id.Clock-structItem.GetID().Clock+1)
+		*structs = append((*structs)[:index+1], append([]AbstractStructInterface{newItem}, (*structs)[index+1:]...)...)
+	}
+	return item
+}
+
+// AddStruct 将结构体追加到对应客户端的结构体列表末尾
+// 结构体的时钟必须紧接在该客户端当前状态之后
+func AddStruct(store *StructStore, s AbstractStructInterface) {
+	structs, exists := store.Clients[s.GetID().Client]
+	if !exists {
+		structs = &[]AbstractStructInterface{}
+		store.Clients[s.GetID().Client] = structs
+	} else {
+		lastStruct := (*structs)[len(*structs)-1]
+		if lastStruct.GetID().Clock+lastStruct.GetLength() != s.GetID().Clock {
+			panic(util.ErrUnexpectedCase)
+		}
+	}
+	*structs = append(*structs, s)
+}
+
+// ReplaceStruct 使用 newStruct 替换存储中的结构体，两者的 ID 与长度必须相同
+func ReplaceStruct(store *StructStore, s AbstractStructInterface, newStruct AbstractStructInterface) {
+	structs := store.Clients[s.GetID().Client]
+	(*structs)[FindIndexSS(*structs, s.GetID().Clock)] = newStruct
+}
+
+// followRedone 沿着 Redone 找到项目最终被重做的结构，diff 为 id 在该结构中的偏移
+func followRedone(store *StructStore, id *util.ID) (AbstractStructInterface, int) {
+	nextID := id
+	diff := 0
+	var s AbstractStructInterface
+	for {
+		if diff > 0 {
+			nextID = util.NewID(nextID.Client, nextID.Clock+diff)
+		}
+		s = GetItem(store, nextID)
+		diff = nextID.Clock - s.GetID().Clock
+		item, ok := s.(*Item)
+		if !ok || item.Redone == nil {
+			break
+		}
+		nextID = item.Redone
+	}
+	return s, diff
+}
+
+// Find 查找包含给定ID的结构体
+func Find(store *StructStore, id *util.ID) AbstractStructInterface {
+	structs, ok := store.Clients[id.Client]
+	if !ok {
+		panic(fmt.Sprintf("Client ID %d 在内存中不存在", id.Client))
+	}
+	return (*structs)[FindIndexSS(*structs, id.Clock)]
+}
+
+// GetItem 查找包含给定ID的结构体，不会分割项目
+func GetItem(store *StructStore, id *util.ID) AbstractStructInterface {
+	return Find(store, id)
+}
+
+// FindIndexCleanStart 查找包含给定时钟的结构体索引，必要时分割项目，使结构体恰好从该时钟开始
+func FindIndexCleanStart(transaction *Transaction, structs *[]AbstractStructInterface, clock int) int {
+	index := FindIndexSS(*structs, clock)
+	s := (*structs)[index]
+	if item, ok := s.(*Item); ok && s.GetID().Clock < clock {
+		newItem := SplitItem(transaction, item, clock-s.GetID().Clock)
+		*structs = append((*structs)[:index+1], append([]AbstractStructInterface{newItem}, (*structs)[index+1:]...)...)
+		return index + 1
+	}
+	return index
+}
+
+// GetItemCleanStart 获取项目并确保其从给定ID开始
+func GetItemCleanStart(transaction *Transaction, id *util.ID) *Item {
+	structs, ok := transaction.Doc.Store.Clients[id.Client]
+	if !ok {
+		panic(fmt.Sprintf("Client ID %d 在内存中不存在", id.Client))
+	}
+	item, ok := (*structs)[FindIndexCleanStart(transaction, structs, id.Clock)].(*Item)
+	if !ok {
+		panic(util.ErrUnexpectedCase)
+	}
+	return item
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"CollabEdit/core"
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"CollabEdit/util"
+	"reflect"
+	"testing"
+)
+
+const testTypeRef = 99
+
+// refType 只写入类型引用的测试类型
+type refType struct {
+	*types.AbstractType
+}
+
+func (r *refType) Write(encoder util.EncoderInterface) {
+	encoder.WriteTypeRef(testTypeRef)
+}
+
+func init() {
+	struts.RegisterTypeRef(testTypeRef, func(decoder util.UpdateDecoderInterface) struts.AbstractTypeInterface {
+		return &refType{AbstractType: types.NewAbstractType()}
+	})
+}
+
+// roundTrip 将内容写入 V1 编码器后再读取出来
+func roundTrip(content struts.AbstractContentInterface, offset int) struts.AbstractContentInterface {
+	encoder := util.NewUpdateEncoderV1()
+	content.Write(encoder, offset)
+	decoder := util.NewUpdateDecoderV1(core.CreateDecoder(encoder.ToBytes()))
+	return struts.ReadItemContent(decoder, byte(content.GetRef()))
+}
+
+func TestContentRoundTrip(t *testing.T) {
+	subDoc := struts.NewDoc(&struts.DocOpts{GC: true, Guid: "sub", AutoLoad: true})
+	contents := []struts.AbstractContentInterface{
+		struts.NewContentDeleted(3),
+		struts.NewContentJSON([]interface{}{"a", float64(1), nil, map[string]interface{}{"b": true}}),
+		struts.NewContentBinary([]byte{1, 2, 3}),
+		struts.NewContentString("hello"),
+		struts.NewContentEmbed(map[string]interface{}{"image": "x.png"}),
+		struts.NewContentFormat("bold", true),
+		struts.NewContentType(&refType{AbstractType: types.NewAbstractType()}),
+		struts.NewContentAny([]interface{}{"a", 1, -300, 1.5, 0.1, true, nil, []byte{7}, []interface{}{1, "x"}, map[string]interface{}{"k": "v"}}),
+		struts.NewContentDoc(subDoc),
+	}
+	for ref, content := range contents {
+		if content.GetRef() != ref+1 {
+			t.Fatalf("期望内容引用为 %d, 但得到 %d", ref+1, content.GetRef())
+		}
+		decoded := roundTrip(content, 0)
+		if reflect.TypeOf(decoded) != reflect.TypeOf(content) {
+			t.Fatalf("引用 %d 期望类型 %T, 但得到 %T", ref+1, content, decoded)
+		}
+		if decoded.GetLength() != content.GetLength() || decoded.IsCountable() != content.IsCountable() {
+			t.Errorf("引用 %d 的长度或可计数属性不一致", ref+1)
+		}
+		switch c := content.(type) {
+		case *struts.ContentType:
+			if _, ok := decoded.(*struts.ContentType).Type.(*refType); !ok {
+				t.Errorf("期望读取到注册的测试类型")
+			}
+		case *struts.ContentDoc:
+			d := decoded.(*struts.ContentDoc)
+			if d.Doc.Guid != c.Doc.Guid || !d.Opts.AutoLoad || !d.Opts.GC {
+				t.Errorf("子文档选项不一致: %+v", d.Opts)
+			}
+		case *struts.ContentFormat:
+			d := decoded.(*struts.ContentFormat)
+			if d.Key != c.Key || d.Value != c.Value {
+				t.Errorf("格式化属性不一致: %v=%v", d.Key, d.Value)
+			}
+		case *struts.ContentAny:
+			expected := []interface{}{"a", 1, -300, 1.5, 0.1, true, nil, []byte{7}, []interface{}{1, "x"}, map[string]interface{}{"k": "v"}}
+			if got := decoded.GetContent(); !reflect.DeepEqual(got, expected) {
+				t.Errorf("期望 %v, 但得到 %v", expected, got)
+			}
+		default:
+			if !reflect.DeepEqual(decoded.GetContent(), content.GetContent()) {
+				t.Errorf("引用 %d 期望 %v, 但得到 %v", ref+1, content.GetContent(), decoded.GetContent())
+			}
+		}
+	}
+}
+
+func TestContentStringUTF16(t *testing.T) {
+	content := struts.NewContentString("a😀b")
+	if content.GetLength() != 4 {
+		t.Fatalf("期望 UTF-16 长度为 4, 但得到 %d", content.GetLength())
+	}
+	if got := roundTrip(content, 1).(*struts.ContentString).Str; got != "😀b" {
+		t.Errorf("期望从偏移量 1 写入 😀b, 但得到 %q", got)
+	}
+	// 在代理对中间分割时两侧都会被替换为 U+FFFD
+	right := content.Splice(2).(*struts.ContentString)
+	if content.Str != "a�" || right.Str != "�b" {
+		t.Errorf("分割结果不正确: %q %q", content.Str, right.Str)
+	}
+	left := struts.NewContentString("ab")
+	if !left.MergeWith(struts.NewContentString("cd")) || left.Str != "abcd" {
+		t.Errorf("期望合并为 abcd, 但得到 %q", left.Str)
+	}
+}
+
+func TestContentDeletedIntegrate(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	yType := types.NewAbstractType()
+	yType.Integrate(doc, nil)
+	doc.Transact(func(transaction *struts.Transaction) {
+		item := struts.NewItem(util.NewID(1, 0), nil, nil, nil, nil, yType, "", struts.NewContentDeleted(2))
+		item.Integrate(transaction, 0)
+		if !item.GetDeleted() || !transaction.DeleteSet.IsDeleted(util.NewID(1, 1)) {
+			t.Errorf("已删除内容整合后应被标记删除并加入删除集合")
+		}
+	}, nil, false)
+	if yType.GetLength() != 0 {
+		t.Errorf("已删除内容不应计入长度")
+	}
+}
@@ -0,0 +1,63 @@
+package test
+
+import (
+	"CollabEdit/core"
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"CollabEdit/util"
+	"reflect"
+	"testing"
+)
+
+// newListDoc 创建一个包含由客户端 1 依次插入的 a b c 的文档
+func newListDoc() (*struts.Doc, *types.AbstractType) {
+	doc := struts.NewDoc(nil)
+	yType := types.NewAbstractType()
+	yType.Integrate(doc, nil)
+	var origin *util.ID
+	for clock, value := range []interface{}{"a", "b", "c"} {
+		integrateRemote(doc, yType, remoteItem{id: util.NewID(1, clock), origin: origin, value: value})
+		origin = util.NewID(1, clock)
+	}
+	return doc, yType
+}
+
+func TestDeleteSetTravelsBetweenDocs(t *testing.T) {
+	doc1, type1 := newListDoc()
+	doc2, type2 := newListDoc()
+
+	deleted := util.NewDeleteSet()
+	util.AddToDeleteSet(deleted, 1, 1, 1)
+	doc1.Transact(func(transaction *struts.Transaction) {
+		struts.IterateDeletedStructs(transaction, deleted, func(s struts.AbstractStructInterface) {
+			if item, ok := s.(*struts.Item); ok {
+				item.Delete(transaction)
+			}
+		})
+	}, nil, true)
+	if got := types.TypeListToArray(type1); !reflect.DeepEqual(got, []interface{}{"a", "c"}) {
+		t.Fatalf("删除后期望 [a c], 但得到 %v", got)
+	}
+
+	ds := struts.CreateDeleteSetFromStructStore(doc1.Store)
+	if got := *ds.Clients[1]; !reflect.DeepEqual(got, []util.DeleteItem{{Clock: 1, Len: 1}}) {
+		t.Fatalf("期望删除集合为 [{1 1}], 但得到 %v", got)
+	}
+	// 客户端 2 的结构尚未到达，这部分删除无法应用
+	util.AddToDeleteSet(ds, 2, 0, 1)
+
+	encoder := util.NewDSEncoderV2()
+	util.WriteDeleteSet(encoder, ds)
+	var unapplied *util.DeleteSet
+	doc2.Transact(func(transaction *struts.Transaction) {
+		decoder := util.NewDSDecoderV2(core.CreateDecoder(encoder.ToBytes()))
+		unapplied = struts.ReadAndApplyDeleteSet(decoder, transaction, doc2.Store)
+	}, nil, false)
+
+	if got := types.TypeListToArray(type2); !reflect.DeepEqual(got, []interface{}{"a", "c"}) {
+		t.Errorf("应用删除集合后期望 [a c], 但得到 %v", got)
+	}
+	if unapplied == nil || len(unapplied.Clients) != 1 || !unapplied.IsDeleted(util.NewID(2, 0)) {
+		t.Errorf("期望返回客户端 2 未应用的删除")
+	}
+}
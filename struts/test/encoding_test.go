@@ -0,0 +1,202 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"reflect"
+	"testing"
+)
+
+// yjsTextUpdate Yjs 中客户端 1 执行 getText("text").insert(0, "abc") 后 encodeStateAsUpdate 的结果
+var yjsTextUpdate = []byte{1, 1, 1, 0, 4, 1, 4, 't', 'e', 'x', 't', 3, 'a', 'b', 'c', 0}
+
+// yjsMapUpdate Yjs 中客户端 1 执行 getMap("map").set("k", 1) 后 encodeStateAsUpdate 的结果
+var yjsMapUpdate = []byte{1, 1, 1, 0, 40, 1, 3, 'm', 'a', 'p', 1, 'k', 1, 125, 1, 0}
+
+func TestEncodeStateAsUpdateMatchesYjs(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	doc.ClientID = 1
+	types.GetText(doc, "text").Insert(0, "abc", nil)
+	if got := struts.EncodeStateAsUpdate(doc, nil); !reflect.DeepEqual(got, yjsTextUpdate) {
+		t.Errorf("期望 %v, 但得到 %v", yjsTextUpdate, got)
+	}
+
+	doc = struts.NewDoc(nil)
+	doc.ClientID = 1
+	types.GetMap(doc, "map").Set("k", 1)
+	if got := struts.EncodeStateAsUpdate(doc, nil); !reflect.DeepEqual(got, yjsMapUpdate) {
+		t.Errorf("期望 %v, 但得到 %v", yjsMapUpdate, got)
+	}
+}
+
+func TestApplyYjsUpdate(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	struts.ApplyUpdate(doc, yjsTextUpdate, nil)
+	if got := types.GetText(doc, "text").ToString(); got != "abc" {
+		t.Errorf("期望 abc, 但得到 %q", got)
+	}
+	// 两个更新都来自客户端 1 的时钟 0，需要分别应用到不同的文档
+	doc = struts.NewDoc(nil)
+	struts.ApplyUpdate(doc, yjsMapUpdate, nil)
+	if got := types.GetMap(doc, "map").Get("k"); got != 1 {
+		t.Errorf("期望 1, 但得到 %v", got)
+	}
+}
+
+func TestApplyUpdateBetweenDocs(t *testing.T) {
+	doc1 := struts.NewDoc(nil)
+	doc1.ClientID = 1
+	doc2 := struts.NewDoc(nil)
+	doc2.ClientID = 2
+
+	arr1 := types.GetArray(doc1, "array")
+	arr1.Push([]interface{}{1, "two", types.NewYMapFrom(map[string]interface{}{"x": true})})
+	text2 := types.GetText(doc2, "text")
+	text2.Insert(0, "hello", nil)
+	text2.Format(0, 2, map[string]interface{}{"bold": true})
+
+	var origin interface{}
+	doc2.On("afterTransaction", func(args interface{}) {
+		if tr := args.(*struts.Transaction); !tr.Local {
+			origin = tr.Origin
+		}
+	})
+	struts.ApplyUpdate(doc2, struts.EncodeStateAsUpdate(doc1, nil), "remote")
+	struts.ApplyUpdate(doc1, struts.EncodeStateAsUpdate(doc2, nil), nil)
+	if origin != "remote" {
+		t.Errorf("远程事务的来源应为 remote, 但得到 %v", origin)
+	}
+
+	arr1.Delete(0, 1)
+	text2.Delete(4, 1)
+	struts.ApplyUpdate(doc2, struts.EncodeStateAsUpdate(doc1, nil), nil)
+	struts.ApplyUpdate(doc1, struts.EncodeStateAsUpdate(doc2, nil), nil)
+
+	for _, doc := range []*struts.Doc{doc1, doc2} {
+		if got := types.GetArray(doc, "array").ToJSON(); !reflect.DeepEqual(got, []interface{}{"two", map[string]interface{}{"x": true}}) {
+			t.Errorf("数组内容不正确: %v", got)
+		}
+		expected := []types.DeltaOp{{Insert: "he", Attributes: map[string]interface{}{"bold": true}}, {Insert: "ll"}}
+		if got := types.GetText(doc, "text").ToDelta(); !reflect.DeepEqual(got, expected) {
+			t.Errorf("期望 %v, 但得到 %v", expected, got)
+		}
+	}
+	if !reflect.DeepEqual(struts.EncodeStateAsUpdate(doc1, nil), struts.EncodeStateAsUpdate(doc2, nil)) {
+		t.Errorf("同步后两个文档的编码应该相同")
+	}
+}
+
+// TestApplyUpdateOutOfOrder 依赖缺失的结构会被暂存，而不是整合到文档中
+func TestApplyUpdateOutOfOrder(t *testing.T) {
+	doc1 := struts.NewDoc(nil)
+	doc1.ClientID = 1
+	text1 := types.GetText(doc1, "text")
+	text1.Insert(0, "a", nil)
+	first := struts.EncodeStateAsUpdate(doc1, nil)
+	text1.Insert(1, "b", nil)
+	// 只包含第二次插入的更新
+	second := struts.EncodeStateAsUpdate(doc1, []byte{1, 1, 1})
+
+	doc2 := struts.NewDoc(nil)
+	struts.ApplyUpdate(doc2, second, nil)
+	if got := types.GetText(doc2, "text").ToString(); got != "" {
+		t.Fatalf("依赖缺失时不应整合, 但得到 %q", got)
+	}
+	if doc2.Store.PendingStructs == nil || doc2.Store.PendingStructs.Missing[1] != 0 {
+		t.Fatalf("期望暂存缺少客户端 1 时钟 0 的结构")
+	}
+	struts.ApplyUpdate(doc2, first, nil)
+	if got := types.GetText(doc2, "text").ToString(); got != "ab" {
+		t.Errorf("缺失的结构到达后应重新整合暂存的结构, 期望 ab, 但得到 %q", got)
+	}
+	if doc2.Store.PendingStructs != nil {
+		t.Errorf("整合完成后不应再有暂存的结构")
+	}
+}
+
+// TestPendingUpdatesMerged 多个依赖缺失的更新会被合并暂存，删除集合也会等到结构到达后应用
+func TestPendingUpdatesMerged(t *testing.T) {
+	doc1 := struts.NewDoc(nil)
+	doc1.ClientID = 1
+	text1 := types.GetText(doc1, "text")
+	var updates [][]byte
+	for i, c := range []string{"a", "b", "c"} {
+		sv := struts.EncodeStateVectorFromMap(map[int]int{1: i})
+		text1.Insert(i, c, nil)
+		updates = append(updates, struts.EncodeStateAsUpdate(doc1, sv))
+	}
+	text1.Delete(1, 1)
+	deletion := struts.EncodeStateAsUpdate(doc1, struts.EncodeStateVector(doc1))
+
+	doc2 := struts.NewDoc(nil)
+	struts.ApplyUpdate(doc2, deletion, nil)
+	struts.ApplyUpdate(doc2, updates[2], nil)
+	struts.ApplyUpdate(doc2, updates[1], nil)
+	if doc2.Store.PendingDs == nil {
+		t.Fatalf("期望暂存无法应用的删除集合")
+	}
+	missing := doc2.Missing()
+	if len(missing) != 1 || missing[0] != (struts.MissingRange{Client: 1, From: 0, To: 0}) {
+		t.Fatalf("期望等待客户端 1 时钟 0 的结构, 但得到 %v", missing)
+	}
+	struts.ApplyUpdate(doc2, updates[0], nil)
+	if got := types.GetText(doc2, "text").ToString(); got != "ac" {
+		t.Errorf("期望 ac, 但得到 %q", got)
+	}
+	if doc2.Store.PendingStructs != nil || doc2.Store.PendingDs != nil || len(doc2.Missing()) != 0 {
+		t.Errorf("所有结构到达后不应再有暂存的更新")
+	}
+}
+
+// yjsTextUpdateV2 Yjs 中客户端 1 执行 getText("text").insert(0, "abc") 后 encodeStateAsUpdateV2 的结果
+var yjsTextUpdateV2 = []byte{
+	0,    // 功能标志
+	0,    // 键时钟
+	1, 1, // 客户端
+	0, 0, // 左右时钟
+	1, 4, // 信息
+	10, 7, 't', 'e', 'x', 't', 'a', 'b', 'c', 4, 3, // 字符串
+	1, 1, // 父信息
+	0, 0, // 类型引用与长度
+	1, 1, 0, 0, // rest 数据
+}
+
+func TestEncodeStateAsUpdateV2MatchesYjs(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	doc.ClientID = 1
+	types.GetText(doc, "text").Insert(0, "abc", nil)
+	if got := struts.EncodeStateAsUpdateV2(doc, nil); !reflect.DeepEqual(got, yjsTextUpdateV2) {
+		t.Errorf("期望 %v, 但得到 %v", yjsTextUpdateV2, got)
+	}
+	doc = struts.NewDoc(nil)
+	struts.ApplyUpdateV2(doc, yjsTextUpdateV2, nil)
+	if got := types.GetText(doc, "text").ToString(); got != "abc" {
+		t.Errorf("期望 abc, 但得到 %q", got)
+	}
+}
+
+func TestApplyUpdateV2BetweenDocs(t *testing.T) {
+	doc1 := struts.NewDoc(nil)
+	doc1.ClientID = 1
+	fragment := types.GetXmlFragment(doc1, "xml")
+	el := types.NewYXmlElement("p")
+	el.SetAttribute("class", "a")
+	text := types.NewYXmlText("hello")
+	text.Format(0, 2, map[string]interface{}{"bold": true})
+	el.Push([]interface{}{text})
+	fragment.Push([]interface{}{el, types.NewYXmlHook("image")})
+	types.GetMap(doc1, "map").Set("bin", []byte{1, 2, 3})
+	text.Delete(4, 1)
+
+	doc2 := struts.NewDoc(nil)
+	struts.ApplyUpdateV2(doc2, struts.EncodeStateAsUpdateV2(doc1, nil), nil)
+	if got := types.GetXmlFragment(doc2, "xml").ToString(); got != fragment.ToString() {
+		t.Errorf("期望 %s, 但得到 %s", fragment.ToString(), got)
+	}
+	if got := types.GetMap(doc2, "map").Get("bin"); !reflect.DeepEqual(got, []byte{1, 2, 3}) {
+		t.Errorf("二进制内容不正确: %v", got)
+	}
+	if !reflect.DeepEqual(struts.EncodeStateAsUpdate(doc1, nil), struts.EncodeStateAsUpdate(doc2, nil)) {
+		t.Errorf("V2 同步后两个文档的编码应该相同")
+	}
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"testing"
+)
+
+// clientStructs 返回文档中当前客户端的所有结构
+func clientStructs(doc *struts.Doc) []struts.AbstractStructInterface {
+	return *doc.Store.Clients[doc.ClientID]
+}
+
+// TestGCReplacesDeletedContent 被删除的内容替换为 ContentDeleted，相邻的项目会被合并
+func TestGCReplacesDeletedContent(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	text := types.GetText(doc, "text")
+	for i, c := range []string{"a", "b", "c", "d"} {
+		text.Insert(i, c, nil)
+	}
+	text.Delete(0, 2)
+	structs := clientStructs(doc)
+	if len(structs) != 2 {
+		t.Fatalf("期望合并为 2 个结构, 但得到 %d", len(structs))
+	}
+	if _, ok := structs[0].(*struts.Item).Content.(*struts.ContentDeleted); !ok {
+		t.Errorf("被删除的内容应替换为 ContentDeleted")
+	}
+	if got := text.ToString(); got != "cd" {
+		t.Errorf("期望 cd, 但得到 %q", got)
+	}
+}
+
+// TestGCCollapsesDeletedTypes 被删除的类型中的所有项目都替换为 GC 结构并合并
+func TestGCCollapsesDeletedTypes(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	arr := types.GetArray(doc, "array")
+	arr.Insert(0, []interface{}{types.NewYMapFrom(map[string]interface{}{"a": 1, "b": 2})})
+	ymap := arr.Get(0).(*types.YMap)
+	ymap.Set("a", 3)
+	arr.Delete(0, 1)
+	structs := clientStructs(doc)
+	if len(structs) != 2 {
+		t.Fatalf("期望 2 个结构, 但得到 %d", len(structs))
+	}
+	if _, ok := structs[0].(*struts.Item).Content.(*struts.ContentDeleted); !ok {
+		t.Errorf("被删除的类型应替换为 ContentDeleted")
+	}
+	gc, ok := structs[1].(*struts.GC)
+	if !ok || gc.Length != 3 {
+		t.Errorf("类型中的项目应合并为长度为 3 的 GC 结构")
+	}
+	// 回收后的文档仍然可以同步到其他文档
+	doc2 := struts.NewDoc(nil)
+	struts.ApplyUpdate(doc2, struts.EncodeStateAsUpdate(doc, nil), nil)
+	if got := types.GetArray(doc2, "array").Length(); got != 0 {
+		t.Errorf("期望空数组, 但长度为 %d", got)
+	}
+}
+
+func TestGCFilterAndKeep(t *testing.T) {
+	doc := struts.NewDoc(&struts.DocOpts{GC: true, GCFilter: func(item *struts.Item) bool {
+		return item.ParentSub != "b"
+	}})
+	ymap := types.GetMap(doc, "map")
+	for _, key := range []string{"a", "b", "c"} {
+		ymap.Set(key, key)
+	}
+	struts.KeepItem(clientStructs(doc)[2].(*struts.Item), true)
+	for _, key := range []string{"a", "b", "c"} {
+		ymap.Delete(key)
+	}
+	for i, s := range clientStructs(doc) {
+		_, deleted := s.(*struts.Item).Content.(*struts.ContentDeleted)
+		if deleted != (i == 0) {
+			t.Errorf("结构 %d 的回收状态错误", i)
+		}
+	}
+}
+
+func TestGCDisabled(t *testing.T) {
+	doc := struts.NewDoc(&struts.DocOpts{GC: false})
+	text := types.GetText(doc, "text")
+	text.Insert(0, "abc", nil)
+	text.Delete(0, 3)
+	content, ok := clientStructs(doc)[0].(*struts.Item).Content.(*struts.ContentString)
+	if !ok || content.Str != "abc" {
+		t.Errorf("禁用垃圾回收时应保留被删除的内容")
+	}
+}
@@ -0,0 +1,113 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"CollabEdit/util"
+	"reflect"
+	"testing"
+)
+
+// remoteItem 描述一个远程客户端创建的项目
+type remoteItem struct {
+	id          *util.ID
+	origin      *util.ID
+	rightOrigin *util.ID
+	parentSub   string
+	value       interface{}
+}
+
+// integrateRemote 按照 origin 查找左右项目后整合，模拟远程项目的整合过程
+func integrateRemote(doc *struts.Doc, parent struts.AbstractTypeInterface, r remoteItem) {
+	doc.Transact(func(transaction *struts.Transaction) {
+		var left, right *struts.Item
+		if r.origin != nil {
+			left = struts.GetItemCleanEnd(transaction, doc.Store, r.origin)
+		}
+		if r.rightOrigin != nil {
+			right = struts.GetItemCleanStart(transaction, r.rightOrigin)
+		}
+		id := util.NewID(r.id.Client, r.id.Clock)
+		item := struts.NewItem(id, left, r.origin, right, r.rightOrigin, parent, r.parentSub, struts.NewContentAny([]interface{}{r.value}))
+		item.Integrate(transaction, 0)
+	}, nil, false)
+}
+
+func TestIntegrateConvergesInAnyOrder(t *testing.T) {
+	a := remoteItem{id: util.NewID(1, 0), value: "a"}
+	b := remoteItem{id: util.NewID(2, 0), origin: util.NewID(1, 0), value: "b"}
+	c := remoteItem{id: util.NewID(3, 0), origin: util.NewID(1, 0), value: "c"}
+	d := remoteItem{id: util.NewID(3, 1), rightOrigin: util.NewID(1, 0), value: "d"}
+	e := remoteItem{id: util.NewID(2, 1), origin: util.NewID(2, 0), value: "e"}
+
+	orders := [][]remoteItem{
+		{a, b, e, c, d},
+		{a, c, d, b, e},
+		{a, c, b, d, e},
+		{a, b, c, e, d},
+	}
+	expected := []interface{}{"d", "a", "b", "e", "c"}
+	for _, order := range orders {
+		doc := struts.NewDoc(nil)
+		yType := types.NewAbstractType()
+		yType.Integrate(doc, nil)
+		for _, r := range order {
+			integrateRemote(doc, yType, r)
+		}
+		if got := types.TypeListToArray(yType); !reflect.DeepEqual(got, expected) {
+			t.Errorf("期望内容为 %v, 但得到 %v", expected, got)
+		}
+		if yType.GetLength() != len(expected) {
+			t.Errorf("期望长度为 %d, 但得到 %d", len(expected), yType.GetLength())
+		}
+	}
+}
+
+func TestIntegrateParentSubLastWriterWins(t *testing.T) {
+	first := remoteItem{id: util.NewID(1, 0), parentSub: "k", value: 1}
+	second := remoteItem{id: util.NewID(2, 0), parentSub: "k", value: 2}
+	for _, order := range [][]remoteItem{{first, second}, {second, first}} {
+		doc := struts.NewDoc(nil)
+		yType := types.NewAbstractType()
+		yType.Integrate(doc, nil)
+		for _, r := range order {
+			integrateRemote(doc, yType, r)
+		}
+		current := yType.GetDataMap()["k"]
+		if current == nil || current.GetDeleted() {
+			t.Fatalf("期望映射中存在未删除的值")
+		}
+		if got := current.Content.GetContent()[0]; got != 2 {
+			t.Errorf("期望客户端ID较大的写入获胜, 但得到 %v", got)
+		}
+		if current.Left == nil || !current.Left.GetDeleted() {
+			t.Errorf("期望被覆盖的值被删除")
+		}
+		if yType.GetLength() != 0 {
+			t.Errorf("映射项目不应计入长度")
+		}
+	}
+}
+
+// TestIntegrateAfterGC 项目与已回收的结构重叠时，剩余部分同样整合为 GC 结构
+func TestIntegrateAfterGC(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	yType := types.NewAbstractType()
+	yType.Integrate(doc, nil)
+	doc.Transact(func(transaction *struts.Transaction) {
+		struts.NewGC(util.NewID(2, 0), 1).Integrate(transaction, 0)
+		item := struts.NewItem(util.NewID(2, 0), nil, nil, nil, nil, yType, "", struts.NewContentAny([]interface{}{"a", "b"}))
+		item.Integrate(transaction, 1)
+	}, nil, false)
+	if got := struts.GetState(doc.Store, 2); got != 2 {
+		t.Errorf("期望客户端 2 的状态为 2, 但得到 %d", got)
+	}
+	for _, s := range *doc.Store.Clients[2] {
+		if _, ok := s.(*struts.GC); !ok {
+			t.Errorf("与回收结构相邻的项目应整合为 GC 结构, 但得到 %T", s)
+		}
+	}
+	if yType.GetLength() != 0 {
+		t.Errorf("期望长度为 0, 但得到 %d", yType.GetLength())
+	}
+}
@@ -0,0 +1,114 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"encoding/json"
+	"testing"
+)
+
+// TestRelativePositionConcurrentChanges 其他客户端的插入与删除不影响相对位置指向的字符
+func TestRelativePositionConcurrentChanges(t *testing.T) {
+	doc1 := struts.NewDoc(nil)
+	doc1.ClientID = 1
+	text1 := types.GetText(doc1, "text")
+	text1.Insert(0, "hello world", nil)
+	doc2 := struts.NewDoc(nil)
+	doc2.ClientID = 2
+	struts.ApplyUpdate(doc2, struts.EncodeStateAsUpdate(doc1, nil), nil)
+
+	rpos := struts.CreateRelativePositionFromTypeIndex(text1, 6, 0)
+	text2 := types.GetText(doc2, "text")
+	text2.Insert(0, ">> ", nil)
+	text2.Delete(3, 2)
+	struts.ApplyUpdate(doc1, struts.EncodeStateAsUpdate(doc2, struts.EncodeStateVector(doc1)), nil)
+	if got := text1.ToString(); got != ">> llo world" {
+		t.Fatalf("期望 >> llo world, 但得到 %q", got)
+	}
+	abs := struts.CreateAbsolutePositionFromRelativePosition(rpos, doc1)
+	if abs == nil || abs.Type != text1 || abs.Index != 7 {
+		t.Errorf("相对位置应指向索引 7, 但得到 %+v", abs)
+	}
+	// 在另一个文档中也能解析出相同的位置
+	abs = struts.CreateAbsolutePositionFromRelativePosition(rpos, doc2)
+	if abs == nil || abs.Type != text2 || abs.Index != 7 {
+		t.Errorf("相对位置应指向索引 7, 但得到 %+v", abs)
+	}
+}
+
+func TestRelativePositionAssoc(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	text := types.GetText(doc, "text")
+	text.Insert(0, "abc", nil)
+	tests := []struct {
+		index, assoc, expected int
+	}{
+		{0, 0, 0}, {0, -1, 0}, {2, 0, 2}, {2, -1, 2}, {3, 0, 3}, {3, -1, 3},
+	}
+	for _, tt := range tests {
+		rpos := struts.CreateRelativePositionFromTypeIndex(text, tt.index, tt.assoc)
+		abs := struts.CreateAbsolutePositionFromRelativePosition(rpos, doc)
+		if abs == nil || abs.Index != tt.expected || abs.Assoc != tt.assoc {
+			t.Errorf("索引 %d assoc %d: 期望 %d, 但得到 %+v", tt.index, tt.assoc, tt.expected, abs)
+		}
+	}
+	// 与左侧关联的位置在左侧插入后保持在字符之后，与右侧关联的位置保持在字符之前
+	left := struts.CreateRelativePositionFromTypeIndex(text, 1, -1)
+	right := struts.CreateRelativePositionFromTypeIndex(text, 1, 0)
+	text.Insert(1, "xy", nil)
+	if abs := struts.CreateAbsolutePositionFromRelativePosition(left, doc); abs.Index != 1 {
+		t.Errorf("与左侧关联的位置应为 1, 但得到 %d", abs.Index)
+	}
+	if abs := struts.CreateAbsolutePositionFromRelativePosition(right, doc); abs.Index != 3 {
+		t.Errorf("与右侧关联的位置应为 3, 但得到 %d", abs.Index)
+	}
+	// 末尾的位置在追加内容后仍然在末尾
+	end := struts.CreateRelativePositionFromTypeIndex(text, text.Length(), 0)
+	text.Insert(text.Length(), "z", nil)
+	if abs := struts.CreateAbsolutePositionFromRelativePosition(end, doc); abs.Index != text.Length() {
+		t.Errorf("末尾的位置应为 %d, 但得到 %d", text.Length(), abs.Index)
+	}
+}
+
+func TestRelativePositionEncoding(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	text := types.GetText(doc, "text")
+	arr := types.GetArray(doc, "array")
+	text.Insert(0, "abc", nil)
+	arr.Insert(0, []interface{}{types.NewYText("nested")})
+	nested := arr.Get(0).(*types.YText)
+	positions := []*struts.RelativePosition{
+		struts.CreateRelativePositionFromTypeIndex(text, 1, 0),
+		struts.CreateRelativePositionFromTypeIndex(text, 3, 0),
+		struts.CreateRelativePositionFromTypeIndex(nested, 6, 0),
+		struts.CreateRelativePositionFromTypeIndex(nested, 0, -1),
+	}
+	if positions[0].Tname != "text" || positions[1].Tname != "text" || positions[2].Type == nil || positions[3].Type == nil {
+		t.Fatalf("位置应通过顶层类型名称或类型ID描述所在的类型")
+	}
+	for i, rpos := range positions {
+		// 二进制编码在有项目时只写入项目
+		expected := rpos
+		if rpos.Item != nil {
+			expected = struts.NewRelativePosition(nil, "", rpos.Item, rpos.Assoc)
+		}
+		if decoded := struts.DecodeRelativePosition(struts.EncodeRelativePosition(rpos)); !struts.CompareRelativePositions(expected, decoded) {
+			t.Errorf("位置 %d 二进制编码后解码的结果不同: %+v", i, decoded)
+		}
+		buf, err := json.Marshal(rpos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := &struts.RelativePosition{}
+		if err := json.Unmarshal(buf, decoded); err != nil || !struts.CompareRelativePositions(rpos, decoded) {
+			t.Errorf("位置 %d JSON 编码后解码的结果不同: %s", i, buf)
+		}
+	}
+	abs := struts.CreateAbsolutePositionFromRelativePosition(positions[2], doc)
+	if abs == nil || abs.Type != nested || abs.Index != 6 {
+		t.Errorf("嵌套类型末尾的位置应为 6, 但得到 %+v", abs)
+	}
+	if struts.CompareRelativePositions(positions[0], positions[1]) {
+		t.Errorf("不同的位置不应相等")
+	}
+}
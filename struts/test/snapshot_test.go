@@ -0,0 +1,79 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"CollabEdit/util"
+	"testing"
+)
+
+func TestSnapshotEncoding(t *testing.T) {
+	doc := struts.NewDoc(&struts.DocOpts{GC: false})
+	doc.ClientID = 1
+	text := types.GetText(doc, "text")
+	text.Insert(0, "abc", nil)
+	text.Delete(1, 1)
+	snap := struts.CreateSnapshot(doc)
+	if !util.EqualSnapshots(snap, struts.DecodeSnapshot(struts.EncodeSnapshot(snap))) {
+		t.Errorf("V1 编码后解码的快照应与原快照相同")
+	}
+	if !util.EqualSnapshots(snap, struts.DecodeSnapshotV2(struts.EncodeSnapshotV2(snap))) {
+		t.Errorf("V2 编码后解码的快照应与原快照相同")
+	}
+	text.Insert(0, "d", nil)
+	if util.EqualSnapshots(snap, struts.CreateSnapshot(doc)) {
+		t.Errorf("文档修改后的快照不应与原快照相同")
+	}
+	if util.EqualSnapshots(util.EmptySnapshot(), snap) {
+		t.Errorf("空快照不应与原快照相同")
+	}
+}
+
+func TestSnapshotContainsUpdate(t *testing.T) {
+	doc := struts.NewDoc(&struts.DocOpts{GC: false})
+	text := types.GetText(doc, "text")
+	text.Insert(0, "abc", nil)
+	insertion := struts.EncodeStateAsUpdate(doc, nil)
+	snap := struts.CreateSnapshot(doc)
+	text.Delete(0, 1)
+	deletion := struts.EncodeStateAsUpdate(doc, struts.EncodeStateVector(doc))
+	if !struts.SnapshotContainsUpdate(snap, insertion) {
+		t.Errorf("快照应包含之前的插入")
+	}
+	if struts.SnapshotContainsUpdate(snap, deletion) {
+		t.Errorf("快照不应包含之后的删除")
+	}
+	if !struts.SnapshotContainsUpdateV2(struts.CreateSnapshot(doc), struts.ConvertUpdateFormatV1ToV2(deletion)) {
+		t.Errorf("新的快照应包含所有更新")
+	}
+}
+
+func TestCreateDocFromSnapshot(t *testing.T) {
+	doc := struts.NewDoc(&struts.DocOpts{GC: false})
+	text := types.GetText(doc, "text")
+	text.Insert(0, "hello world", nil)
+	snap := struts.CreateSnapshot(doc)
+	text.Delete(0, 6)
+	text.Insert(5, "!", nil)
+	if got := text.ToString(); got != "world!" {
+		t.Fatalf("期望 world!, 但得到 %q", got)
+	}
+	restored := struts.CreateDocFromSnapshot(doc, snap)
+	if got := types.GetText(restored, "text").ToString(); got != "hello world" {
+		t.Errorf("期望 hello world, 但得到 %q", got)
+	}
+	if got := text.ToString(); got != "world!" {
+		t.Errorf("原文档不应改变, 但得到 %q", got)
+	}
+}
+
+func TestCreateDocFromSnapshotRequiresGCDisabled(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	types.GetText(doc, "text").Insert(0, "a", nil)
+	defer func() {
+		if r := recover(); r != util.ErrGCEnabled {
+			t.Errorf("期望 ErrGCEnabled, 但得到 %v", r)
+		}
+	}()
+	struts.CreateDocFromSnapshot(doc, struts.CreateSnapshot(doc))
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"reflect"
+	"testing"
+)
+
+func TestStateVectorRoundTrip(t *testing.T) {
+	sv := map[int]int{1: 3, 300: 20, 70000: 1}
+	encoded := struts.EncodeStateVectorFromMap(sv)
+	// 客户端按 ID 降序写入
+	expected := []byte{3, 0xf0, 0xa2, 0x04, 1, 0xac, 0x02, 20, 1, 3}
+	if !reflect.DeepEqual(encoded, expected) {
+		t.Errorf("期望 %v, 但得到 %v", expected, encoded)
+	}
+	if got := struts.DecodeStateVector(encoded); !reflect.DeepEqual(got, sv) {
+		t.Errorf("期望 %v, 但得到 %v", sv, got)
+	}
+
+	doc := struts.NewDoc(nil)
+	doc.ClientID = 5
+	types.GetText(doc, "text").Insert(0, "hello", nil)
+	if got := struts.DecodeStateVector(struts.EncodeStateVector(doc)); !reflect.DeepEqual(got, map[int]int{5: 5}) {
+		t.Errorf("期望 map[5:5], 但得到 %v", got)
+	}
+}
+
+func TestEncodeStateAsUpdateDiff(t *testing.T) {
+	doc1 := struts.NewDoc(nil)
+	doc1.ClientID = 1
+	text1 := types.GetText(doc1, "text")
+	text1.Insert(0, "ab", nil)
+
+	doc2 := struts.NewDoc(nil)
+	doc2.ClientID = 2
+	struts.ApplyUpdate(doc2, struts.EncodeStateAsUpdate(doc1, nil), nil)
+
+	// 两次插入的项目会合并为一个，编码差异时需要从时钟 2 处分割
+	text1.Insert(2, "c", nil)
+	if n := len(*doc1.Store.Clients[1]); n != 1 {
+		t.Fatalf("期望项目被合并为 1 个, 但得到 %d 个", n)
+	}
+	full := struts.EncodeStateAsUpdate(doc1, nil)
+	diff := struts.EncodeStateAsUpdate(doc1, struts.EncodeStateVector(doc2))
+	if len(diff) >= len(full) {
+		t.Errorf("差异更新 (%d 字节) 应小于完整更新 (%d 字节)", len(diff), len(full))
+	}
+	struts.ApplyUpdate(doc2, diff, nil)
+	if got := types.GetText(doc2, "text").ToString(); got != "abc" {
+		t.Errorf("期望 abc, 但得到 %q", got)
+	}
+
+	// 对方已经拥有所有结构时只包含删除集合
+	empty := struts.EncodeStateAsUpdate(doc1, struts.EncodeStateVector(doc2))
+	if !reflect.DeepEqual(empty, []byte{0, 0}) {
+		t.Errorf("期望空更新 [0 0], 但得到 %v", empty)
+	}
+	emptyV2 := struts.EncodeStateAsUpdateV2(doc1, struts.EncodeStateVector(doc2))
+	struts.ApplyUpdateV2(doc2, emptyV2, nil)
+	if got := types.GetText(doc2, "text").ToString(); got != "abc" {
+		t.Errorf("应用空更新后期望 abc, 但得到 %q", got)
+	}
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// subdocGuids 返回子文档集合中排序后的全局唯一标识
+func subdocGuids(docs map[*struts.Doc]struct{}) []string {
+	guids := make([]string, 0, len(docs))
+	for doc := range docs {
+		guids = append(guids, doc.Guid)
+	}
+	sort.Strings(guids)
+	return guids
+}
+
+// observeSubdocs 记录文档最近一次 subdocs 事件中新增、删除与加载的子文档
+func observeSubdocs(doc *struts.Doc) *[3][]string {
+	last := &[3][]string{}
+	doc.On("subdocs", func(args interface{}) {
+		event := args.(*struts.SubdocsEvent)
+		*last = [3][]string{subdocGuids(event.Added), subdocGuids(event.Removed), subdocGuids(event.Loaded)}
+	})
+	return last
+}
+
+func TestSubdocs(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	event := observeSubdocs(doc)
+	subdocs := types.GetMap(doc, "subdocs")
+	expect := func(added, removed, loaded []string) {
+		t.Helper()
+		if expected := [3][]string{added, removed, loaded}; !reflect.DeepEqual(*event, expected) {
+			t.Errorf("期望 subdocs 事件 %v, 但得到 %v", expected, *event)
+		}
+		*event = [3][]string{}
+	}
+
+	docA := struts.NewDoc(&struts.DocOpts{Guid: "a"})
+	docA.Load()
+	subdocs.Set("a", docA)
+	expect([]string{"a"}, []string{}, []string{"a"})
+
+	subdocs.Set("b", struts.NewDoc(&struts.DocOpts{Guid: "a"}))
+	expect([]string{"a"}, []string{}, []string{})
+	subdocs.Get("b").(*struts.Doc).Load()
+	expect([]string{}, []string{}, []string{"a"})
+
+	docC := struts.NewDoc(&struts.DocOpts{Guid: "c", AutoLoad: true})
+	docC.Load()
+	subdocs.Set("c", docC)
+	expect([]string{"c"}, []string{}, []string{"c"})
+	if got := doc.GetSubdocGuids(); !reflect.DeepEqual(got, map[string]struct{}{"a": {}, "c": {}}) {
+		t.Errorf("期望子文档 a 与 c, 但得到 %v", got)
+	}
+	if docC.ClientID != doc.ClientID {
+		t.Errorf("子文档应使用父文档的客户端ID")
+	}
+
+	// 远程文档只会自动加载设置了 AutoLoad 的子文档
+	doc2 := struts.NewDoc(nil)
+	event2 := observeSubdocs(doc2)
+	struts.ApplyUpdate(doc2, struts.EncodeStateAsUpdate(doc, nil), nil)
+	if expected := [3][]string{{"a", "a", "c"}, {}, {"c"}}; !reflect.DeepEqual(*event2, expected) {
+		t.Errorf("期望 subdocs 事件 %v, 但得到 %v", expected, *event2)
+	}
+	subdocs2 := types.GetMap(doc2, "subdocs")
+	remoteA := subdocs2.Get("a").(*struts.Doc)
+	if remoteA.ShouldLoad || !subdocs2.Get("c").(*struts.Doc).ShouldLoad {
+		t.Errorf("只有 c 应被自动加载")
+	}
+	remoteA.Load()
+	if expected := [3][]string{{}, {}, {"a"}}; !reflect.DeepEqual(*event2, expected) {
+		t.Errorf("期望 subdocs 事件 %v, 但得到 %v", expected, *event2)
+	}
+
+	// 删除父项目会销毁子文档
+	subdocs2.Delete("a")
+	if !reflect.DeepEqual((*event2)[1], []string{"a"}) || !remoteA.IsDestroyed {
+		t.Errorf("删除后子文档 a 应被移除并销毁, 事件 %v", *event2)
+	}
+	if got := doc2.GetSubdocGuids(); !reflect.DeepEqual(got, map[string]struct{}{"a": {}, "c": {}}) {
+		t.Errorf("期望子文档 a 与 c, 但得到 %v", got)
+	}
+}
+
+// TestSubdocDestroy 销毁子文档后父文档中的项目引用一份未加载的新文档
+func TestSubdocDestroy(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	subdocs := types.GetMap(doc, "subdocs")
+	subdoc := struts.NewDoc(&struts.DocOpts{Guid: "a", ShouldLoad: true})
+	subdocs.Set("a", subdoc)
+	types.GetText(subdoc, "text").Insert(0, "hello", nil)
+	var destroyed *struts.Doc
+	subdoc.On("destroy", func(args interface{}) { destroyed = args.(*struts.Doc) })
+
+	subdoc.Destroy()
+	if destroyed != subdoc || !subdoc.IsDestroyed || subdoc.Item != nil {
+		t.Fatalf("子文档应被销毁")
+	}
+	fresh := subdocs.Get("a").(*struts.Doc)
+	if fresh == subdoc || fresh.Guid != "a" || fresh.ShouldLoad || fresh.Item == nil {
+		t.Fatalf("父文档中应替换为未加载的新文档")
+	}
+	if _, ok := doc.GetSubdocs()[fresh]; !ok || len(doc.GetSubdocs()) != 1 {
+		t.Errorf("父文档的子文档集合应只包含新文档")
+	}
+	if got := types.GetText(fresh, "text").ToString(); got != "" {
+		t.Errorf("新文档应为空, 但得到 %q", got)
+	}
+}
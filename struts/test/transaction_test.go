@@ -0,0 +1,116 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"CollabEdit/util"
+	"reflect"
+	"testing"
+)
+
+// recordType 记录 CallObserver 调用的测试类型
+type recordType struct {
+	*types.AbstractType
+	calls []map[string]struct{}
+}
+
+func (r *recordType) CallObserver(transaction *struts.Transaction, parentSubs map[string]struct{}) {
+	r.calls = append(r.calls, parentSubs)
+}
+
+func TestTransactLifecycle(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	var events []string
+	for _, name := range []string{"beforeAllTransactions", "beforeTransaction", "beforeObserverCalls", "afterTransaction", "afterTransactionCleanup", "afterAllTransactions"} {
+		eventName := name
+		doc.On(eventName, func(args interface{}) {
+			events = append(events, eventName)
+		})
+	}
+
+	yType := &recordType{AbstractType: types.NewAbstractType()}
+	yType.Integrate(doc, nil)
+	client := doc.ClientID
+
+	var outer, inner *struts.Transaction
+	doc.Transact(func(transaction *struts.Transaction) {
+		outer = transaction
+		// 嵌套调用复用外层事务
+		doc.Transact(func(transaction *struts.Transaction) {
+			inner = transaction
+		}, nil, true)
+
+		// 插入两个可以合并的相邻项目
+		left := struts.NewItem(util.NewID(client, 0), nil, nil, nil, nil, yType, "", struts.NewContentAny([]interface{}{1, 2}))
+		right := struts.NewItem(util.NewID(client, 2), left, left.LastId(), nil, nil, yType, "", struts.NewContentAny([]interface{}{3}))
+		left.Right = right
+		yType.SetStart(left)
+		doc.Store.Clients[client] = &[]struts.AbstractStructInterface{left, right}
+		struts.AddChangedTypeToTransaction(transaction, yType, "")
+	}, "origin", true)
+
+	if outer != inner {
+		t.Fatalf("期望嵌套事务复用外层事务")
+	}
+	if outer.Origin != "origin" || !outer.Local {
+		t.Errorf("事务来源或本地标记错误")
+	}
+	expected := []string{"beforeAllTransactions", "beforeTransaction", "beforeObserverCalls", "afterTransaction", "afterTransactionCleanup", "afterAllTransactions"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("期望事件顺序为 %v, 但得到 %v", expected, events)
+	}
+	if outer.AfterState[client] != 3 || outer.BeforeState[client] != 0 {
+		t.Errorf("期望状态从 0 变为 3, 但得到 %v -> %v", outer.BeforeState, outer.AfterState)
+	}
+	if len(yType.calls) != 1 {
+		t.Fatalf("期望观察者被调用一次, 但得到 %d 次", len(yType.calls))
+	}
+	if _, ok := yType.calls[0][""]; !ok {
+		t.Errorf("期望列表变化被记录")
+	}
+	// 清理阶段会合并相邻的结构
+	structs := *doc.Store.Clients[client]
+	if len(structs) != 1 || structs[0].GetLength() != 3 {
+		t.Errorf("期望结构被合并为一个长度为 3 的项目, 但得到 %d 个结构", len(structs))
+	}
+	if doc.Transaction != nil || len(doc.TransactionCleanups) != 0 {
+		t.Errorf("期望事务结束后被清理")
+	}
+}
+
+func TestSortAndMergeDeleteSet(t *testing.T) {
+	ds := util.NewDeleteSet()
+	ds.Clients[1] = &[]util.DeleteItem{{Clock: 5, Len: 2}, {Clock: 0, Len: 2}, {Clock: 2, Len: 1}, {Clock: 6, Len: 3}}
+	util.SortAndMergeDeleteSet(ds)
+	expected := []util.DeleteItem{{Clock: 0, Len: 3}, {Clock: 5, Len: 4}}
+	if !reflect.DeepEqual(*ds.Clients[1], expected) {
+		t.Errorf("期望 %v, 但得到 %v", expected, *ds.Clients[1])
+	}
+}
+
+func TestUpdateEvent(t *testing.T) {
+	doc1 := struts.NewDoc(nil)
+	doc2 := struts.NewDoc(nil)
+	var origins []interface{}
+	doc1.On("update", func(args interface{}) {
+		event := args.(*struts.UpdateEvent)
+		origins = append(origins, event.Origin)
+		struts.ApplyUpdate(doc2, event.Update, doc1)
+	})
+	doc1.On("updateV2", func(args interface{}) {
+		if got := struts.ConvertUpdateFormatV2ToV1(args.(*struts.UpdateEvent).Update); len(got) == 0 {
+			t.Errorf("V2 更新不应为空")
+		}
+	})
+	text := types.GetText(doc1, "text")
+	doc1.Transact(func(tr *struts.Transaction) { text.Insert(0, "abc", nil) }, "local", true)
+	text.Delete(0, 1)
+	// 没有修改的事务不发出更新
+	doc1.Transact(func(tr *struts.Transaction) {}, nil, true)
+	if !reflect.DeepEqual(origins, []interface{}{"local", nil}) {
+		t.Errorf("期望两次更新, 但得到 %v", origins)
+	}
+	if got := types.GetText(doc2, "text").ToString(); got != "bc" {
+		t.Errorf("期望 bc, 但得到 %q", got)
+	}
+}
@@ -0,0 +1,149 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"reflect"
+	"testing"
+)
+
+func TestUndoRedoText(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	text := types.GetText(doc, "text")
+	um := struts.NewUndoManager([]struts.AbstractTypeInterface{text}, nil)
+	text.Insert(0, "abc", nil)
+	text.Insert(3, "def", nil)
+	um.StopCapturing()
+	text.Delete(0, 1)
+	if um.Undo() == nil || text.ToString() != "abcdef" {
+		t.Fatalf("撤销删除后期望 abcdef, 但得到 %q", text.ToString())
+	}
+	// 捕获间隔内的两次插入是同一个步骤
+	um.Undo()
+	if got := text.ToString(); got != "" {
+		t.Fatalf("撤销插入后期望空文本, 但得到 %q", got)
+	}
+	if um.Undo() != nil || um.CanUndo() {
+		t.Errorf("没有可撤销的修改时应返回 nil")
+	}
+	um.Redo()
+	um.Redo()
+	if got := text.ToString(); got != "bcdef" {
+		t.Errorf("重做后期望 bcdef, 但得到 %q", got)
+	}
+	// 新的修改会清空重做栈
+	um.Undo()
+	text.Insert(0, "x", nil)
+	if um.CanRedo() {
+		t.Errorf("新的修改后重做栈应被清空")
+	}
+}
+
+func TestUndoTrackedOrigins(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	text := types.GetText(doc, "text")
+	other := types.GetText(doc, "other")
+	um := struts.NewUndoManager([]struts.AbstractTypeInterface{text}, &struts.UndoManagerOpts{
+		TrackedOrigins: []interface{}{"local"},
+	})
+	doc.Transact(func(tr *struts.Transaction) { text.Insert(0, "a", nil) }, "local", true)
+	um.StopCapturing()
+	doc.Transact(func(tr *struts.Transaction) { text.Insert(1, "b", nil) }, "remote", true)
+	doc.Transact(func(tr *struts.Transaction) { other.Insert(0, "c", nil) }, "local", true)
+	if len(um.UndoStack) != 1 {
+		t.Fatalf("只应跟踪作用域中来源为 local 的修改, 但有 %d 个步骤", len(um.UndoStack))
+	}
+	um.Undo()
+	if text.ToString() != "b" || other.ToString() != "c" {
+		t.Errorf("撤销只应影响跟踪的修改, 但得到 %q 与 %q", text.ToString(), other.ToString())
+	}
+}
+
+func TestUndoManagerEvents(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	ymap := types.GetMap(doc, "map")
+	um := struts.NewUndoManager([]struts.AbstractTypeInterface{ymap}, nil)
+	var events []string
+	for _, name := range []string{"stack-item-added", "stack-item-updated", "stack-item-popped"} {
+		name := name
+		um.On(name, func(args interface{}) {
+			event := args.(*struts.StackItemEvent)
+			if name == "stack-item-added" {
+				event.StackItem.Meta["cursor"] = 1
+			}
+			events = append(events, name+":"+event.Type)
+		})
+	}
+	ymap.Set("a", 1)
+	ymap.Set("b", 2)
+	stackItem := um.Undo()
+	if stackItem == nil || stackItem.Meta["cursor"] != 1 {
+		t.Errorf("步骤的元数据应被保留")
+	}
+	expected := []string{"stack-item-added:undo", "stack-item-updated:undo", "stack-item-added:redo", "stack-item-popped:undo"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("期望事件 %v, 但得到 %v", expected, events)
+	}
+	if len(ymap.Keys()) != 0 {
+		t.Errorf("撤销后映射应为空")
+	}
+}
+
+// TestUndoDeletionWithConcurrentChanges 撤销删除时，被其他客户端同时修改的类型也能恢复
+func TestUndoDeletionWithConcurrentChanges(t *testing.T) {
+	doc1 := struts.NewDoc(nil)
+	doc1.ClientID = 1
+	arr1 := types.GetArray(doc1, "array")
+	arr1.Insert(0, []interface{}{"x", types.NewYMapFrom(map[string]interface{}{"a": 1}), "y"})
+	doc2 := struts.NewDoc(nil)
+	doc2.ClientID = 2
+	struts.ApplyUpdate(doc2, struts.EncodeStateAsUpdate(doc1, nil), nil)
+
+	um := struts.NewUndoManager([]struts.AbstractTypeInterface{arr1}, nil)
+	arr1.Delete(1, 1)
+	// 另一个客户端同时修改了被删除的映射，并在它前后插入内容
+	arr2 := types.GetArray(doc2, "array")
+	arr2.Get(1).(*types.YMap).Set("b", 2)
+	arr2.Insert(1, []interface{}{"l"})
+	arr2.Insert(3, []interface{}{"r"})
+	sync := func(from, to *struts.Doc) {
+		struts.ApplyUpdate(to, struts.EncodeStateAsUpdate(from, struts.EncodeStateVector(to)), "remote")
+	}
+	sync(doc1, doc2)
+	sync(doc2, doc1)
+	if got := arr1.ToJSON(); !reflect.DeepEqual(got, []interface{}{"x", "l", "r", "y"}) {
+		t.Fatalf("期望 [x l r y], 但得到 %v", got)
+	}
+
+	um.Undo()
+	expected := []interface{}{"x", "l", map[string]interface{}{"a": 1}, "r", "y"}
+	if got := arr1.ToJSON(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("撤销后映射应恢复到原来的位置, 但得到 %v", got)
+	}
+	sync(doc1, doc2)
+	if !reflect.DeepEqual(arr2.ToJSON(), arr1.ToJSON()) {
+		t.Errorf("撤销应同步到其他客户端, 期望 %v, 但得到 %v", arr1.ToJSON(), arr2.ToJSON())
+	}
+	um.Redo()
+	if got := arr1.ToJSON(); !reflect.DeepEqual(got, []interface{}{"x", "l", "r", "y"}) {
+		t.Errorf("重做后期望 [x l r y], 但得到 %v", got)
+	}
+}
+
+// TestUndoManagerDestroy 销毁一个撤销管理器不影响同一文档上的其他撤销管理器
+func TestUndoManagerDestroy(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	textA := types.GetText(doc, "a")
+	textB := types.GetText(doc, "b")
+	umA := struts.NewUndoManager([]struts.AbstractTypeInterface{textA}, nil)
+	umB := struts.NewUndoManager([]struts.AbstractTypeInterface{textB}, nil)
+	umB.Destroy()
+	textA.Insert(0, "a", nil)
+	textB.Insert(0, "b", nil)
+	if !umA.CanUndo() {
+		t.Errorf("未销毁的撤销管理器应继续跟踪修改")
+	}
+	if umB.CanUndo() {
+		t.Errorf("已销毁的撤销管理器不应跟踪修改")
+	}
+}
@@ -0,0 +1,146 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// textUpdates 返回客户端 1 依次插入 a、b、c 产生的三个增量更新
+func textUpdates() (*struts.Doc, [][]byte) {
+	doc := struts.NewDoc(nil)
+	doc.ClientID = 1
+	text := types.GetText(doc, "text")
+	var updates [][]byte
+	for i, c := range []string{"a", "b", "c"} {
+		sv := struts.EncodeStateVectorFromMap(map[int]int{1: i})
+		text.Insert(i, c, nil)
+		updates = append(updates, struts.EncodeStateAsUpdate(doc, sv))
+	}
+	return doc, updates
+}
+
+func TestMergeUpdates(t *testing.T) {
+	doc1, updates := textUpdates()
+	merged := struts.MergeUpdates(updates)
+	doc2 := struts.NewDoc(nil)
+	struts.ApplyUpdate(doc2, merged, nil)
+	if got := types.GetText(doc2, "text").ToString(); got != "abc" {
+		t.Errorf("期望 abc, 但得到 %q", got)
+	}
+	// 重复的更新只保留一份
+	again := struts.MergeUpdates([][]byte{merged, updates[1], merged})
+	if !bytes.Equal(again, merged) {
+		t.Errorf("合并重复的更新应得到相同的结果")
+	}
+	if !bytes.Equal(struts.EncodeStateVectorFromUpdate(merged), struts.EncodeStateVector(doc1)) {
+		t.Errorf("从合并的更新计算的状态向量应与文档一致")
+	}
+}
+
+// TestMergeUpdatesWithGap 中间缺失的结构用 Skip 结构填补，应用时缺失部分之后的结构会被暂存
+func TestMergeUpdatesWithGap(t *testing.T) {
+	_, updates := textUpdates()
+	merged := struts.MergeUpdates([][]byte{updates[0], updates[2]})
+	meta := struts.ParseUpdateMeta(merged)
+	if !reflect.DeepEqual(meta.From, map[int]int{1: 0}) || !reflect.DeepEqual(meta.To, map[int]int{1: 3}) {
+		t.Errorf("时钟范围错误: %+v", meta)
+	}
+	// Skip 之后的结构不计入状态向量
+	if sv := struts.DecodeStateVector(struts.EncodeStateVectorFromUpdate(merged)); !reflect.DeepEqual(sv, map[int]int{1: 1}) {
+		t.Errorf("期望状态向量 {1: 1}, 但得到 %v", sv)
+	}
+	doc := struts.NewDoc(nil)
+	struts.ApplyUpdate(doc, merged, nil)
+	if got := types.GetText(doc, "text").ToString(); got != "a" {
+		t.Errorf("期望 a, 但得到 %q", got)
+	}
+	struts.ApplyUpdate(doc, updates[1], nil)
+	if got := types.GetText(doc, "text").ToString(); got != "abc" {
+		t.Errorf("期望 abc, 但得到 %q", got)
+	}
+}
+
+func TestDiffUpdate(t *testing.T) {
+	doc, updates := textUpdates()
+	full := struts.EncodeStateAsUpdate(doc, nil)
+	sv := struts.EncodeStateVectorFromMap(map[int]int{1: 1})
+	if diff := struts.DiffUpdate(full, sv); !bytes.Equal(diff, struts.EncodeStateAsUpdate(doc, sv)) {
+		t.Errorf("DiffUpdate 的结果应与 EncodeStateAsUpdate 一致")
+	}
+	if diff := struts.DiffUpdate(full, struts.EncodeStateVectorFromMap(map[int]int{1: 2})); !bytes.Equal(diff, updates[2]) {
+		t.Errorf("期望只包含最后一次插入")
+	}
+	fullV2 := struts.EncodeStateAsUpdateV2(doc, nil)
+	if diff := struts.DiffUpdateV2(fullV2, sv); !bytes.Equal(diff, struts.EncodeStateAsUpdateV2(doc, sv)) {
+		t.Errorf("DiffUpdateV2 的结果应与 EncodeStateAsUpdateV2 一致")
+	}
+	meta := struts.ParseUpdateMetaV2(struts.DiffUpdateV2(fullV2, sv))
+	if meta.From[1] != 1 || meta.To[1] != 3 {
+		t.Errorf("时钟范围错误: %+v", meta)
+	}
+}
+
+func TestMergeUpdatesV2(t *testing.T) {
+	doc1 := struts.NewDoc(nil)
+	doc1.ClientID = 1
+	types.GetText(doc1, "text").Insert(0, "ab", nil)
+	doc2 := struts.NewDoc(nil)
+	doc2.ClientID = 2
+	types.GetMap(doc2, "map").Set("k", "v")
+	merged := struts.MergeUpdatesV2([][]byte{struts.EncodeStateAsUpdateV2(doc1, nil), struts.EncodeStateAsUpdateV2(doc2, nil)})
+	if sv := struts.DecodeStateVector(struts.EncodeStateVectorFromUpdateV2(merged)); !reflect.DeepEqual(sv, map[int]int{1: 2, 2: 1}) {
+		t.Errorf("期望状态向量 {1: 2, 2: 1}, 但得到 %v", sv)
+	}
+	doc3 := struts.NewDoc(nil)
+	struts.ApplyUpdateV2(doc3, merged, nil)
+	if got := types.GetText(doc3, "text").ToString(); got != "ab" {
+		t.Errorf("期望 ab, 但得到 %q", got)
+	}
+	if got := types.GetMap(doc3, "map").Get("k"); got != "v" {
+		t.Errorf("期望 v, 但得到 %v", got)
+	}
+}
+
+func TestConvertUpdateFormat(t *testing.T) {
+	if got := struts.ConvertUpdateFormatV1ToV2(yjsTextUpdate); !bytes.Equal(got, yjsTextUpdateV2) {
+		t.Errorf("期望 %v, 但得到 %v", yjsTextUpdateV2, got)
+	}
+	if got := struts.ConvertUpdateFormatV2ToV1(yjsTextUpdateV2); !bytes.Equal(got, yjsTextUpdate) {
+		t.Errorf("期望 %v, 但得到 %v", yjsTextUpdate, got)
+	}
+	doc := struts.NewDoc(nil)
+	doc.ClientID = 1
+	types.GetMap(doc, "map").Set("k", map[string]interface{}{"a": "b"})
+	types.GetText(doc, "text").Insert(0, "abc", map[string]interface{}{"bold": true})
+	types.GetText(doc, "text").Delete(1, 1)
+	v1 := struts.EncodeStateAsUpdate(doc, nil)
+	v2 := struts.EncodeStateAsUpdateV2(doc, nil)
+	if !bytes.Equal(struts.ConvertUpdateFormatV1ToV2(v1), v2) {
+		t.Errorf("V1 转换为 V2 的结果应与 EncodeStateAsUpdateV2 一致")
+	}
+	if !bytes.Equal(struts.ConvertUpdateFormatV2ToV1(v2), v1) {
+		t.Errorf("V2 转换为 V1 的结果应与 EncodeStateAsUpdate 一致")
+	}
+}
+
+// TestEncodeStateAsUpdateIncludesPending 暂存的结构与删除集合会随文档的状态一起编码
+func TestEncodeStateAsUpdateIncludesPending(t *testing.T) {
+	doc1, updates := textUpdates()
+	types.GetText(doc1, "text").Delete(0, 1)
+	deletion := struts.EncodeStateAsUpdate(doc1, struts.EncodeStateVector(doc1))
+	doc2 := struts.NewDoc(nil)
+	struts.ApplyUpdate(doc2, updates[2], nil)
+	struts.ApplyUpdate(doc2, deletion, nil)
+
+	doc3 := struts.NewDoc(nil)
+	struts.ApplyUpdate(doc3, struts.EncodeStateAsUpdate(doc2, nil), nil)
+	struts.ApplyUpdateV2(doc3, struts.EncodeStateAsUpdateV2(doc2, nil), nil)
+	struts.ApplyUpdate(doc3, updates[0], nil)
+	struts.ApplyUpdate(doc3, updates[1], nil)
+	if got := types.GetText(doc3, "text").ToString(); got != "bc" {
+		t.Errorf("期望 bc, 但得到 %q", got)
+	}
+}
@@ -0,0 +1,268 @@
+package struts
+
+import (
+	"CollabEdit/util"
+	"log"
+	"math"
+	"sort"
+)
+
+type Transaction struct {
+	Doc                   *Doc                                          //文档
+	DeleteSet             *util.DeleteSet                               //删除集合
+	BeforeState           map[int]int                                   //变化前的状态
+	AfterState            map[int]int                                   //变化后的状态
+	Local                 bool                                          //变化是否来源这个文件
+	Origin                interface{}                                   //事务来源
+	Meta                  map[interface{}]interface{}                   //事务元数据，可供插件存储数据
+	Changed               map[AbstractTypeInterface]map[string]struct{} // 变化的类型
+	ChangedParentTypes    map[AbstractTypeInterface][]interface{}       // 变化的父类型
+	MergeStructs          []AbstractStructInterface                     // 变化的结构
+	SubDocsAdded          map[*Doc]struct{}                             // 新增的子文档
+	SubDocsRemoved        map[*Doc]struct{}                             // 删除的子文档
+	SubDocsLoaded         map[*Doc]struct{}                             // 加载的子文档
+	NeedFormattingCleanup bool                                          // 是否需要格式化清理
+}
+
+// formattingCleanup 远程事务修改了格式化属性后的清理函数，由 YText 所在的包注册
+var formattingCleanup func(transaction *Transaction)
+
+// RegisterFormattingCleanup 注册格式化清理函数
+func RegisterFormattingCleanup(f func(transaction *Transaction)) {
+	formattingCleanup = f
+}
+
+// NewTransaction 创建事务，记录事务开始前的状态向量
+func NewTransaction(doc *Doc, origin interface{}, local bool) *Transaction {
+	return &Transaction{
+		Doc:                doc,
+		DeleteSet:          util.NewDeleteSet(),
+		BeforeState:        GetStateVector(doc.Store),
+		AfterState:         make(map[int]int),
+		Local:              local,
+		Origin:             origin,
+		Meta:               make(map[interface{}]interface{}),
+		Changed:            make(map[AbstractTypeInterface]map[string]struct{}),
+		ChangedParentTypes: make(map[AbstractTypeInterface][]interface{}),
+		MergeStructs:       make([]AbstractStructInterface, 0),
+		SubDocsAdded:       make(map[*Doc]struct{}),
+		SubDocsRemoved:     make(map[*Doc]struct{}),
+		SubDocsLoaded:      make(map[*Doc]struct{}),
+	}
+}
+
+// NextID 为本地客户端生成下一个ID
+func NextID(transaction *Transaction) *util.ID {
+	doc := transaction.Doc
+	return util.NewID(doc.ClientID, GetState(doc.Store, doc.ClientID))
+}
+
+// AddChangedTypeToTransaction 如果类型在事务开始前就已存在，则将其标记为已变化
+// parentSub 为空字符串表示列表内容发生了变化
+func AddChangedTypeToTransaction(transaction *Transaction, t AbstractTypeInterface, parentSub string) {
+	item := t.GetItem()
+	if item == nil || (item.ID.Clock < transaction.BeforeState[item.ID.Client] && !item.GetDeleted()) {
+		subs, exists := transaction.Changed[t]
+		if !exists {
+			subs = make(map[string]struct{})
+			transaction.Changed[t] = subs
+		}
+		subs[parentSub] = struct{}{}
+	}
+}
+
+// tryToMergeWithLefts 尝试将 pos 位置的结构与其左侧的结构合并，返回被合并的结构数量
+func tryToMergeWithLefts(structs *[]AbstractStructInterface, pos int) int {
+	right := (*structs)[pos]
+	i := pos
+	for ; i > 0; i-- {
+		left := (*structs)[i-1]
+		if left.GetDeleted() != right.GetDeleted() || !left.MergeWith(right) {
+			break
+		}
+		// 如果被合并的项目是父类型映射中的最新值，需要替换为合并后的项目
+		if r, ok := right.(*Item); ok && r.ParentSub != "" && r.Parent.GetDataMap()[r.ParentSub] == r {
+			r.Parent.GetDataMap()[r.ParentSub] = left.(*Item)
+		}
+		right = left
+	}
+	merged := pos - i
+	if merged > 0 {
+		// 从数组中移除所有被合并的结构
+		*structs = append((*structs)[:pos+1-merged], (*structs)[pos+1:]...)
+	}
+	return merged
+}
+
+// tryGcDeleteSet 将删除集合中已删除的项目替换为 ContentDeleted 或 GC
+func tryGcDeleteSet(ds *util.DeleteSet, store *StructStore, gcFilter func(item *Item) bool) {
+	for client, deleteItems := range ds.Clients {
+		structs := store.Clients[client]
+		for di := len(*deleteItems) - 1; di >= 0; di-- {
+			deleteItem := (*deleteItems)[di]
+			endDeleteItemClock := deleteItem.Clock + deleteItem.Len
+			for si := FindIndexSS(*structs, deleteItem.Clock); si < len(*structs); si++ {
+				s := (*structs)[si]
+				if endDeleteItemClock <= s.GetID().Clock {
+					break
+				}
+				if item, ok := s.(*Item); ok && item.GetDeleted() && !item.Keep() && gcFilter(item) {
+					item.GC(store, false)
+				}
+			}
+		}
+	}
+}
+
+// tryMergeDeleteSet 从右往左尝试合并被删除或被回收的结构
+func tryMergeDeleteSet(ds *util.DeleteSet, store *StructStore) {
+	for client, deleteItems := range ds.Clients {
+		structs := store.Clients[client]
+		for di := len(*deleteItems) - 1; di >= 0; di-- {
+			deleteItem := (*deleteItems)[di]
+			// 从最后一个被删除项目的右侧开始合并
+			mostRightIndexToCheck := int(math.Min(float64(len(*structs)-1), float64(1+FindIndexSS(*structs, deleteItem.Clock+deleteItem.Len-1))))
+			for si := mostRightIndexToCheck; si > 0 && (*structs)[si].GetID().Clock >= deleteItem.Clock; {
+				si -= 1 + tryToMergeWithLefts(structs, si)
+			}
+		}
+	}
+}
+
+// cleanupTransactions 依次清理事务：调用观察者、回收删除内容、合并结构并发出事件
+// 观察者中可能会开启新的事务，所以每次都从 doc.TransactionCleanups 读取最新的事务列表
+func cleanupTransactions(doc *Doc, i int) {
+	if i >= len(doc.TransactionCleanups) {
+		return
+	}
+	transaction := doc.TransactionCleanups[i]
+	store := doc.Store
+	ds := transaction.DeleteSet
+	mergeStructs := transaction.MergeStructs
+	defer func() {
+		// 将删除的项目替换为 ContentDeleted 或 GC，这里才真正从文档中移除内容
+		if doc.Gc {
+			tryGcDeleteSet(ds, store, doc.GcFilter)
+		}
+		tryMergeDeleteSet(ds, store)
+
+		// 在所有受影响的客户端上尝试合并结构
+		for client, clock := range transaction.AfterState {
+			beforeClock := transaction.BeforeState[client]
+			if beforeClock != clock {
+				structs := store.Clients[client]
+				// 从右往左迭代，这样可以安全地删除元素
+				firstChangePos := int(math.Max(float64(FindIndexSS(*structs, beforeClock)), 1))
+				for si := len(*structs) - 1; si >= firstChangePos; {
+					si -= 1 + tryToMergeWithLefts(structs, si)
+				}
+			}
+		}
+		// 尝试合并 mergeStructs 中记录的结构
+		for mi := len(mergeStructs) - 1; mi >= 0; mi-- {
+			id := mergeStructs[mi].GetID()
+			structs := store.Clients[id.Client]
+			replacedStructPos := FindIndexSS(*structs, id.Clock)
+			if replacedStructPos+1 < len(*structs) {
+				if tryToMergeWithLefts(structs, replacedStructPos+1) > 1 {
+					continue // 两侧都已经合并，无需再检查
+				}
+			}
+			if replacedStructPos > 0 {
+				tryToMergeWithLefts(structs, replacedStructPos)
+			}
+		}
+		if !transaction.Local && transaction.AfterState[doc.ClientID] != transaction.BeforeState[doc.ClientID] {
+			log.Printf("[CollabEdit] 其他客户端似乎正在使用相同的客户端ID，已更换客户端ID。")
+			doc.ClientID = int(generateNewClientId())
+		}
+		doc.Emit("afterTransactionCleanup", transaction)
+		if doc.HasObservers("update") {
+			encoder := util.NewUpdateEncoderV1()
+			if writeUpdateMessageFromTransaction(encoder, transaction) {
+				doc.Emit("update", NewUpdateEvent(encoder.ToBytes(), transaction))
+			}
+		}
+		if doc.HasObservers("updateV2") {
+			encoder := util.NewUpdateEncoderV2()
+			if writeUpdateMessageFromTransaction(encoder, transaction) {
+				doc.Emit("updateV2", NewUpdateEvent(encoder.ToBytes(), transaction))
+			}
+		}
+		if len(transaction.SubDocsAdded) > 0 || len(transaction.SubDocsRemoved) > 0 || len(transaction.SubDocsLoaded) > 0 {
+			for subdoc := range transaction.SubDocsAdded {
+				subdoc.ClientID = doc.ClientID
+				if subdoc.CollectionID == "" {
+					subdoc.CollectionID = doc.CollectionID
+				}
+				doc.SubDocs[subdoc] = struct{}{}
+			}
+			for subdoc := range transaction.SubDocsRemoved {
+				delete(doc.SubDocs, subdoc)
+			}
+			doc.Emit("subdocs", &SubdocsEvent{
+				Added:       transaction.SubDocsAdded,
+				Removed:     transaction.SubDocsRemoved,
+				Loaded:      transaction.SubDocsLoaded,
+				Transaction: transaction,
+			})
+			// 被删除的子文档在父文档中替换为未加载的新文档
+			for subdoc := range transaction.SubDocsRemoved {
+				subdoc.Destroy()
+			}
+		}
+
+		if len(doc.TransactionCleanups) <= i+1 {
+			transactionCleanups := doc.TransactionCleanups
+			doc.TransactionCleanups = make([]*Transaction, 0)
+			doc.Emit("afterAllTransactions", transactionCleanups)
+		} else {
+			cleanupTransactions(doc, i+1)
+		}
+	}()
+
+	util.SortAndMergeDeleteSet(ds)
+	transaction.AfterState = GetStateVector(store)
+	doc.Emit("beforeObserverCalls", transaction)
+	// 调用已变化类型的观察者
+	for t, subs := range transaction.Changed {
+		if t.GetItem() == nil || !t.GetItem().GetDeleted() {
+			t.CallObserver(transaction, subs)
+		}
+	}
+	// 调用深度观察者
+	for t, events := range transaction.ChangedParentTypes {
+		deepHandler := t.GetDeepHandler()
+		if deepHandler != nil && len(deepHandler.Events) > 0 && (t.GetItem() == nil || !t.GetItem().GetDeleted()) {
+			// 观察者中可能删除了发生变化的类型，过滤掉这些事件
+			filtered := make([]interface{}, 0, len(events))
+			for _, event := range events {
+				if e, ok := event.(YEventInterface); ok {
+					target := e.GetTarget()
+					if target.GetItem() != nil && target.GetItem().GetDeleted() {
+						continue
+					}
+					e.SetCurrentTarget(t)
+				}
+				filtered = append(filtered, event)
+			}
+			// 与 Yjs 一致，按路径长度排序，离当前目标越近的事件越靠前
+			sort.SliceStable(filtered, func(a, b int) bool {
+				return eventPathLength(filtered[a]) < eventPathLength(filtered[b])
+			})
+			deepHandler.CallEvents(filtered, transaction)
+		}
+	}
+	doc.Emit("afterTransaction", transaction)
+	if transaction.NeedFormattingCleanup && formattingCleanup != nil {
+		formattingCleanup(transaction)
+	}
+}
+
+// eventPathLength 返回事件从当前目标开始的路径长度
+func eventPathLength(event interface{}) int {
+	if e, ok := event.(YEventInterface); ok {
+		return len(e.Path())
+	}
+	return 0
+}
@@ -0,0 +1,46 @@
+package struts
+
+import "CollabEdit/util"
+
+// AbstractTypeInterface 共享类型在结构层可见的部分
+// struts 只依赖这个接口，具体的类型实现（types 包）反向依赖 struts，从而避免包之间的循环引用
+type AbstractTypeInterface interface {
+	SetItem(item *Item)                                                    // SetItem 设置项目
+	GetItem() *Item                                                        // GetItem 获取项目
+	GetDoc() *Doc                                                          // GetDoc 获取所属文档
+	SetDataMap(dataMap map[string]*Item)                                   // SetDataMap 设置项目
+	GetDataMap() map[string]*Item                                          // GetDataMap 获取项目
+	SetStart(start *Item)                                                  // SetStart 设置开始项目
+	GetStart() *Item                                                       // GetStart 获取开始项目
+	SetLength(length int)                                                  // SetLength 设置长度
+	GetLength() int                                                        // GetLength 获取长度
+	SetHandler(handler *util.EventHandler)                                 // SetHandler 设置观察者
+	GetHandler() *util.EventHandler                                        // GetHandler 获取观察者
+	SetDeepHandler(handler *util.EventHandler)                             // SetDeepHandler 设置深度观察者
+	GetDeepHandler() *util.EventHandler                                    // GetDeepHandler 获取深度观察者
+	Integrate(y *Doc, item *Item)                                          // Integrate 将此类型集成到 Yjs 实例中
+	Copy() AbstractTypeInterface                                           // Copy 返回此数据类型的副本
+	Clone() AbstractTypeInterface                                          // Clone 返回此数据类型的副本
+	Write(encoder util.EncoderInterface)                                   // Write 将此类型写入编码器
+	CallObserver(transaction *Transaction, parentSubs map[string]struct{}) // CallObserver 创建 YEvent 并调用所有类型观察者
+	ToJSON() interface{}                                                   // ToJSON 返回此类型的 JSON 表示
+}
+
+// SearchMarkerOwner 维护数组搜索标记的类型实现此接口
+// 项目合并后右侧项目会被丢弃，指向它的搜索标记需要移动到左侧项目上
+type SearchMarkerOwner interface {
+	MoveSearchMarkers(left, right *Item) // MoveSearchMarkers 将指向 right 的搜索标记移动到 left
+	ClearSearchMarkers()                 // ClearSearchMarkers 移除并停用搜索标记
+}
+
+// FormattingOwner 支持格式化属性的类型（如 YText）实现此接口
+type FormattingOwner interface {
+	SetHasFormatting(hasFormatting bool) // SetHasFormatting 标记类型中包含格式化属性
+}
+
+// YEventInterface 类型事件在结构层可见的部分，事务清理时用于设置深度观察者的当前目标并排序事件
+type YEventInterface interface {
+	GetTarget() AbstractTypeInterface         // GetTarget 获取发生变化的类型
+	SetCurrentTarget(t AbstractTypeInterface) // SetCurrentTarget 设置当前调用事件处理器的类型
+	Path() []interface{}                      // Path 从当前目标到发生变化的类型的路径
+}
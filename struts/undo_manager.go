@@ -0,0 +1,393 @@
+package struts
+
+import (
+	"CollabEdit/core"
+	"CollabEdit/util"
+	"reflect"
+	"time"
+)
+
+// DefaultCaptureTimeout 默认的捕获间隔，间隔内的修改合并为一个撤销步骤
+const DefaultCaptureTimeout = 500 * time.Millisecond
+
+// StackItem 撤销栈中的一个步骤，记录这一步插入与删除的结构
+type StackItem struct {
+	Insertions *util.DeleteSet             // 插入的结构
+	Deletions  *util.DeleteSet             // 删除的结构
+	Meta       map[interface{}]interface{} // 元数据，可用于保存光标位置等信息
+}
+
+// NewStackItem 创建撤销栈中的步骤
+func NewStackItem(deletions, insertions *util.DeleteSet) *StackItem {
+	return &StackItem{
+		Insertions: insertions,
+		Deletions:  deletions,
+		Meta:       make(map[interface{}]interface{}),
+	}
+}
+
+// StackItemEvent 撤销栈变化时发出的事件
+type StackItemEvent struct {
+	StackItem          *StackItem                              // 变化的步骤
+	Origin             interface{}                             // 事务来源
+	Type               string                                  // "undo" 表示撤销栈，"redo" 表示重做栈
+	ChangedParentTypes map[AbstractTypeInterface][]interface{} // 事务中变化的类型
+}
+
+// StackClearedEvent 撤销栈被清空时发出的事件
+type StackClearedEvent struct {
+	UndoStackCleared bool // 撤销栈是否被清空
+	RedoStackCleared bool // 重做栈是否被清空
+}
+
+// UndoManagerOpts 撤销管理器的选项
+type UndoManagerOpts struct {
+	CaptureTimeout         time.Duration                       // 捕获间隔，为 0 时使用 DefaultCaptureTimeout，为负数时每个事务都是单独的步骤
+	CaptureTransaction     func(transaction *Transaction) bool // 是否捕获事务
+	DeleteFilter           func(item *Item) bool               // 撤销时是否删除项目
+	TrackedOrigins         []interface{}                       // 跟踪的事务来源，可以是来源本身或来源的 reflect.Type，为 nil 时只跟踪来源为 nil 的事务
+	IgnoreRemoteMapChanges bool                                // 重做映射中的值时是否忽略其他客户端的修改
+}
+
+// UndoManager 撤销管理器，只撤销作用域中的类型上由跟踪的来源产生的修改
+// 撤销管理器会发出 stack-item-added、stack-item-updated、stack-item-popped 与 stack-cleared 事件
+type UndoManager struct {
+	core.Observable
+	Doc                      *Doc
+	Scope                    []AbstractTypeInterface
+	UndoStack                []*StackItem
+	RedoStack                []*StackItem
+	deleteFilter             func(item *Item) bool
+	trackedOrigins           map[interface{}]struct{}
+	captureTransaction       func(transaction *Transaction) bool
+	captureTimeout           time.Duration
+	ignoreRemoteMapChanges   bool
+	undoing                  bool
+	redoing                  bool
+	currStackItem            *StackItem
+	lastChange               time.Time
+	afterTransactionObserver *core.Observer
+}
+
+// NewUndoManager 创建作用于 scope 中类型的撤销管理器，opts 为 nil 时使用默认选项
+func NewUndoManager(scope []AbstractTypeInterface, opts *UndoManagerOpts) *UndoManager {
+	if len(scope) == 0 {
+		panic(util.ErrUnexpectedCase)
+	}
+	if opts == nil {
+		opts = &UndoManagerOpts{}
+	}
+	um := &UndoManager{
+		Doc:                    scope[0].GetDoc(),
+		UndoStack:              make([]*StackItem, 0),
+		RedoStack:              make([]*StackItem, 0),
+		deleteFilter:           opts.DeleteFilter,
+		trackedOrigins:         make(map[interface{}]struct{}),
+		captureTransaction:     opts.CaptureTransaction,
+		captureTimeout:         opts.CaptureTimeout,
+		ignoreRemoteMapChanges: opts.IgnoreRemoteMapChanges,
+	}
+	um.Observers = make(map[string][]*core.Observer)
+	um.AddToScope(scope...)
+	if um.deleteFilter == nil {
+		um.deleteFilter = func(item *Item) bool { return true }
+	}
+	if um.captureTransaction == nil {
+		um.captureTransaction = func(transaction *Transaction) bool { return true }
+	}
+	if um.captureTimeout == 0 {
+		um.captureTimeout = DefaultCaptureTimeout
+	}
+	if opts.TrackedOrigins == nil {
+		um.trackedOrigins[nil] = struct{}{}
+	}
+	for _, origin := range opts.TrackedOrigins {
+		um.AddTrackedOrigin(origin)
+	}
+	// 撤销与重做产生的事务总是被跟踪
+	um.trackedOrigins[um] = struct{}{}
+	um.afterTransactionObserver = um.Doc.On("afterTransaction", func(args interface{}) {
+		um.afterTransaction(args.(*Transaction))
+	})
+	return um
+}
+
+// AddToScope 将类型加入撤销管理器的作用域
+func (um *UndoManager) AddToScope(types ...AbstractTypeInterface) {
+	for _, t := range types {
+		if t.GetDoc() != um.Doc {
+			panic(util.ErrUnexpectedCase)
+		}
+		exists := false
+		for _, s := range um.Scope {
+			if s == t {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			um.Scope = append(um.Scope, t)
+		}
+	}
+}
+
+// AddTrackedOrigin 跟踪来源为 origin 的事务
+func (um *UndoManager) AddTrackedOrigin(origin interface{}) {
+	um.trackedOrigins[origin] = struct{}{}
+}
+
+// RemoveTrackedOrigin 不再跟踪来源为 origin 的事务
+func (um *UndoManager) RemoveTrackedOrigin(origin interface{}) {
+	delete(um.trackedOrigins, origin)
+}
+
+// isTrackedOrigin 判断事务来源是否被跟踪，来源本身或来源的类型被跟踪都可以
+func (um *UndoManager) isTrackedOrigin(origin interface{}) bool {
+	if origin == nil || reflect.TypeOf(origin).Comparable() {
+		if _, ok := um.trackedOrigins[origin]; ok {
+			return true
+		}
+	}
+	if origin == nil {
+		return false
+	}
+	_, ok := um.trackedOrigins[reflect.TypeOf(origin)]
+	return ok
+}
+
+// inScope 判断项目是否属于作用域中的某个类型
+func (um *UndoManager) inScope(item *Item) bool {
+	for _, t := range um.Scope {
+		if IsParentOf(t, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// afterTransaction 在事务结束后记录作用域中的修改
+func (um *UndoManager) afterTransaction(transaction *Transaction) {
+	// 只跟踪满足条件的事务
+	changedScope := false
+	for _, t := range um.Scope {
+		if _, ok := transaction.ChangedParentTypes[t]; ok {
+			changedScope = true
+			break
+		}
+	}
+	if !um.captureTransaction(transaction) || !changedScope || !um.isTrackedOrigin(transaction.Origin) {
+		return
+	}
+	undoing := um.undoing
+	redoing := um.redoing
+	stack := &um.UndoStack
+	if undoing {
+		stack = &um.RedoStack
+		// 下一次撤销不应合并到这一步中
+		um.StopCapturing()
+	} else if !redoing {
+		// 新的修改会清空重做栈
+		um.Clear(false, true)
+	}
+	insertions := util.NewDeleteSet()
+	for client, endClock := range transaction.AfterState {
+		startClock := transaction.BeforeState[client]
+		if length := endClock - startClock; length > 0 {
+			util.AddToDeleteSet(insertions, client, startClock, length)
+		}
+	}
+	now := time.Now()
+	didAdd := false
+	if !um.lastChange.IsZero() && now.Sub(um.lastChange) < um.captureTimeout && len(*stack) > 0 && !undoing && !redoing {
+		// 合并到上一步中
+		lastOp := (*stack)[len(*stack)-1]
+		lastOp.Deletions = util.MergeDeleteSets([]*util.DeleteSet{lastOp.Deletions, transaction.DeleteSet})
+		lastOp.Insertions = util.MergeDeleteSets([]*util.DeleteSet{lastOp.Insertions, insertions})
+	} else {
+		*stack = append(*stack, NewStackItem(transaction.DeleteSet, insertions))
+		didAdd = true
+	}
+	if !undoing && !redoing {
+		um.lastChange = now
+	}
+	// 被删除的结构在撤销时需要恢复，不能被垃圾回收
+	IterateDeletedStructs(transaction, transaction.DeleteSet, func(s AbstractStructInterface) {
+		if item, ok := s.(*Item); ok && um.inScope(item) {
+			KeepItem(item, true)
+		}
+	})
+	eventType := "undo"
+	if undoing {
+		eventType = "redo"
+	}
+	event := &StackItemEvent{
+		StackItem:          (*stack)[len(*stack)-1],
+		Origin:             transaction.Origin,
+		Type:               eventType,
+		ChangedParentTypes: transaction.ChangedParentTypes,
+	}
+	if didAdd {
+		um.Emit("stack-item-added", event)
+	} else {
+		um.Emit("stack-item-updated", event)
+	}
+}
+
+// clearStackItem 步骤被移除后，其中删除的结构不再需要保留
+func (um *UndoManager) clearStackItem(transaction *Transaction, stackItem *StackItem) {
+	IterateDeletedStructs(transaction, stackItem.Deletions, func(s AbstractStructInterface) {
+		if item, ok := s.(*Item); ok && um.inScope(item) {
+			KeepItem(item, false)
+		}
+	})
+}
+
+// Clear 清空撤销栈与重做栈
+func (um *UndoManager) Clear(clearUndoStack, clearRedoStack bool) {
+	if (clearUndoStack && um.CanUndo()) || (clearRedoStack && um.CanRedo()) {
+		um.Doc.Transact(func(transaction *Transaction) {
+			if clearUndoStack {
+				for _, stackItem := range um.UndoStack {
+					um.clearStackItem(transaction, stackItem)
+				}
+				um.UndoStack = make([]*StackItem, 0)
+			}
+			if clearRedoStack {
+				for _, stackItem := range um.RedoStack {
+					um.clearStackItem(transaction, stackItem)
+				}
+				um.RedoStack = make([]*StackItem, 0)
+			}
+			um.Emit("stack-cleared", &StackClearedEvent{UndoStackCleared: clearUndoStack, RedoStackCleared: clearRedoStack})
+		}, nil, true)
+	}
+}
+
+// StopCapturing 之后的修改不再合并到当前步骤中
+func (um *UndoManager) StopCapturing() {
+	um.lastChange = time.Time{}
+}
+
+// Undo 撤销上一步，没有可撤销的修改时返回 nil
+func (um *UndoManager) Undo() *StackItem {
+	um.undoing = true
+	defer func() { um.undoing = false }()
+	return um.popStackItem(&um.UndoStack, "undo")
+}
+
+// Redo 重做上一次撤销的步骤，没有可重做的修改时返回 nil
+func (um *UndoManager) Redo() *StackItem {
+	um.redoing = true
+	defer func() { um.redoing = false }()
+	return um.popStackItem(&um.RedoStack, "redo")
+}
+
+// CanUndo 是否有可撤销的修改
+func (um *UndoManager) CanUndo() bool {
+	return len(um.UndoStack) > 0
+}
+
+// CanRedo 是否有可重做的修改
+func (um *UndoManager) CanRedo() bool {
+	return len(um.RedoStack) > 0
+}
+
+// Destroy 停止跟踪文档的修改
+func (um *UndoManager) Destroy() {
+	delete(um.trackedOrigins, um)
+	um.Doc.OffObserver("afterTransaction", um.afterTransactionObserver)
+}
+
+// popStackItem 从栈中取出步骤并应用：删除其中插入的结构，重做其中删除的结构
+// 没有产生任何修改的步骤会被丢弃，继续取出下一个步骤
+func (um *UndoManager) popStackItem(stack *[]*StackItem, eventType string) *StackItem {
+	doc := um.Doc
+	var tr *Transaction
+	doc.Transact(func(transaction *Transaction) {
+		tr = transaction
+		for len(*stack) > 0 && um.currStackItem == nil {
+			store := doc.Store
+			stackItem := (*stack)[len(*stack)-1]
+			*stack = (*stack)[:len(*stack)-1]
+			itemsToRedo := make(map[*Item]struct{})
+			redoOrder := make([]*Item, 0)
+			itemsToDelete := make([]*Item, 0)
+			performedChange := false
+			IterateDeletedStructs(transaction, stackItem.Insertions, func(s AbstractStructInterface) {
+				item, ok := s.(*Item)
+				if !ok {
+					return
+				}
+				if item.Redone != nil {
+					redone, diff := followRedone(store, item.ID)
+					redoneItem, ok := redone.(*Item)
+					if !ok {
+						return
+					}
+					if diff > 0 {
+						redoneItem = GetItemCleanStart(transaction, util.NewID(redoneItem.ID.Client, redoneItem.ID.Clock+diff))
+					}
+					item = redoneItem
+				}
+				if !item.GetDeleted() && um.inScope(item) {
+					itemsToDelete = append(itemsToDelete, item)
+				}
+			})
+			IterateDeletedStructs(transaction, stackItem.Deletions, func(s AbstractStructInterface) {
+				// 同一步骤中插入又删除的结构不需要重做
+				if item, ok := s.(*Item); ok && um.inScope(item) && !stackItem.Insertions.IsDeleted(item.ID) {
+					if _, exists := itemsToRedo[item]; !exists {
+						itemsToRedo[item] = struct{}{}
+						redoOrder = append(redoOrder, item)
+					}
+				}
+			})
+			for _, item := range redoOrder {
+				if redoItem(transaction, item, itemsToRedo, stackItem.Insertions, um.ignoreRemoteMapChanges) != nil {
+					performedChange = true
+				}
+			}
+			// 倒序删除，子项目先于父类型被删除，过滤时可以获得更多信息
+			for i := len(itemsToDelete) - 1; i >= 0; i-- {
+				item := itemsToDelete[i]
+				if um.deleteFilter(item) {
+					item.Delete(transaction)
+					performedChange = true
+				}
+			}
+			if performedChange {
+				um.currStackItem = stackItem
+			}
+		}
+		for t, subs := range transaction.Changed {
+			// 列表内容变化后搜索标记不再有效
+			if _, ok := subs[""]; ok {
+				if owner, ok := t.(SearchMarkerOwner); ok {
+					owner.ClearSearchMarkers()
+				}
+			}
+		}
+	}, um, true)
+	res := um.currStackItem
+	if res != nil {
+		um.Emit("stack-item-popped", &StackItemEvent{
+			StackItem:          res,
+			Origin:             um,
+			Type:               eventType,
+			ChangedParentTypes: tr.ChangedParentTypes,
+		})
+		um.currStackItem = nil
+	}
+	return res
+}
+
+// IsParentOf 判断 parent 是否为项目的父类型或祖先类型
+func IsParentOf(parent AbstractTypeInterface, child *Item) bool {
+	for child != nil {
+		if child.Parent == parent {
+			return true
+		}
+		child = child.Parent.GetItem()
+	}
+	return false
+}
@@ -0,0 +1,480 @@
+package struts
+
+import (
+	"CollabEdit/core"
+	"CollabEdit/util"
+	"sort"
+)
+
+// LazyStructReader 逐个读取更新中的结构，项目的依赖与父类型不会被解析
+type LazyStructReader struct {
+	decoder           util.UpdateDecoderInterface
+	filterSkips       bool
+	numOfStateUpdates int // 尚未读取的客户端数量
+	numberOfStructs   int // 当前客户端尚未读取的结构数量
+	client            int
+	clock             int
+	Curr              AbstractStructInterface // 当前结构，读取完毕时为 nil
+}
+
+// NewLazyStructReader 创建结构读取器并读取第一个结构，filterSkips 为 true 时跳过 Skip 结构
+func NewLazyStructReader(decoder util.UpdateDecoderInterface, filterSkips bool) *LazyStructReader {
+	r := &LazyStructReader{
+		decoder:           decoder,
+		filterSkips:       filterSkips,
+		numOfStateUpdates: int(decoder.RestDecoder().ReadVarUint()),
+	}
+	r.Next()
+	return r
+}
+
+// Next 读取下一个结构
+func (r *LazyStructReader) Next() AbstractStructInterface {
+	for {
+		r.Curr = r.read()
+		if _, isSkip := r.Curr.(*Skip); !r.filterSkips || !isSkip {
+			return r.Curr
+		}
+	}
+}
+
+// read 从解码器中读取下一个结构，没有更多结构时返回 nil
+func (r *LazyStructReader) read() AbstractStructInterface {
+	decoder := r.decoder
+	for r.numberOfStructs == 0 {
+		if r.numOfStateUpdates == 0 {
+			return nil
+		}
+		r.numOfStateUpdates--
+		r.numberOfStructs = int(decoder.RestDecoder().ReadVarUint())
+		r.client = decoder.ReadClient()
+		r.clock = int(decoder.RestDecoder().ReadVarUint())
+	}
+	r.numberOfStructs--
+	id := util.NewID(r.client, r.clock)
+	info := decoder.ReadInfo()
+	var s AbstractStructInterface
+	switch {
+	case info == structSkipRefNumber:
+		s = NewSkip(id, int(decoder.RestDecoder().ReadVarUint()))
+	case core.BITS5&info != 0:
+		cantCopyParentInfo := info&(core.BIT7|core.BIT8) == 0
+		var origin, rightOrigin *util.ID
+		if info&core.BIT8 == core.BIT8 {
+			origin = decoder.ReadLeftID()
+		}
+		if info&core.BIT7 == core.BIT7 {
+			rightOrigin = decoder.ReadRightID()
+		}
+		var parentID *util.ID
+		parentKey, parentSub := "", ""
+		if cantCopyParentInfo {
+			if decoder.ReadParentInfo() {
+				parentKey = decoder.ReadString()
+			} else {
+				parentID = decoder.ReadLeftID()
+			}
+			if info&core.BIT6 == core.BIT6 {
+				parentSub = decoder.ReadString()
+			}
+		}
+		item := NewItem(id, nil, origin, nil, rightOrigin, nil, parentSub, ReadItemContent(decoder, info))
+		item.ParentID = parentID
+		item.ParentKey = parentKey
+		s = item
+	default:
+		s = NewGC(id, decoder.ReadLen())
+	}
+	r.clock += s.GetLength()
+	return s
+}
+
+// lazyClientStructs 一个客户端已写入的结构
+type lazyClientStructs struct {
+	written     int
+	restEncoder []byte
+}
+
+// LazyStructWriter 逐个写入结构，连续的同一客户端的结构写在一起
+type LazyStructWriter struct {
+	currClient    int
+	written       int
+	encoder       util.UpdateEncoderInterface
+	clientStructs []lazyClientStructs
+}
+
+// NewLazyStructWriter 创建结构写入器
+func NewLazyStructWriter(encoder util.UpdateEncoderInterface) *LazyStructWriter {
+	return &LazyStructWriter{
+		encoder:       encoder,
+		clientStructs: make([]lazyClientStructs, 0),
+	}
+}
+
+// Write 写入结构，offset 大于 0 时只写入结构的后半部分
+func (w *LazyStructWriter) Write(s AbstractStructInterface, offset int) {
+	// 开始写入新的客户端时，先保存之前的客户端
+	if w.written > 0 && w.currClient != s.GetID().Client {
+		w.flush()
+	}
+	if w.written == 0 {
+		w.currClient = s.GetID().Client
+		// 写入下一个客户端
+		w.encoder.WriteClient(s.GetID().Client)
+		// 写入第一个结构的时钟
+		w.encoder.RestEncoder().WriteVarUint(uint(s.GetID().Clock + offset))
+	}
+	s.Write(w.encoder, offset)
+	w.written++
+}
+
+// flush 保存当前客户端已写入的结构，并为下一个客户端准备新的编码器
+func (w *LazyStructWriter) flush() {
+	if w.written > 0 {
+		w.clientStructs = append(w.clientStructs, lazyClientStructs{
+			written:     w.written,
+			restEncoder: w.encoder.RestEncoder().ToBytes(),
+		})
+		w.encoder.SetRestEncoder(core.CreateEncoder())
+		w.written = 0
+	}
+}
+
+// Finish 写入所有客户端的结构，之后可以继续写入删除集合
+func (w *LazyStructWriter) Finish() {
+	w.flush()
+	restEncoder := w.encoder.RestEncoder()
+	// 写入客户端数量，每个客户端写入结构数量与结构数据
+	restEncoder.WriteVarUint(uint(len(w.clientStructs)))
+	for _, partStructs := range w.clientStructs {
+		restEncoder.WriteVarUint(uint(partStructs.written))
+		restEncoder.WriteByteArray(partStructs.restEncoder)
+	}
+}
+
+// sliceStruct 返回结构从 diff 开始的后半部分
+func sliceStruct(left AbstractStructInterface, diff int) AbstractStructInterface {
+	id := left.GetID()
+	switch l := left.(type) {
+	case *GC:
+		return NewGC(util.NewID(id.Client, id.Clock+diff), l.Length-diff)
+	case *Skip:
+		return NewSkip(util.NewID(id.Client, id.Clock+diff), l.Length-diff)
+	case *Item:
+		item := NewItem(
+			util.NewID(id.Client, id.Clock+diff),
+			nil,
+			util.NewID(id.Client, id.Clock+diff-1),
+			nil,
+			l.RightOrigin,
+			l.Parent,
+			l.ParentSub,
+			l.Content.Splice(diff),
+		)
+		item.ParentID = l.ParentID
+		item.ParentKey = l.ParentKey
+		return item
+	}
+	panic(util.ErrUnexpectedCase)
+}
+
+// currWrite 等待写入的结构
+type currWrite struct {
+	s      AbstractStructInterface
+	offset int
+}
+
+// mergeUpdatesWith 使用给定的编解码器合并多个更新，重复的结构只保留一份，缺失的部分用 Skip 结构表示
+func mergeUpdatesWith(updates [][]byte, newDecoder func(decoder *core.Decoder) util.UpdateDecoderInterface, newEncoder func() util.UpdateEncoderInterface) []byte {
+	if len(updates) == 1 {
+		return updates[0]
+	}
+	updateDecoders := make([]util.UpdateDecoderInterface, len(updates))
+	lazyStructDecoders := make([]*LazyStructReader, len(updates))
+	for i, update := range updates {
+		updateDecoders[i] = newDecoder(core.CreateDecoder(update))
+		lazyStructDecoders[i] = NewLazyStructReader(updateDecoders[i], true)
+	}
+	var cw *currWrite
+	updateEncoder := newEncoder()
+	lazyStructEncoder := NewLazyStructWriter(updateEncoder)
+
+	for {
+		// 移除读取完毕的解码器
+		decoders := lazyStructDecoders[:0]
+		for _, dec := range lazyStructDecoders {
+			if dec.Curr != nil {
+				decoders = append(decoders, dec)
+			}
+		}
+		lazyStructDecoders = decoders
+		// 客户端 ID 大的排在前面，同一客户端时钟小的排在前面，同一位置非 Skip 结构排在前面
+		sort.SliceStable(lazyStructDecoders, func(i, j int) bool {
+			a, b := lazyStructDecoders[i].Curr, lazyStructDecoders[j].Curr
+			if a.GetID().Client == b.GetID().Client {
+				if clockDiff := a.GetID().Clock - b.GetID().Clock; clockDiff != 0 {
+					return clockDiff < 0
+				}
+				_, aIsSkip := a.(*Skip)
+				_, bIsSkip := b.(*Skip)
+				return !aIsSkip && bIsSkip
+			}
+			return a.GetID().Client > b.GetID().Client
+		})
+		if len(lazyStructDecoders) == 0 {
+			break
+		}
+		currDecoder := lazyStructDecoders[0]
+		// 写入来自 firstClient 的结构
+		firstClient := currDecoder.Curr.GetID().Client
+
+		if cw != nil {
+			curr := currDecoder.Curr
+			iterated := false
+			// 跳过已经写入的结构
+			for curr != nil && curr.GetID().Clock+curr.GetLength() <= cw.s.GetID().Clock+cw.s.GetLength() && curr.GetID().Client >= cw.s.GetID().Client {
+				curr = currDecoder.Next()
+				iterated = true
+			}
+			if curr == nil || // 当前解码器已读取完毕
+				curr.GetID().Client != firstClient || // 其他解码器中可能还有 firstClient 的结构
+				(iterated && curr.GetID().Clock > cw.s.GetID().Clock+cw.s.GetLength()) { // 跳过结构后可能遗漏了其他解码器中的结构
+				continue
+			}
+			if firstClient != cw.s.GetID().Client {
+				lazyStructEncoder.Write(cw.s, cw.offset)
+				cw = &currWrite{s: curr, offset: 0}
+				currDecoder.Next()
+			} else if cw.s.GetID().Clock+cw.s.GetLength() < curr.GetID().Clock {
+				// 两个结构之间有缺失，用 Skip 结构填补
+				if skip, ok := cw.s.(*Skip); ok {
+					// 扩展已有的 Skip 结构
+					skip.Length = curr.GetID().Clock + curr.GetLength() - skip.ID.Clock
+				} else {
+					lazyStructEncoder.Write(cw.s, cw.offset)
+					diff := curr.GetID().Clock - cw.s.GetID().Clock - cw.s.GetLength()
+					cw = &currWrite{s: NewSkip(util.NewID(firstClient, cw.s.GetID().Clock+cw.s.GetLength()), diff), offset: 0}
+				}
+			} else {
+				diff := cw.s.GetID().Clock + cw.s.GetLength() - curr.GetID().Clock
+				if diff > 0 {
+					if skip, ok := cw.s.(*Skip); ok {
+						// 优先缩短 Skip 结构，因为另一个结构可能包含更多信息
+						skip.Length -= diff
+					} else {
+						curr = sliceStruct(curr, diff)
+					}
+				}
+				if !cw.s.MergeWith(curr) {
+					lazyStructEncoder.Write(cw.s, cw.offset)
+					cw = &currWrite{s: curr, offset: 0}
+					currDecoder.Next()
+				}
+			}
+		} else {
+			cw = &currWrite{s: currDecoder.Curr, offset: 0}
+			currDecoder.Next()
+		}
+		// 直接写入当前解码器中紧随其后的结构
+		for next := currDecoder.Curr; next != nil && next.GetID().Client == firstClient && next.GetID().Clock == cw.s.GetID().Clock+cw.s.GetLength(); next = currDecoder.Next() {
+			if _, ok := next.(*Skip); ok {
+				break
+			}
+			lazyStructEncoder.Write(cw.s, cw.offset)
+			cw = &currWrite{s: next, offset: 0}
+		}
+	}
+	if cw != nil {
+		lazyStructEncoder.Write(cw.s, cw.offset)
+	}
+	lazyStructEncoder.Finish()
+
+	dss := make([]*util.DeleteSet, len(updateDecoders))
+	for i, decoder := range updateDecoders {
+		dss[i] = util.ReadDeleteSet(decoder)
+	}
+	util.WriteDeleteSet(updateEncoder, util.MergeDeleteSets(dss))
+	return updateEncoder.ToBytes()
+}
+
+// MergeUpdates 合并多个 V1 格式的更新，不需要创建文档
+func MergeUpdates(updates [][]byte) []byte {
+	return mergeUpdatesWith(updates, newUpdateDecoderV1, newUpdateEncoderV1)
+}
+
+// MergeUpdatesV2 合并多个 V2 格式的更新，不需要创建文档
+func MergeUpdatesV2(updates [][]byte) []byte {
+	return mergeUpdatesWith(updates, newUpdateDecoderV2, newUpdateEncoderV2)
+}
+
+// diffUpdateWith 使用给定的编解码器计算更新中对方缺少的部分
+func diffUpdateWith(update []byte, sv []byte, newDecoder func(decoder *core.Decoder) util.UpdateDecoderInterface, newEncoder func() util.UpdateEncoderInterface) []byte {
+	state := DecodeStateVector(sv)
+	encoder := newEncoder()
+	lazyStructWriter := NewLazyStructWriter(encoder)
+	decoder := newDecoder(core.CreateDecoder(update))
+	reader := NewLazyStructReader(decoder, false)
+	for reader.Curr != nil {
+		curr := reader.Curr
+		currClient := curr.GetID().Client
+		svClock := state[currClient]
+		if _, ok := curr.(*Skip); ok {
+			reader.Next()
+			continue
+		}
+		if curr.GetID().Clock+curr.GetLength() > svClock {
+			lazyStructWriter.Write(curr, max(svClock-curr.GetID().Clock, 0))
+			reader.Next()
+			for reader.Curr != nil && reader.Curr.GetID().Client == currClient {
+				lazyStructWriter.Write(reader.Curr, 0)
+				reader.Next()
+			}
+		} else {
+			// 跳过对方已经拥有的结构
+			for reader.Curr != nil && reader.Curr.GetID().Client == currClient && reader.Curr.GetID().Clock+reader.Curr.GetLength() <= svClock {
+				reader.Next()
+			}
+		}
+	}
+	lazyStructWriter.Finish()
+	util.WriteDeleteSet(encoder, util.ReadDeleteSet(decoder))
+	return encoder.ToBytes()
+}
+
+// DiffUpdate 返回 V1 格式的更新中状态向量 sv 尚未包含的部分，删除集合会完整保留
+func DiffUpdate(update []byte, sv []byte) []byte {
+	return diffUpdateWith(update, sv, newUpdateDecoderV1, newUpdateEncoderV1)
+}
+
+// DiffUpdateV2 返回 V2 格式的更新中状态向量 sv 尚未包含的部分，删除集合会完整保留
+func DiffUpdateV2(update []byte, sv []byte) []byte {
+	return diffUpdateWith(update, sv, newUpdateDecoderV2, newUpdateEncoderV2)
+}
+
+// stateVectorFromUpdate 计算更新中每个客户端从时钟 0 开始连续的结构所达到的状态
+func stateVectorFromUpdate(decoder util.UpdateDecoderInterface) map[int]int {
+	sv := make(map[int]int)
+	reader := NewLazyStructReader(decoder, false)
+	curr := reader.Curr
+	if curr == nil {
+		return sv
+	}
+	currClient := curr.GetID().Client
+	stopCounting := curr.GetID().Clock != 0 // 必须从时钟 0 开始
+	currClock := 0
+	for ; curr != nil; curr = reader.Next() {
+		if currClient != curr.GetID().Client {
+			if currClock != 0 {
+				sv[currClient] = currClock
+			}
+			currClient = curr.GetID().Client
+			currClock = 0
+			stopCounting = curr.GetID().Clock != 0
+		}
+		if _, ok := curr.(*Skip); ok {
+			stopCounting = true
+		}
+		if !stopCounting {
+			currClock = curr.GetID().Clock + curr.GetLength()
+		}
+	}
+	if currClock != 0 {
+		sv[currClient] = currClock
+	}
+	return sv
+}
+
+// EncodeStateVectorFromUpdate 从 V1 格式的更新计算编码后的状态向量，不需要创建文档
+func EncodeStateVectorFromUpdate(update []byte) []byte {
+	return EncodeStateVectorFromMap(stateVectorFromUpdate(util.NewUpdateDecoderV1(core.CreateDecoder(update))))
+}
+
+// EncodeStateVectorFromUpdateV2 从 V2 格式的更新计算编码后的状态向量，不需要创建文档
+func EncodeStateVectorFromUpdateV2(update []byte) []byte {
+	encoder := util.NewDSEncoderV2()
+	WriteStateVector(encoder, stateVectorFromUpdate(util.NewUpdateDecoderV2(core.CreateDecoder(update))))
+	return encoder.ToBytes()
+}
+
+// UpdateMeta 更新中每个客户端的结构所覆盖的时钟范围
+type UpdateMeta struct {
+	From map[int]int // 每个客户端的第一个时钟
+	To   map[int]int // 每个客户端最后一个结构之后的时钟
+}
+
+// parseUpdateMetaWith 读取更新中每个客户端的时钟范围
+func parseUpdateMetaWith(decoder util.UpdateDecoderInterface) UpdateMeta {
+	meta := UpdateMeta{From: make(map[int]int), To: make(map[int]int)}
+	reader := NewLazyStructReader(decoder, false)
+	curr := reader.Curr
+	if curr == nil {
+		return meta
+	}
+	currClient := curr.GetID().Client
+	currClock := curr.GetID().Clock
+	meta.From[currClient] = currClock
+	for ; curr != nil; curr = reader.Next() {
+		if currClient != curr.GetID().Client {
+			meta.To[currClient] = currClock
+			meta.From[curr.GetID().Client] = curr.GetID().Clock
+			currClient = curr.GetID().Client
+		}
+		currClock = curr.GetID().Clock + curr.GetLength()
+	}
+	meta.To[currClient] = currClock
+	return meta
+}
+
+// ParseUpdateMeta 读取 V1 格式的更新中每个客户端的时钟范围
+func ParseUpdateMeta(update []byte) UpdateMeta {
+	return parseUpdateMetaWith(util.NewUpdateDecoderV1(core.CreateDecoder(update)))
+}
+
+// ParseUpdateMetaV2 读取 V2 格式的更新中每个客户端的时钟范围
+func ParseUpdateMetaV2(update []byte) UpdateMeta {
+	return parseUpdateMetaWith(util.NewUpdateDecoderV2(core.CreateDecoder(update)))
+}
+
+// convertUpdateFormat 逐个读取结构并使用新的编码器写入，不需要创建文档
+func convertUpdateFormat(update []byte, newDecoder func(decoder *core.Decoder) util.UpdateDecoderInterface, newEncoder func() util.UpdateEncoderInterface) []byte {
+	updateDecoder := newDecoder(core.CreateDecoder(update))
+	lazyDecoder := NewLazyStructReader(updateDecoder, false)
+	updateEncoder := newEncoder()
+	lazyWriter := NewLazyStructWriter(updateEncoder)
+	for curr := lazyDecoder.Curr; curr != nil; curr = lazyDecoder.Next() {
+		lazyWriter.Write(curr, 0)
+	}
+	lazyWriter.Finish()
+	util.WriteDeleteSet(updateEncoder, util.ReadDeleteSet(updateDecoder))
+	return updateEncoder.ToBytes()
+}
+
+// ConvertUpdateFormatV1ToV2 将 V1 格式的更新转换为 V2 格式
+func ConvertUpdateFormatV1ToV2(update []byte) []byte {
+	return convertUpdateFormat(update, newUpdateDecoderV1, newUpdateEncoderV2)
+}
+
+// ConvertUpdateFormatV2ToV1 将 V2 格式的更新转换为 V1 格式
+func ConvertUpdateFormatV2ToV1(update []byte) []byte {
+	return convertUpdateFormat(update, newUpdateDecoderV2, newUpdateEncoderV1)
+}
+
+// newUpdateDecoderV1 创建 V1 更新解码器
+func newUpdateDecoderV1(decoder *core.Decoder) util.UpdateDecoderInterface {
+	return util.NewUpdateDecoderV1(decoder)
+}
+
+// newUpdateDecoderV2 创建 V2 更新解码器
+func newUpdateDecoderV2(decoder *core.Decoder) util.UpdateDecoderInterface {
+	return util.NewUpdateDecoderV2(decoder)
+}
+
+// newUpdateEncoderV1 创建 V1 更新编码器
+func newUpdateEncoderV1() util.UpdateEncoderInterface {
+	return util.NewUpdateEncoderV1()
+}
+
+// newUpdateEncoderV2 创建 V2 更新编码器
+func newUpdateEncoderV2() util.UpdateEncoderInterface {
+	return util.NewUpdateEncoderV2()
+}
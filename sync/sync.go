@@ -0,0 +1,93 @@
+// Package sync 实现与 y-protocols 兼容的文档同步协议
+//
+// 同步分为两步：客户端发送自己的状态向量（SyncStep1），对方回复客户端缺少的更新（SyncStep2）。
+// 双方都完成这两步后文档即已同步，之后的修改通过 Update 消息发送。
+// 从对方收到的更新以对方的 Peer 作为事务来源应用，监听文档的 update 事件时通过 Peer.IsOriginOf 避免将更新发回给对方
+package sync
+
+import (
+	"CollabEdit/core"
+	"CollabEdit/struts"
+	"CollabEdit/util"
+	"log"
+)
+
+// 同步协议的消息类型
+const (
+	MessageYjsSyncStep1 = 0 // 包含发送方状态向量的消息
+	MessageYjsSyncStep2 = 1 // 包含接收方缺少的更新的消息
+	MessageYjsUpdate    = 2 // 包含增量更新的消息
+)
+
+// Peer 同步的对方，作为应用对方更新时的事务来源，每个对方使用各自的 Peer
+type Peer struct {
+	Name string // 对方的名称，只用于调试
+}
+
+// NewPeer 创建同步的对方
+func NewPeer(name string) *Peer {
+	return &Peer{Name: name}
+}
+
+// IsOriginOf 判断文档更新是否是从该对方收到的，这样的更新不需要再发回给对方
+func (p *Peer) IsOriginOf(event *struts.UpdateEvent) bool {
+	origin, ok := event.Origin.(*Peer)
+	return ok && origin == p
+}
+
+// WriteSyncStep1 写入包含文档状态向量的 SyncStep1 消息
+func WriteSyncStep1(encoder *core.Encoder, doc *struts.Doc) {
+	encoder.WriteVarUint(MessageYjsSyncStep1)
+	encoder.WriteVarByteArray(struts.EncodeStateVector(doc))
+}
+
+// WriteSyncStep2 写入包含对方缺少的更新的 SyncStep2 消息，encodedStateVector 为对方编码后的状态向量
+func WriteSyncStep2(encoder *core.Encoder, doc *struts.Doc, encodedStateVector []byte) {
+	encoder.WriteVarUint(MessageYjsSyncStep2)
+	encoder.WriteVarByteArray(struts.EncodeStateAsUpdate(doc, encodedStateVector))
+}
+
+// ReadSyncStep1 读取 SyncStep1 消息，并将回复的 SyncStep2 消息写入编码器
+func ReadSyncStep1(decoder *core.Decoder, encoder *core.Encoder, doc *struts.Doc) {
+	WriteSyncStep2(encoder, doc, decoder.ReadVarUint8Array())
+}
+
+// ReadSyncStep2 读取 SyncStep2 消息，并以 transactionOrigin 为来源将更新应用到文档中，transactionOrigin 通常为发送消息的 Peer
+// 对方发送的更新无法应用时只记录错误，不影响之后的同步
+func ReadSyncStep2(decoder *core.Decoder, doc *struts.Doc, transactionOrigin interface{}) {
+	update := decoder.ReadVarUint8Array()
+	defer func() {
+		if err := recover(); err != nil {
+			log.Printf("[CollabEdit] 处理更新时出错: %v", err)
+		}
+	}()
+	struts.ApplyUpdate(doc, update, transactionOrigin)
+}
+
+// WriteUpdate 写入包含增量更新的 Update 消息
+func WriteUpdate(encoder *core.Encoder, update []byte) {
+	encoder.WriteVarUint(MessageYjsUpdate)
+	encoder.WriteVarByteArray(update)
+}
+
+// ReadUpdate 读取 Update 消息，与 SyncStep2 的处理方式相同
+func ReadUpdate(decoder *core.Decoder, doc *struts.Doc, transactionOrigin interface{}) {
+	ReadSyncStep2(decoder, doc, transactionOrigin)
+}
+
+// ReadSyncMessage 读取一条同步消息并分发处理，需要回复的内容写入 encoder，返回消息类型
+// 只有 SyncStep1 消息需要回复，调用方可以通过 encoder 是否有内容判断是否需要发送回复
+func ReadSyncMessage(decoder *core.Decoder, encoder *core.Encoder, doc *struts.Doc, transactionOrigin interface{}) int {
+	messageType := int(decoder.ReadVarUint())
+	switch messageType {
+	case MessageYjsSyncStep1:
+		ReadSyncStep1(decoder, encoder, doc)
+	case MessageYjsSyncStep2:
+		ReadSyncStep2(decoder, doc, transactionOrigin)
+	case MessageYjsUpdate:
+		ReadUpdate(decoder, doc, transactionOrigin)
+	default:
+		panic(util.ErrUnknownMessageType)
+	}
+	return messageType
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"CollabEdit/core"
+	"CollabEdit/struts"
+	ysync "CollabEdit/sync"
+	"CollabEdit/types"
+	"CollabEdit/util"
+	"testing"
+)
+
+// exchange 将 from 发送的消息交给 to 处理，返回 to 的回复
+func exchange(message []byte, to *struts.Doc, origin interface{}) []byte {
+	encoder := core.CreateEncoder()
+	ysync.ReadSyncMessage(core.CreateDecoder(message), encoder, to, origin)
+	return encoder.ToBytes()
+}
+
+func TestSyncSteps(t *testing.T) {
+	doc1 := struts.NewDoc(nil)
+	doc2 := struts.NewDoc(nil)
+	types.GetText(doc1, "text").Insert(0, "hello", nil)
+	types.GetText(doc2, "text").Insert(0, "world", nil)
+
+	// 双方互相发送 SyncStep1，并处理对方回复的 SyncStep2
+	for _, pair := range [][2]*struts.Doc{{doc1, doc2}, {doc2, doc1}} {
+		encoder := core.CreateEncoder()
+		ysync.WriteSyncStep1(encoder, pair[0])
+		reply := exchange(encoder.ToBytes(), pair[1], ysync.NewPeer("peer"))
+		if messageType := ysync.ReadSyncMessage(core.CreateDecoder(reply), core.CreateEncoder(), pair[0], ysync.NewPeer("peer")); messageType != ysync.MessageYjsSyncStep2 {
+			t.Fatalf("期望回复 SyncStep2, 但得到 %d", messageType)
+		}
+	}
+	text1, text2 := types.GetText(doc1, "text").ToString(), types.GetText(doc2, "text").ToString()
+	if text1 != text2 || len(text1) != 10 {
+		t.Errorf("同步后文档应相同, 但得到 %q 与 %q", text1, text2)
+	}
+}
+
+// TestSyncUpdateNotEchoed 从对方收到的更新以对方为来源应用，不会再发回给对方
+func TestSyncUpdateNotEchoed(t *testing.T) {
+	doc1 := struts.NewDoc(nil)
+	doc2 := struts.NewDoc(nil)
+	// peer1 是 doc2 眼中的 doc1，peer2 是 doc1 眼中的 doc2
+	peer1, peer2 := ysync.NewPeer("doc1"), ysync.NewPeer("doc2")
+	var sent [][]byte
+	doc2.On("update", func(args interface{}) {
+		event := args.(*struts.UpdateEvent)
+		if peer1.IsOriginOf(event) {
+			return
+		}
+		encoder := core.CreateEncoder()
+		ysync.WriteUpdate(encoder, event.Update)
+		sent = append(sent, encoder.ToBytes())
+	})
+	doc1.On("update", func(args interface{}) {
+		event := args.(*struts.UpdateEvent)
+		if peer2.IsOriginOf(event) {
+			return
+		}
+		encoder := core.CreateEncoder()
+		ysync.WriteUpdate(encoder, event.Update)
+		if reply := exchange(encoder.ToBytes(), doc2, peer1); len(reply) != 0 {
+			t.Errorf("Update 消息不需要回复")
+		}
+	})
+	types.GetMap(doc1, "map").Set("a", 1)
+	if len(sent) != 0 {
+		t.Errorf("来自对方的更新不应发回给对方")
+	}
+	types.GetMap(doc2, "map").Set("b", 2)
+	if len(sent) != 1 {
+		t.Fatalf("本地的修改应发送给对方, 但发送了 %d 条消息", len(sent))
+	}
+	exchange(sent[0], doc1, peer2)
+	if got := types.GetMap(doc1, "map").Get("b"); got != 2 {
+		t.Errorf("期望 2, 但得到 %v", got)
+	}
+}
+
+func TestSyncUnknownMessage(t *testing.T) {
+	defer func() {
+		if r := recover(); r != util.ErrUnknownMessageType {
+			t.Errorf("期望 ErrUnknownMessageType, 但得到 %v", r)
+		}
+	}()
+	exchange([]byte{3}, struts.NewDoc(nil), nil)
+}
+
+// TestPeerIsOriginOf 只有作为事务来源的 Peer 才是更新的来源，与名称无关
+func TestPeerIsOriginOf(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	peer, other := ysync.NewPeer("peer"), ysync.NewPeer("peer")
+	var event *struts.UpdateEvent
+	doc.On("update", func(args interface{}) { event = args.(*struts.UpdateEvent) })
+	doc.Transact(func(transaction *struts.Transaction) {
+		types.GetMap(doc, "map").Set("a", 1)
+	}, peer, false)
+	if !peer.IsOriginOf(event) || other.IsOriginOf(event) {
+		t.Errorf("只有 peer 应是更新的来源")
+	}
+}
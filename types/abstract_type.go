@@ -3,12 +3,16 @@ package types
 import (
 	"CollabEdit/struts"
 	"CollabEdit/util"
-	"errors"
-	"github.com/mitchellh/mapstructure"
 	"math"
 	"sync/atomic"
 )
 
+func init() {
+	struts.RegisterAbstractType(func() struts.AbstractTypeInterface {
+		return NewAbstractType()
+	})
+}
+
 // 最大搜索标记数量
 const maxSearchMarker = 80
 
@@ -132,9 +136,9 @@ func FindMarker(y_array AbstractTypeInterface, index int) *ArraySearchMarker {
 }
 
 // UpdateMarkerChanges 更新标记位置
-func UpdateMarkerChanges(searchMarker []*ArraySearchMarker, index, length int) {
-	for i := len(searchMarker) - 1; i >= 0; i-- {
-		m := searchMarker[i]
+func UpdateMarkerChanges(searchMarker *[]*ArraySearchMarker, index, length int) {
+	for i := len(*searchMarker) - 1; i >= 0; i-- {
+		m := (*searchMarker)[i]
 		if length > 0 {
 			p := m.P
 			p.Marker = false
@@ -147,14 +151,14 @@ func UpdateMarkerChanges(searchMarker []*ArraySearchMarker, index, length int) {
 			}
 			if p == nil || p.Marker {
 				// 如果更新位置为空或位置已被标记，则删除标记
-				searchMarker = append(searchMarker[:i], searchMarker[i+1:]...)
+				*searchMarker = append((*searchMarker)[:i], (*searchMarker)[i+1:]...)
 				continue
 			}
 			m.P = p
 			p.Marker = true
 		}
 		if index < m.Index || (length > 0 && index == m.Index) {
-			m.Index = int(math.Max(float64(m.Index), float64(index+length)))
+			m.Index = int(math.Max(float64(index), float64(m.Index+length)))
 		}
 	}
 }
@@ -171,8 +175,8 @@ func GetTypeChildren(t AbstractTypeInterface) []*struts.Item {
 }
 
 // CallTypeObservers 函数，调用事件监听器，并将事件添加到所有父类型的事件监听器中
-func CallTypeObservers(typeInstance AbstractTypeInterface, transaction *util.Transaction, event *interface{}) {
-	changedType := typeInstance
+func CallTypeObservers(changedType AbstractTypeInterface, transaction *struts.Transaction, event YEventInterface) {
+	var typeInstance struts.AbstractTypeInterface = changedType
 	changedParentTypes := transaction.ChangedParentTypes
 	for {
 		if _, exists := changedParentTypes[typeInstance]; !exists {
@@ -190,45 +194,31 @@ func CallTypeObservers(typeInstance AbstractTypeInterface, transaction *util.Tra
 
 // AbstractTypeInterface 接口定义
 type AbstractTypeInterface interface {
-	SetItem(item *struts.Item)                                                   // SetItem 设置项目
-	GetItem() *struts.Item                                                       // GetItem 获取项目
-	SetDataMap(dataMap map[string]*struts.Item)                                  // SetDataMap 设置项目
-	GetDataMap() map[string]*struts.Item                                         // GetDataMap 获取项目
-	SetStart(start *struts.Item)                                                 // SetStart 设置开始项目
-	GetStart() *struts.Item                                                      // GetStart 获取开始项目
-	SetLength(length int)                                                        // SetLength 设置长度
-	GetLength() int                                                              // GetLength 获取长度
-	SetHandler(handler *util.EventHandler)                                       // SetHandler 设置观察者
-	GetHandler() *util.EventHandler                                              // GetHandler 获取观察者
-	SetDeepHandler(handler *util.EventHandler)                                   // SetDeepHandler 设置深度观察者
-	GetDeepHandler() *util.EventHandler                                          // GetDeepHandler 获取深度观察者
-	SetSearchMarker(searchMarker *[]*ArraySearchMarker)                          // SetSearchMarker 设置全局搜索标记
-	GetSearchMarker() *[]*ArraySearchMarker                                      // GetSearchMarker 获取全局搜索标记
-	Parent() AbstractTypeInterface                                               // Parent 返回父类型
-	Integrate(y *util.Doc, item *struts.Item)                                    // Integrate 将此类型集成到 Yjs 实例中
-	Copy() AbstractTypeInterface                                                 // Copy 返回此数据类型的副本
-	Clone() AbstractTypeInterface                                                // Clone 返回此数据类型的副本
-	Write(encoder util.EncoderInterface)                                         // Write 将此类型写入编码器
-	First() *struts.Item                                                         // First 返回第一个未删除的项
-	CallObserver(transaction *util.Transaction, parentSubs map[interface{}]bool) // CallObserver 创建 YEvent 并调用所有类型观察者
-	Observe(f func(eventType *interface{}, transaction *util.Transaction))       // Observe 注册观察者函数
-	ObserveDeep(f func(events []*util.YEvent, transaction *util.Transaction))    // ObserveDeep 注册深度观察者函数
-	Unobserve(f func(eventType *interface{}, transaction *util.Transaction))     // Unobserve 取消注册观察者函数
-	UnobserveDeep(f func(events []*util.YEvent, transaction *util.Transaction))  // UnobserveDeep 取消注册深度观察者函数
-	ToJSON() interface{}                                                         // ToJSON 返回此类型的 JSON 表示
+	struts.AbstractTypeInterface                                                     // 结构层可见的类型接口
+	SetSearchMarker(searchMarker *[]*ArraySearchMarker)                              // SetSearchMarker 设置全局搜索标记
+	GetSearchMarker() *[]*ArraySearchMarker                                          // GetSearchMarker 获取全局搜索标记
+	Parent() struts.AbstractTypeInterface                                            // Parent 返回父类型
+	First() *struts.Item                                                             // First 返回第一个未删除的项
+	Observe(f func(event YEventInterface, transaction *struts.Transaction))          // Observe 注册观察者函数
+	ObserveDeep(f func(events []YEventInterface, transaction *struts.Transaction))   // ObserveDeep 注册深度观察者函数
+	Unobserve(f func(event YEventInterface, transaction *struts.Transaction))        // Unobserve 取消注册观察者函数
+	UnobserveDeep(f func(events []YEventInterface, transaction *struts.Transaction)) // UnobserveDeep 取消注册深度观察者函数
 }
 
 type AbstractType struct {
 	item         *struts.Item            // item 项目
 	DataMap      map[string]*struts.Item // DataMap 数据映射
 	start        *struts.Item            // start 开始项目
-	doc          *util.Doc               // doc 文档
+	doc          *struts.Doc             // doc 文档
 	length       int                     // length 长度
 	eventHandler *util.EventHandler      // eventHandler 事件处理器
 	deepHandler  *util.EventHandler      // deepHandler 深度事件处理器
 	searchMarker *[]*ArraySearchMarker   // searchMarker 搜索标记
 }
 
+// AbstractType 需要同时满足结构层与类型层的接口
+var _ AbstractTypeInterface = (*AbstractType)(nil)
+
 // NewAbstractType 创建一个新的 AbstractType 实例
 func NewAbstractType() *AbstractType {
 	// 返回一个新的 AbstractType 实例
@@ -254,6 +244,11 @@ func (a *AbstractType) GetItem() *struts.Item {
 	return a.item
 }
 
+// GetDoc 获取所属文档
+func (a *AbstractType) GetDoc() *struts.Doc {
+	return a.doc
+}
+
 // SetDataMap 设置项目
 func (a *AbstractType) SetDataMap(dataMap map[string]*struts.Item) {
 	a.DataMap = dataMap
@@ -314,8 +309,28 @@ func (a *AbstractType) GetSearchMarker() *[]*ArraySearchMarker {
 	return a.searchMarker
 }
 
+// MoveSearchMarkers 项目合并后，将指向 right 的搜索标记移动到 left 上并调整索引
+func (a *AbstractType) MoveSearchMarkers(left, right *struts.Item) {
+	if a.searchMarker == nil {
+		return
+	}
+	for _, marker := range *a.searchMarker {
+		if marker.P == right {
+			marker.P = left
+			if !left.GetDeleted() && left.Countable() {
+				marker.Index -= left.Length
+			}
+		}
+	}
+}
+
+// ClearSearchMarkers 移除并停用搜索标记，之后的位置查找都从头开始
+func (a *AbstractType) ClearSearchMarkers() {
+	a.searchMarker = nil
+}
+
 // Parent 方法返回父类型
-func (a *AbstractType) Parent() AbstractTypeInterface {
+func (a *AbstractType) Parent() struts.AbstractTypeInterface {
 	// 如果 item 不为 nil，则返回 item 的父类型
 	if a.item != nil {
 		return a.item.Parent
@@ -325,7 +340,7 @@ func (a *AbstractType) Parent() AbstractTypeInterface {
 }
 
 // Integrate 方法将此类型集成到 Yjs 实例中
-func (a *AbstractType) Integrate(y *util.Doc, item *struts.Item) {
+func (a *AbstractType) Integrate(y *struts.Doc, item *struts.Item) {
 	// 将 doc 设置为 y
 	a.doc = y
 	// 将 item 设置为 item
@@ -333,13 +348,13 @@ func (a *AbstractType) Integrate(y *util.Doc, item *struts.Item) {
 }
 
 // Copy 方法返回此数据类型的副本
-func (a *AbstractType) Copy() AbstractTypeInterface {
+func (a *AbstractType) Copy() struts.AbstractTypeInterface {
 	// 抛出未实现方法错误
 	panic(util.ErrMethodUnimplemented)
 }
 
 // Clone 方法返回此数据类型的副本
-func (a *AbstractType) Clone() AbstractTypeInterface {
+func (a *AbstractType) Clone() struts.AbstractTypeInterface {
 	// 抛出未实现方法错误
 	panic(util.ErrMethodUnimplemented)
 }
@@ -362,7 +377,7 @@ func (a *AbstractType) First() *struts.Item {
 }
 
 // CallObserver 方法创建 YEvent 并调用所有类型观察者
-func (a *AbstractType) CallObserver(transaction *util.Transaction, parentSubs map[interface{}]bool) {
+func (a *AbstractType) CallObserver(transaction *struts.Transaction, parentSubs map[string]struct{}) {
 	// 如果事务不是本地事务且 searchMarker 不为 nil，则将 searchMarker 设为空
 	if !transaction.Local && a.searchMarker != nil {
 		*a.searchMarker = nil
@@ -370,63 +385,48 @@ func (a *AbstractType) CallObserver(transaction *util.Transaction, parentSubs ma
 }
 
 // Observe 方法注册观察者函数
-func (a *AbstractType) Observe(f func(eventType *interface{}, transaction *util.Transaction)) {
-	// 添加事件处理逻辑（未实现）
-	// 包装函数，将 f 转换为符合 AddEvent 期望的类型
+func (a *AbstractType) Observe(f func(event YEventInterface, transaction *struts.Transaction)) {
+	// 包装函数，将 f 转换为符合 AddEvent 期望的类型，取消注册时仍然使用 f 识别
 	wrappedFunc := func(arg0 interface{}, arg1 interface{}) {
-		eventType, ok1 := arg0.(*interface{})
-		transaction, ok2 := arg1.(*util.Transaction)
-
+		event, ok1 := arg0.(YEventInterface)
+		transaction, ok2 := arg1.(*struts.Transaction)
 		if !ok1 || !ok2 {
 			panic(util.ErrTypeConversion)
 		}
-
-		f(eventType, transaction)
+		f(event, transaction)
 	}
-	a.eventHandler.AddEvent(wrappedFunc)
+	a.eventHandler.AddEventFor(f, wrappedFunc)
 }
 
-// ObserveDeep 方法注册深度观察者函数
-func (a *AbstractType) ObserveDeep(f func(events []*util.YEvent, transaction *util.Transaction)) {
-	// 添加事件处理逻辑
+// ObserveDeep 方法注册深度观察者函数，子类型的变化也会触发
+func (a *AbstractType) ObserveDeep(f func(events []YEventInterface, transaction *struts.Transaction)) {
 	wrappedFunc := func(arg0 interface{}, arg1 interface{}) {
-		events, ok1 := arg0.([]*util.YEvent)
-		transaction, ok2 := arg1.(*util.Transaction)
+		args, ok1 := arg0.([]interface{})
+		transaction, ok2 := arg1.(*struts.Transaction)
 		if !ok1 || !ok2 {
 			panic(util.ErrTypeConversion)
 		}
+		events := make([]YEventInterface, 0, len(args))
+		for _, arg := range args {
+			event, ok := arg.(YEventInterface)
+			if !ok {
+				panic(util.ErrTypeConversion)
+			}
+			events = append(events, event)
+		}
 		f(events, transaction)
 	}
-	a.eventHandler.AddEvent(wrappedFunc)
+	a.deepHandler.AddEventFor(f, wrappedFunc)
 }
 
 // Unobserve 方法取消注册观察者函数
-func (a *AbstractType) Unobserve(f func(eventType *interface{}, transaction *util.Transaction)) {
-	// 删除事件处理逻辑
-	// 添加事件处理逻辑
-	wrappedFunc := func(arg0 interface{}, arg1 interface{}) {
-		events, ok1 := arg0.(*interface{})
-		transaction, ok2 := arg1.(*util.Transaction)
-		if !ok1 || !ok2 {
-			panic(util.ErrTypeConversion)
-		}
-		f(events, transaction)
-	}
-	a.eventHandler.RemoveEvent(wrappedFunc)
+func (a *AbstractType) Unobserve(f func(event YEventInterface, transaction *struts.Transaction)) {
+	a.eventHandler.RemoveEvent(f)
 }
 
 // UnobserveDeep 方法取消注册深度观察者函数
-func (a *AbstractType) UnobserveDeep(f func(events []*util.YEvent, transaction *util.Transaction)) {
-	// 删除事件处理逻辑
-	wrappedFunc := func(arg0 interface{}, arg1 interface{}) {
-		events, ok1 := arg0.([]*util.YEvent)
-		transaction, ok2 := arg1.(*util.Transaction)
-		if !ok1 || !ok2 {
-			panic(util.ErrTypeConversion)
-		}
-		f(events, transaction)
-	}
-	a.eventHandler.RemoveEvent(wrappedFunc)
+func (a *AbstractType) UnobserveDeep(f func(events []YEventInterface, transaction *struts.Transaction)) {
+	a.deepHandler.RemoveEvent(f)
 }
 
 // ToJSON 方法返回此类型的 JSON 表示
@@ -635,8 +635,9 @@ func typeListGet(t AbstractTypeInterface, index int) interface{} {
 	return nil // 如果未找到，返回 nil
 }
 
-// typeListInsertGenericsAfter 在链表中插入多种类型的内容
-func typeListInsertGenericsAfter(transaction *util.Transaction, parent AbstractTypeInterface, referenceItem *struts.Item, content []interface{}) {
+// typeListInsertGenericsAfter 在链表中 referenceItem 之后插入多种类型的内容
+// 连续的普通值会被打包为一个 ContentAny，二进制数据、子文档与共享类型各自占用一个项目
+func typeListInsertGenericsAfter(transaction *struts.Transaction, parent AbstractTypeInterface, referenceItem *struts.Item, content []interface{}) {
 	left := referenceItem
 	doc := transaction.Doc
 	ownClientId := doc.ClientID
@@ -645,107 +646,221 @@ func typeListInsertGenericsAfter(transaction *util.Transaction, parent AbstractT
 	if referenceItem != nil {
 		right = referenceItem.Right
 	}
-
-	var jsonContent []interface{}
-	var leftID *util.ID
-	if left != nil {
-		leftID = left.LastId()
-	}
-
 	var rightID *util.ID
 	if right != nil {
 		rightID = right.ID
 	}
+
+	var jsonContent []interface{}
+	// insertAfterLeft 在 left 之后插入内容，并将新项目作为新的 left
+	insertAfterLeft := func(c struts.AbstractContentInterface) {
+		var leftID *util.ID
+		if left != nil {
+			leftID = left.LastId()
+		}
+		left = struts.NewItem(util.NewID(ownClientId, struts.GetState(store, ownClientId)), left, leftID, right, rightID, parent, "", c)
+		left.Integrate(transaction, 0)
+	}
 	packJsonContent := func() {
 		if len(jsonContent) > 0 {
-			clock := util.GetState(store, ownClientId)
-			id := util.NewID(ownClientId, clock)
-			left = struts.NewItem(id, left, leftID, right, rightID, parent, "", struts.NewContentAny(jsonContent))
-			left.Integrate(transaction, 0)
+			insertAfterLeft(struts.NewContentAny(jsonContent))
 			jsonContent = nil
 		}
 	}
 
 	for _, c := range content {
-		if c == nil {
+		switch v := c.(type) {
+		case []byte:
+			packJsonContent()
+			insertAfterLeft(struts.NewContentBinary(v))
+		case *struts.Doc:
+			packJsonContent()
+			insertAfterLeft(struts.NewContentDoc(v))
+		case AbstractTypeInterface:
+			packJsonContent()
+			insertAfterLeft(struts.NewContentType(v))
+		default:
 			jsonContent = append(jsonContent, c)
-		} else {
-			switch c.(type) {
-			case float64, int, map[string]interface{}, bool, []interface{}, string:
-				jsonContent = append(jsonContent, c)
-			default:
-				packJsonContent()
-				switch _ := c.(type) {
-				case []byte:
-					left = struts.NewItem(
-						util.NewID(ownClientId, util.GetState(store, ownClientId)),
-						left,
-						leftID,
-						right,
-						rightID,
-						parent,
-						"",
-						struts.NewContentBinary(convertToByteSlice(c)),
-					)
-					left.Integrate(transaction, 0)
-					break
-				case util.Doc:
-					left = struts.NewItem(
-						util.NewID(ownClientId, util.GetState(store, ownClientId)),
-						left,
-						leftID,
-						right,
-						rightID,
-						parent,
-						"",
-						struts.NewContentDoc(convertToDoc(c)),
-					)
-					left.Integrate(transaction, 0)
-					break
-				case AbstractTypeInterface:
-					left = struts.NewItem(
-						util.NewID(ownClientId, util.GetState(store, ownClientId)),
-						left,
-						leftID,
-						right,
-						rightID,
-						parent,
-						"",
-						struts.NewContentType(convertToAbstractTypeInterface(c)),
-					)
-					left.Integrate(transaction, 0)
-				default:
-					panic(errors.New("unexpected content type in insert operation"))
+		}
+	}
+	packJsonContent()
+}
+
+// typeListInsertGenerics 在指定索引处插入多种类型的内容
+func typeListInsertGenerics(transaction *struts.Transaction, parent AbstractTypeInterface, index int, content []interface{}) {
+	if index > parent.GetLength() {
+		panic(util.ErrLengthExceeded)
+	}
+	if index == 0 {
+		if parent.GetSearchMarker() != nil {
+			UpdateMarkerChanges(parent.GetSearchMarker(), index, len(content))
+		}
+		typeListInsertGenericsAfter(transaction, parent, nil, content)
+		return
+	}
+	startIndex := index
+	marker := FindMarker(parent, index)
+	n := parent.GetStart()
+	if marker != nil {
+		n = marker.P
+		index -= marker.Index
+		// 需要向左移动一个项目，这样下面的算法才能正常工作
+		if index == 0 {
+			n = n.Prev()
+			if n != nil && n.Countable() && !n.GetDeleted() {
+				index += n.Length
+			}
+		}
+	}
+	for ; n != nil; n = n.Right {
+		if !n.GetDeleted() && n.Countable() {
+			if index <= n.Length {
+				if index < n.Length {
+					// 在项目中间插入，需要先分割项目
+					struts.GetItemCleanStart(transaction, util.NewID(n.ID.Client, n.ID.Clock+index))
 				}
+				break
 			}
+			index -= n.Length
 		}
 	}
-	packJsonContent()
+	if parent.GetSearchMarker() != nil {
+		UpdateMarkerChanges(parent.GetSearchMarker(), startIndex, len(content))
+	}
+	typeListInsertGenericsAfter(transaction, parent, n, content)
 }
 
-func convertToByteSlice(content interface{}) []byte {
-	var result []byte
-	err := mapstructure.Decode(content, &result)
-	if err != nil {
-		panic(err)
+// typeListPushGenerics 在链表末尾追加多种类型的内容
+func typeListPushGenerics(transaction *struts.Transaction, parent AbstractTypeInterface, content []interface{}) {
+	// 从索引最大的标记开始向右查找最后一个项目
+	n := parent.GetStart()
+	maxIndex := 0
+	if searchMarker := parent.GetSearchMarker(); searchMarker != nil {
+		for _, marker := range *searchMarker {
+			if marker.Index > maxIndex {
+				maxIndex = marker.Index
+				n = marker.P
+			}
+		}
 	}
-	return result
+	if n != nil {
+		for n.Right != nil {
+			n = n.Right
+		}
+	}
+	typeListInsertGenericsAfter(transaction, parent, n, content)
 }
 
-func convertToDoc(content interface{}) *util.Doc {
-	var result *util.Doc
-	err := mapstructure.Decode(content, &result)
-	if err != nil {
-		panic(err)
+// typeListDelete 从指定索引开始删除 length 个元素
+func typeListDelete(transaction *struts.Transaction, parent AbstractTypeInterface, index int, length int) {
+	if length == 0 {
+		return
+	}
+	startIndex := index
+	startLength := length
+	marker := FindMarker(parent, index)
+	n := parent.GetStart()
+	if marker != nil {
+		n = marker.P
+		index -= marker.Index
+	}
+	// 找到第一个需要删除的项目
+	for ; n != nil && index > 0; n = n.Right {
+		if !n.GetDeleted() && n.Countable() {
+			if index < n.Length {
+				struts.GetItemCleanStart(transaction, util.NewID(n.ID.Client, n.ID.Clock+index))
+			}
+			index -= n.Length
+		}
+	}
+	// 删除项目直到满足长度
+	for length > 0 && n != nil {
+		if !n.GetDeleted() {
+			if length < n.Length {
+				struts.GetItemCleanStart(transaction, util.NewID(n.ID.Client, n.ID.Clock+length))
+			}
+			n.Delete(transaction)
+			length -= n.Length
+		}
+		n = n.Right
+	}
+	if length > 0 {
+		panic(util.ErrLengthExceeded)
+	}
+	if parent.GetSearchMarker() != nil {
+		UpdateMarkerChanges(parent.GetSearchMarker(), startIndex, -startLength+length)
 	}
-	return result
 }
 
-func convertToAbstractTypeInterface(content interface{}) AbstractTypeInterface {
-	var result AbstractTypeInterface
-	err := mapstructure.Decode(content, &result)
-	if err != nil {
-		panic(err)
+// typeMapDelete 删除映射中的键
+func typeMapDelete(transaction *struts.Transaction, parent AbstractTypeInterface, key string) {
+	if c, exists := parent.GetDataMap()[key]; exists {
+		c.Delete(transaction)
 	}
-	return result
+}
+
+// typeMapSet 设置映射中键的值，新项目以当前值为左侧项目整合，并发设置时由整合顺序决定最终的值
+func typeMapSet(transaction *struts.Transaction, parent AbstractTypeInterface, key string, value interface{}) {
+	left := parent.GetDataMap()[key]
+	doc := transaction.Doc
+	ownClientId := doc.ClientID
+	var content struts.AbstractContentInterface
+	switch v := value.(type) {
+	case []byte:
+		content = struts.NewContentBinary(v)
+	case *struts.Doc:
+		content = struts.NewContentDoc(v)
+	case AbstractTypeInterface:
+		content = struts.NewContentType(v)
+	default:
+		content = struts.NewContentAny([]interface{}{value})
+	}
+	var leftID *util.ID
+	if left != nil {
+		leftID = left.LastId()
+	}
+	item := struts.NewItem(util.NewID(ownClientId, struts.GetState(doc.Store, ownClientId)), left, leftID, nil, nil, parent, key, content)
+	item.Integrate(transaction, 0)
+}
+
+// typeMapGet 获取映射中键的值，不存在时返回 nil
+func typeMapGet(parent AbstractTypeInterface, key string) interface{} {
+	val, exists := parent.GetDataMap()[key]
+	if !exists || val.GetDeleted() {
+		return nil
+	}
+	return val.Content.GetContent()[val.Length-1]
+}
+
+// typeMapGetAll 获取映射中所有未删除的键值对
+func typeMapGetAll(parent AbstractTypeInterface) map[string]interface{} {
+	res := make(map[string]interface{})
+	for key, value := range parent.GetDataMap() {
+		if !value.GetDeleted() {
+			res[key] = value.Content.GetContent()[value.Length-1]
+		}
+	}
+	return res
+}
+
+// typeMapHas 检查映射中是否存在键
+func typeMapHas(parent AbstractTypeInterface, key string) bool {
+	val, exists := parent.GetDataMap()[key]
+	return exists && !val.GetDeleted()
+}
+
+// typeMapGetSnapshot 获取键在快照中的值
+func typeMapGetSnapshot(parent AbstractTypeInterface, key string, snapshot *util.Snapshot) interface{} {
+	v := parent.GetDataMap()[key]
+	for v != nil {
+		clock, exists := snapshot.Sv[v.ID.Client]
+		if exists && v.ID.Clock < clock {
+			break
+		}
+		v = v.Left
+	}
+	if v != nil && IsVisible(v, snapshot) {
+		return v.Content.GetContent()[v.Length-1]
+	}
+	return nil
 }
@@ -0,0 +1,40 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"CollabEdit/util"
+	"reflect"
+	"testing"
+)
+
+func TestAbstractTypeAsItemParent(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	yType := types.NewAbstractType()
+	yType.Integrate(doc, nil)
+	if yType.GetDoc() != doc {
+		t.Fatalf("期望类型集成到文档中")
+	}
+
+	// 手动构造一个属于该类型的项目，并放入结构体存储
+	item := struts.NewItem(util.NewID(1, 0), nil, nil, nil, nil, yType, "", struts.NewContentAny([]interface{}{"a", "b", "c"}))
+	yType.SetStart(item)
+	yType.SetLength(item.Length)
+	doc.Store.Clients[1] = &[]struts.AbstractStructInterface{item}
+
+	// 在 clock=0 处分割项目，右侧项目应该挂在同一个父类型上
+	transaction := &struts.Transaction{Doc: doc}
+	left := struts.GetItemCleanEnd(transaction, doc.Store, util.NewID(1, 0))
+	if left != item || left.Length != 1 || left.Right == nil {
+		t.Fatalf("期望项目被分割为两部分")
+	}
+	if left.Right.Parent != yType {
+		t.Errorf("期望右侧项目的父类型为 %v, 但得到 %v", yType, left.Right.Parent)
+	}
+	if len(*doc.Store.Clients[1]) != 2 {
+		t.Errorf("期望存储中有 2 个结构体, 但得到 %d", len(*doc.Store.Clients[1]))
+	}
+	if got := types.TypeListToArray(yType); !reflect.DeepEqual(got, []interface{}{"a", "b", "c"}) {
+		t.Errorf("期望内容为 [a b c], 但得到 %v", got)
+	}
+}
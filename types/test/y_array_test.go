@@ -0,0 +1,112 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestYArrayPrelimContent(t *testing.T) {
+	nested := types.NewYArrayFrom([]interface{}{1, 2})
+	nested.Insert(1, []interface{}{"x"})
+	if nested.Length() != 3 {
+		t.Fatalf("期望整合前长度为 3, 但得到 %d", nested.Length())
+	}
+
+	doc := struts.NewDoc(nil)
+	root := types.GetArray(doc, "list")
+	if types.GetArray(doc, "list") != root {
+		t.Fatalf("同名的顶层类型应返回同一个实例")
+	}
+	root.Push([]interface{}{"a", nested, []byte{1}})
+	root.Unshift([]interface{}{true})
+
+	if got := nested.ToArray(); !reflect.DeepEqual(got, []interface{}{1, "x", 2}) {
+		t.Errorf("整合后期望 [1 x 2], 但得到 %v", got)
+	}
+	expected := []interface{}{true, "a", []interface{}{1, "x", 2}, []byte{1}}
+	if got := root.ToJSON(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("期望 %v, 但得到 %v", expected, got)
+	}
+	if root.Get(2) != nested {
+		t.Errorf("期望索引 2 处为嵌套数组")
+	}
+}
+
+func TestYArrayRandomEdits(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	arr := types.GetArray(doc, "list")
+	var expected []interface{}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		if len(expected) > 0 && r.Intn(3) == 0 {
+			index := r.Intn(len(expected))
+			length := 1 + r.Intn(len(expected)-index)
+			if length > 3 {
+				length = 3
+			}
+			arr.Delete(index, length)
+			expected = append(expected[:index], expected[index+length:]...)
+		} else {
+			index := r.Intn(len(expected) + 1)
+			content := []interface{}{i, i + 1000}
+			arr.Insert(index, content)
+			next := append([]interface{}{}, expected[:index]...)
+			next = append(next, content...)
+			expected = append(next, expected[index:]...)
+		}
+		if arr.Length() != len(expected) {
+			t.Fatalf("第 %d 次操作后期望长度 %d, 但得到 %d", i, len(expected), arr.Length())
+		}
+		if len(expected) > 0 {
+			index := r.Intn(len(expected))
+			if got := arr.Get(index); got != expected[index] {
+				t.Fatalf("第 %d 次操作后索引 %d 期望 %v, 但得到 %v", i, index, expected[index], got)
+			}
+		}
+	}
+	if got := arr.ToArray(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("期望 %v, 但得到 %v", expected, got)
+	}
+	if got := arr.Slice(1, 3); !reflect.DeepEqual(got, expected[1:3]) {
+		t.Errorf("期望切片 %v, 但得到 %v", expected[1:3], got)
+	}
+}
+
+func TestYArrayObserve(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	root := types.GetArray(doc, "list")
+	nested := types.NewYArray()
+	root.Push([]interface{}{nested})
+
+	calls := 0
+	observer := func(event types.YEventInterface, transaction *struts.Transaction) {
+		calls++
+		if _, ok := event.(*types.YArrayEvent); !ok || event.GetYEvent().Target != root {
+			t.Errorf("期望收到 root 的 YArrayEvent")
+		}
+	}
+	var deepEvents []types.YEventInterface
+	deepObserver := func(events []types.YEventInterface, transaction *struts.Transaction) {
+		deepEvents = events
+	}
+	root.Observe(observer)
+	root.ObserveDeep(deepObserver)
+
+	root.Push([]interface{}{1})
+	nested.Push([]interface{}{2})
+	if calls != 1 {
+		t.Errorf("期望观察者被调用 1 次, 但得到 %d", calls)
+	}
+	if len(deepEvents) != 1 || deepEvents[0].GetYEvent().Target != nested || deepEvents[0].GetYEvent().CurrentTarget != root {
+		t.Errorf("深度观察者应收到嵌套数组的事件，且当前目标为 root")
+	}
+
+	root.Unobserve(observer)
+	root.Push([]interface{}{3})
+	if calls != 1 {
+		t.Errorf("取消注册后观察者不应再被调用")
+	}
+}
@@ -0,0 +1,150 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"CollabEdit/util"
+	"reflect"
+	"testing"
+)
+
+func TestYEventArrayDelta(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	arr := types.GetArray(doc, "array")
+	arr.Insert(0, []interface{}{1, 2, 3, 4})
+	var delta []types.DeltaOp
+	var changes *types.YEventChanges
+	arr.Observe(func(event types.YEventInterface, transaction *struts.Transaction) {
+		delta = event.Delta()
+		changes = event.Changes()
+	})
+	doc.Transact(func(transaction *struts.Transaction) {
+		arr.Delete(1, 2)
+		arr.Insert(2, []interface{}{"a", "b"})
+	}, nil, true)
+	expected := []types.DeltaOp{{Retain: 1}, {Delete: 2}, {Retain: 1}, {Insert: []interface{}{"a", "b"}}}
+	if !reflect.DeepEqual(delta, expected) {
+		t.Errorf("期望 %v, 但得到 %v", expected, delta)
+	}
+	if len(changes.Added) != 1 || len(changes.Deleted) != 1 {
+		t.Errorf("期望新增 1 个项目并删除 1 个项目, 但得到 %d 与 %d", len(changes.Added), len(changes.Deleted))
+	}
+}
+
+func TestYEventMapKeys(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	m := types.GetMap(doc, "map")
+	m.Set("update", 1)
+	m.Set("delete", 2)
+	var keys map[string]*types.YEventKeyChange
+	m.Observe(func(event types.YEventInterface, transaction *struts.Transaction) {
+		keys = event.Keys()
+	})
+	doc.Transact(func(transaction *struts.Transaction) {
+		m.Set("update", 3)
+		m.Delete("delete")
+		m.Set("add", 4)
+		// 同一事务中新增又删除的键没有变化
+		m.Set("tmp", 5)
+		m.Delete("tmp")
+	}, nil, true)
+	expected := map[string]*types.YEventKeyChange{
+		"update": {Action: "update", OldValue: 1},
+		"delete": {Action: "delete", OldValue: 2},
+		"add":    {Action: "add"},
+	}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("期望 %v, 但得到 %v", expected, keys)
+	}
+}
+
+func TestYEventTextDelta(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	text := types.GetText(doc, "text")
+	text.Insert(0, "abcdef", nil)
+	var delta []types.DeltaOp
+	text.Observe(func(event types.YEventInterface, transaction *struts.Transaction) {
+		delta = event.Delta()
+	})
+	doc.Transact(func(transaction *struts.Transaction) {
+		text.Delete(0, 1)
+		text.Format(1, 2, map[string]interface{}{"bold": true})
+		text.Insert(5, "xy", map[string]interface{}{"italic": true})
+	}, nil, true)
+	expected := []types.DeltaOp{
+		{Delete: 1},
+		{Retain: 1},
+		{Retain: 2, Attributes: map[string]interface{}{"bold": true}},
+		{Retain: 2},
+		{Insert: "xy", Attributes: map[string]interface{}{"italic": true}},
+	}
+	if !reflect.DeepEqual(delta, expected) {
+		t.Errorf("期望 %v, 但得到 %v", expected, delta)
+	}
+}
+
+func TestYEventPath(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	root := types.GetMap(doc, "map")
+	root.Set("list", types.NewYArrayFrom([]interface{}{"x", types.NewYMap()}))
+	nested := root.Get("list").(*types.YArray).Get(1).(*types.YMap)
+	var paths [][]interface{}
+	root.ObserveDeep(func(events []types.YEventInterface, transaction *struts.Transaction) {
+		for _, event := range events {
+			paths = append(paths, event.Path())
+		}
+	})
+	var ownPath []interface{}
+	nested.ObserveDeep(func(events []types.YEventInterface, transaction *struts.Transaction) {
+		ownPath = events[0].Path()
+	})
+	nested.Set("k", "v")
+	if !reflect.DeepEqual(paths, [][]interface{}{{"list", 1}}) {
+		t.Errorf("期望路径 [list 1], 但得到 %v", paths)
+	}
+	if len(ownPath) != 0 {
+		t.Errorf("目标自身的路径应为空, 但得到 %v", ownPath)
+	}
+}
+
+func TestYEventChangesOutsideHandler(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	arr := types.GetArray(doc, "array")
+	var saved types.YEventInterface
+	arr.Observe(func(event types.YEventInterface, transaction *struts.Transaction) {
+		saved = event
+	})
+	arr.Insert(0, []interface{}{1})
+	defer func() {
+		if r := recover(); r != util.ErrComputeChanges {
+			t.Errorf("期望 ErrComputeChanges, 但得到 %v", r)
+		}
+	}()
+	saved.Changes()
+}
+
+// TestYEventDeepOrder 深度观察者收到的事件按路径长度排序，父类型的事件在前
+func TestYEventDeepOrder(t *testing.T) {
+	for run := 0; run < 50; run++ {
+		doc := struts.NewDoc(nil)
+		root := types.GetMap(doc, "root")
+		root.Set("a", types.NewYMap())
+		a := root.Get("a").(*types.YMap)
+		a.Set("b", types.NewYMap())
+		b := a.Get("b").(*types.YMap)
+		var lengths []int
+		root.ObserveDeep(func(events []types.YEventInterface, transaction *struts.Transaction) {
+			for _, event := range events {
+				lengths = append(lengths, len(event.Path()))
+			}
+		})
+		doc.Transact(func(transaction *struts.Transaction) {
+			b.Set("k", 1)
+			a.Set("k", 1)
+			root.Set("k", 1)
+		}, nil, true)
+		if !reflect.DeepEqual(lengths, []int{0, 1, 2}) {
+			t.Fatalf("期望路径长度依次为 [0 1 2], 但得到 %v", lengths)
+		}
+	}
+}
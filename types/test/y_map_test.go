@@ -0,0 +1,77 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"CollabEdit/util"
+	"reflect"
+	"testing"
+)
+
+func TestYMapBasicOperations(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	m := types.GetMap(doc, "map")
+	nested := types.NewYMapFrom(map[string]interface{}{"x": 1})
+	m.Set("a", "1")
+	m.Set("b", nested)
+	m.Set("a", "2")
+
+	if m.Get("a") != "2" || !m.Has("b") || m.Has("c") || m.Size() != 2 {
+		t.Fatalf("映射内容不正确: %v", m.ToJSON())
+	}
+	if got := m.Keys(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("期望键 [a b], 但得到 %v", got)
+	}
+	expected := map[string]interface{}{"a": "2", "b": map[string]interface{}{"x": 1}}
+	if got := m.ToJSON(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("期望 %v, 但得到 %v", expected, got)
+	}
+
+	m.Delete("a")
+	if m.Has("a") || m.Get("a") != nil {
+		t.Errorf("删除后键 a 不应存在")
+	}
+	m.Clear()
+	if m.Size() != 0 {
+		t.Errorf("清空后映射应为空")
+	}
+}
+
+func TestYMapKeysChanged(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	m := types.GetMap(doc, "map")
+	var keysChanged map[string]struct{}
+	m.Observe(func(event types.YEventInterface, transaction *struts.Transaction) {
+		keysChanged = event.(*types.YMapEvent).KeysChanged
+	})
+	doc.Transact(func(transaction *struts.Transaction) {
+		m.Set("a", 1)
+		m.Set("b", 2)
+	}, nil, true)
+	if !reflect.DeepEqual(keysChanged, map[string]struct{}{"a": {}, "b": {}}) {
+		t.Errorf("期望变化的键为 a 和 b, 但得到 %v", keysChanged)
+	}
+}
+
+// TestYMapConcurrentSet 并发设置同一个键时，客户端 ID 较大的项目排在右侧，成为最终的值
+func TestYMapConcurrentSet(t *testing.T) {
+	for _, remoteClient := range []int{1, 3} {
+		doc := struts.NewDoc(nil)
+		doc.ClientID = 2
+		m := types.GetMap(doc, "map")
+		m.Set("k", "local")
+		// 远程客户端在不知道本地值的情况下设置了同一个键
+		doc.Transact(func(transaction *struts.Transaction) {
+			item := struts.NewItem(util.NewID(remoteClient, 0), nil, nil, nil, nil, m, "k", struts.NewContentAny([]interface{}{"remote"}))
+			item.Integrate(transaction, 0)
+		}, nil, false)
+
+		expected := "local"
+		if remoteClient > doc.ClientID {
+			expected = "remote"
+		}
+		if got := m.Get("k"); got != expected {
+			t.Errorf("远程客户端 %d: 期望 %v, 但得到 %v", remoteClient, expected, got)
+		}
+	}
+}
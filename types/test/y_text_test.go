@@ -0,0 +1,125 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"CollabEdit/util"
+	"reflect"
+	"testing"
+)
+
+func TestYTextInsertFormatDelete(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	text := types.GetText(doc, "text")
+	text.Insert(0, "world", nil)
+	text.Insert(0, "hello ", nil)
+	text.Format(0, 5, map[string]interface{}{"bold": true})
+	if got := text.ToString(); got != "hello world" {
+		t.Fatalf("期望 hello world, 但得到 %q", got)
+	}
+	expected := []types.DeltaOp{
+		{Insert: "hello", Attributes: map[string]interface{}{"bold": true}},
+		{Insert: " world"},
+	}
+	if got := text.ToDelta(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("期望 %v, 但得到 %v", expected, got)
+	}
+
+	// 在加粗文本末尾插入时沿用加粗属性
+	text.Insert(5, "!", nil)
+	text.Delete(6, 6)
+	text.Format(0, 3, map[string]interface{}{"bold": nil})
+	expected = []types.DeltaOp{
+		{Insert: "hel"},
+		{Insert: "lo!", Attributes: map[string]interface{}{"bold": true}},
+	}
+	if got := text.ToDelta(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("期望 %v, 但得到 %v", expected, got)
+	}
+	if text.Length() != 6 {
+		t.Errorf("期望长度 6, 但得到 %d", text.Length())
+	}
+}
+
+func TestYTextApplyDelta(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	text := types.GetText(doc, "text")
+	text.ApplyDelta([]types.DeltaOp{
+		{Insert: "abc", Attributes: map[string]interface{}{"italic": true}},
+		{Insert: map[string]interface{}{"image": "a.png"}},
+		{Insert: "def"},
+	})
+	text.ApplyDelta([]types.DeltaOp{{Retain: 1}, {Delete: 1}, {Retain: 2, Attributes: map[string]interface{}{"italic": nil}}})
+	expected := []types.DeltaOp{
+		{Insert: "a", Attributes: map[string]interface{}{"italic": true}},
+		{Insert: "c"},
+		{Insert: map[string]interface{}{"image": "a.png"}},
+		{Insert: "def"},
+	}
+	if got := text.ToDelta(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("期望 %v, 但得到 %v", expected, got)
+	}
+	clone := text.Clone().(*types.YText)
+	types.GetMap(doc, "map").Set("clone", clone)
+	if got := clone.ToDelta(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("副本期望 %v, 但得到 %v", expected, got)
+	}
+}
+
+// TestYTextUTF16 索引与长度以 UTF-16 码元计算，与 Yjs 保持一致
+func TestYTextUTF16(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	text := types.GetText(doc, "text")
+	text.Insert(0, "a😀b", nil)
+	if text.Length() != 4 {
+		t.Fatalf("期望长度 4, 但得到 %d", text.Length())
+	}
+	text.Delete(1, 2)
+	if got := text.ToString(); got != "ab" {
+		t.Errorf("期望 ab, 但得到 %q", got)
+	}
+}
+
+func TestYTextPrelim(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	text := types.NewYText("abc")
+	text.Format(0, 1, map[string]interface{}{"bold": true})
+	types.GetArray(doc, "array").Push([]interface{}{text})
+	expected := []types.DeltaOp{
+		{Insert: "a", Attributes: map[string]interface{}{"bold": true}},
+		{Insert: "bc"},
+	}
+	if got := text.ToDelta(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("期望 %v, 但得到 %v", expected, got)
+	}
+}
+
+// TestYTextFormattingCleanup 远程并发插入相同的格式化属性后，多余的格式化项目会在事务结束时被删除
+func TestYTextFormattingCleanup(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	doc.ClientID = 2
+	text := types.GetText(doc, "text")
+	text.Insert(0, "abc", nil)
+	text.Format(0, 3, map[string]interface{}{"bold": true})
+
+	bold := text.GetStart()
+	first := bold.Right
+	doc.Transact(func(transaction *struts.Transaction) {
+		item := struts.NewItem(util.NewID(1, 0), bold, bold.LastId(), first, first.ID, text, "", struts.NewContentFormat("bold", true))
+		item.Integrate(transaction, 0)
+	}, nil, false)
+
+	formats := 0
+	for n := text.GetStart(); n != nil; n = n.Right {
+		if _, ok := n.Content.(*struts.ContentFormat); ok && !n.GetDeleted() {
+			formats++
+		}
+	}
+	if formats != 2 {
+		t.Errorf("期望剩余 2 个格式化项目, 但得到 %d", formats)
+	}
+	expected := []types.DeltaOp{{Insert: "abc", Attributes: map[string]interface{}{"bold": true}}}
+	if got := text.ToDelta(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("期望 %v, 但得到 %v", expected, got)
+	}
+}
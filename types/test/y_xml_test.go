@@ -0,0 +1,106 @@
+package test
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/types"
+	"testing"
+)
+
+func TestYXmlToString(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	fragment := types.GetXmlFragment(doc, "prosemirror")
+
+	paragraph := types.NewYXmlElement("p")
+	paragraph.SetAttribute("class", "intro")
+	text := types.NewYXmlText("hello world")
+	text.Format(0, 5, map[string]interface{}{"strong": true, "a": map[string]interface{}{"href": "x"}})
+	paragraph.Insert(0, []interface{}{text})
+	fragment.Push([]interface{}{paragraph, types.NewYXmlElement("hr")})
+
+	expected := `<p class="intro"><a href="x"><strong>hello</strong></a> world</p><hr></hr>`
+	if got := fragment.ToString(); got != expected {
+		t.Fatalf("期望 %s, 但得到 %s", expected, got)
+	}
+	if text.GetItem().Parent != paragraph || paragraph.GetItem().Parent != fragment {
+		t.Errorf("子节点的父类型应为外层的 XML 类型")
+	}
+	if paragraph.NextSibling() == nil || paragraph.PrevSibling() != nil {
+		t.Errorf("兄弟节点不正确")
+	}
+
+	clone := paragraph.Clone().(*types.YXmlElement)
+	fragment.Push([]interface{}{clone})
+	if got := clone.ToString(); got != paragraph.ToString() {
+		t.Errorf("副本期望 %s, 但得到 %s", paragraph.ToString(), got)
+	}
+}
+
+func TestYXmlTreeWalker(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	fragment := types.GetXmlFragment(doc, "xml")
+	div := types.NewYXmlElement("div")
+	div.Push([]interface{}{types.NewYXmlElement("P"), types.NewYXmlText("t"), types.NewYXmlElement("span")})
+	fragment.Push([]interface{}{div, types.NewYXmlElement("p")})
+
+	var names []string
+	walker := fragment.CreateTreeWalker(nil)
+	for node, ok := walker.Next(); ok; node, ok = walker.Next() {
+		if el, ok := node.(*types.YXmlElement); ok {
+			names = append(names, el.NodeName)
+		} else {
+			names = append(names, "#text")
+		}
+	}
+	expected := []string{"div", "P", "#text", "span", "p"}
+	if len(names) != len(expected) {
+		t.Fatalf("期望 %v, 但得到 %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("期望 %v, 但得到 %v", expected, names)
+		}
+	}
+	if got := fragment.QuerySelectorAll("p"); len(got) != 2 {
+		t.Errorf("期望找到 2 个 p 元素, 但得到 %d", len(got))
+	}
+	if got := fragment.QuerySelector("span"); got == nil || got.Parent() != div {
+		t.Errorf("未找到 div 中的 span 元素")
+	}
+}
+
+func TestYXmlEvent(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	fragment := types.GetXmlFragment(doc, "xml")
+	el := types.NewYXmlElement("div")
+	fragment.Push([]interface{}{el})
+
+	var event *types.YXmlEvent
+	el.Observe(func(e types.YEventInterface, transaction *struts.Transaction) {
+		event = e.(*types.YXmlEvent)
+	})
+	doc.Transact(func(transaction *struts.Transaction) {
+		el.SetAttribute("id", "main")
+		el.Push([]interface{}{types.NewYXmlText("x")})
+	}, nil, true)
+	if event == nil || event.Target != el || !event.ChildListChanged {
+		t.Fatalf("事件不正确: %+v", event)
+	}
+	if _, ok := event.AttributesChanged["id"]; !ok {
+		t.Errorf("期望属性 id 发生变化")
+	}
+}
+
+func TestYXmlHook(t *testing.T) {
+	doc := struts.NewDoc(nil)
+	fragment := types.GetXmlFragment(doc, "xml")
+	hook := types.NewYXmlHook("image")
+	hook.Set("src", "a.png")
+	fragment.Push([]interface{}{hook})
+	got, ok := fragment.Get(0).(*types.YXmlHook)
+	if !ok || got.HookName != "image" || got.Get("src") != "a.png" {
+		t.Fatalf("钩子内容不正确")
+	}
+	if hook.GetDataMap()["src"].Parent != hook {
+		t.Errorf("钩子属性的父类型应为钩子本身")
+	}
+}
@@ -0,0 +1,211 @@
+package types
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/util"
+)
+
+func init() {
+	struts.RegisterTypeRef(struts.YArrayRefID, ReadYArray)
+}
+
+// YArrayEvent 描述 YArray 变化的事件
+type YArrayEvent struct {
+	*YEvent
+}
+
+// NewYArrayEvent 创建一个新的 YArrayEvent 实例
+func NewYArrayEvent(yarray *YArray, transaction *struts.Transaction) *YArrayEvent {
+	return &YArrayEvent{
+		YEvent: NewYEvent(yarray, transaction),
+	}
+}
+
+// YArray 共享数组类型
+type YArray struct {
+	*AbstractType
+	prelimContent []interface{} // prelimContent 整合到文档之前插入的内容
+}
+
+// YArray 需要满足类型层的接口
+var _ AbstractTypeInterface = (*YArray)(nil)
+
+// NewYArray 创建一个新的 YArray 实例
+func NewYArray() *YArray {
+	a := &YArray{
+		AbstractType:  NewAbstractType(),
+		prelimContent: make([]interface{}, 0),
+	}
+	a.SetSearchMarker(&[]*ArraySearchMarker{})
+	return a
+}
+
+// NewYArrayFrom 使用给定的内容创建 YArray
+func NewYArrayFrom(items []interface{}) *YArray {
+	a := NewYArray()
+	a.Push(items)
+	return a
+}
+
+// ReadYArray 从解码器读取 YArray
+func ReadYArray(decoder util.UpdateDecoderInterface) struts.AbstractTypeInterface {
+	return NewYArray()
+}
+
+// GetArray 获取文档中名为 name 的顶层 YArray
+func GetArray(doc *struts.Doc, name string) *YArray {
+	return doc.Get(name, func() struts.AbstractTypeInterface {
+		return NewYArray()
+	}).(*YArray)
+}
+
+// Integrate 将此类型整合到文档中，并插入整合前的内容
+func (a *YArray) Integrate(y *struts.Doc, item *struts.Item) {
+	a.AbstractType.Integrate(y, item)
+	content := a.prelimContent
+	a.prelimContent = nil
+	a.Insert(0, content)
+}
+
+// Copy 返回一个空的 YArray
+func (a *YArray) Copy() struts.AbstractTypeInterface {
+	return NewYArray()
+}
+
+// Clone 返回此数组的副本，包含的共享类型也会被复制
+func (a *YArray) Clone() struts.AbstractTypeInterface {
+	arr := NewYArray()
+	content := make([]interface{}, 0, a.Length())
+	for _, c := range a.ToArray() {
+		if t, ok := c.(AbstractTypeInterface); ok {
+			content = append(content, t.Clone())
+		} else {
+			content = append(content, c)
+		}
+	}
+	arr.Insert(0, content)
+	return arr
+}
+
+// Length 返回数组的长度
+func (a *YArray) Length() int {
+	if a.GetDoc() == nil {
+		return len(a.prelimContent)
+	}
+	return a.GetLength()
+}
+
+// CallObserver 创建 YArrayEvent 并调用所有类型观察者
+func (a *YArray) CallObserver(transaction *struts.Transaction, parentSubs map[string]struct{}) {
+	a.AbstractType.CallObserver(transaction, parentSubs)
+	CallTypeObservers(a, transaction, NewYArrayEvent(a, transaction))
+}
+
+// Insert 在指定索引处插入内容
+func (a *YArray) Insert(index int, content []interface{}) {
+	if doc := a.GetDoc(); doc != nil {
+		doc.Transact(func(transaction *struts.Transaction) {
+			typeListInsertGenerics(transaction, a, index, content)
+		}, nil, true)
+	} else {
+		if index > len(a.prelimContent) {
+			panic(util.ErrLengthExceeded)
+		}
+		prelim := append([]interface{}{}, a.prelimContent[:index]...)
+		prelim = append(prelim, content...)
+		a.prelimContent = append(prelim, a.prelimContent[index:]...)
+	}
+}
+
+// Push 在数组末尾追加内容
+func (a *YArray) Push(content []interface{}) {
+	if doc := a.GetDoc(); doc != nil {
+		doc.Transact(func(transaction *struts.Transaction) {
+			typeListPushGenerics(transaction, a, content)
+		}, nil, true)
+	} else {
+		a.prelimContent = append(a.prelimContent, content...)
+	}
+}
+
+// Unshift 在数组开头插入内容
+func (a *YArray) Unshift(content []interface{}) {
+	a.Insert(0, content)
+}
+
+// Delete 从指定索引开始删除 length 个元素
+func (a *YArray) Delete(index int, length int) {
+	if doc := a.GetDoc(); doc != nil {
+		doc.Transact(func(transaction *struts.Transaction) {
+			typeListDelete(transaction, a, index, length)
+		}, nil, true)
+	} else {
+		if index+length > len(a.prelimContent) {
+			panic(util.ErrLengthExceeded)
+		}
+		a.prelimContent = append(a.prelimContent[:index], a.prelimContent[index+length:]...)
+	}
+}
+
+// Get 返回指定索引处的元素
+func (a *YArray) Get(index int) interface{} {
+	if a.GetDoc() == nil {
+		if index < 0 || index >= len(a.prelimContent) {
+			return nil
+		}
+		return a.prelimContent[index]
+	}
+	return typeListGet(a, index)
+}
+
+// ToArray 将数组内容转换为切片
+func (a *YArray) ToArray() []interface{} {
+	if a.GetDoc() == nil {
+		return append([]interface{}{}, a.prelimContent...)
+	}
+	return TypeListToArray(a)
+}
+
+// Slice 返回 [start, end) 范围内的元素，负数索引从末尾开始计算
+func (a *YArray) Slice(start, end int) []interface{} {
+	if a.GetDoc() == nil {
+		length := len(a.prelimContent)
+		if start < 0 {
+			start += length
+		}
+		if end < 0 {
+			end += length
+		}
+		if start >= end {
+			return []interface{}{}
+		}
+		return append([]interface{}{}, a.prelimContent[start:end]...)
+	}
+	return TypeListSlice(a, start, end)
+}
+
+// ForEach 在每个元素上执行一次提供的函数
+func (a *YArray) ForEach(f func(c interface{}, index int)) {
+	for i, c := range a.ToArray() {
+		f(c, i)
+	}
+}
+
+// ToJSON 返回此数组的 JSON 表示，包含的共享类型也会被转换
+func (a *YArray) ToJSON() interface{} {
+	content := a.ToArray()
+	result := make([]interface{}, 0, len(content))
+	for _, c := range content {
+		if t, ok := c.(AbstractTypeInterface); ok {
+			result = append(result, t.ToJSON())
+		} else {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Write 将此类型写入编码器
+func (a *YArray) Write(encoder util.EncoderInterface) {
+	encoder.WriteTypeRef(struts.YArrayRefID)
+}
@@ -0,0 +1,223 @@
+package types
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/util"
+)
+
+// YEventInterface 类型事件接口，具体的事件类型通过嵌入 YEvent 实现
+type YEventInterface interface {
+	struts.YEventInterface
+	GetYEvent() *YEvent                // GetYEvent 获取基础事件
+	Keys() map[string]*YEventKeyChange // Keys 发生变化的键
+	Delta() []DeltaOp                  // Delta 列表内容的变化
+	Changes() *YEventChanges           // Changes 事件描述的所有变化
+}
+
+// YEventKeyChange 描述一个键的变化，Action 为 add、update 或 delete
+type YEventKeyChange struct {
+	Action   string      // Action 变化的类型
+	OldValue interface{} // OldValue 变化之前的值，新增的键没有旧值
+}
+
+// YEventChanges 描述类型在一次事务中的所有变化
+type YEventChanges struct {
+	Added   map[*struts.Item]struct{}   // Added 新增的项目
+	Deleted map[*struts.Item]struct{}   // Deleted 删除的项目
+	Delta   []DeltaOp                   // Delta 列表内容的变化
+	Keys    map[string]*YEventKeyChange // Keys 发生变化的键
+}
+
+// YEvent 描述共享类型在一次事务中发生的变化
+type YEvent struct {
+	Target        AbstractTypeInterface       // Target 发生变化的类型
+	CurrentTarget AbstractTypeInterface       // CurrentTarget 当前调用事件处理器的类型
+	Transaction   *struts.Transaction         // Transaction 触发此事件的事务
+	path          []interface{}               // path 缓存的路径，当前目标改变时重新计算
+	keys          map[string]*YEventKeyChange // keys 缓存的键变化
+	changes       *YEventChanges              // changes 缓存的变化
+}
+
+// NewYEvent 创建一个新的 YEvent 实例
+func NewYEvent(target AbstractTypeInterface, transaction *struts.Transaction) *YEvent {
+	return &YEvent{
+		Target:        target,
+		CurrentTarget: target,
+		Transaction:   transaction,
+	}
+}
+
+// GetYEvent 获取基础事件
+func (e *YEvent) GetYEvent() *YEvent {
+	return e
+}
+
+// GetTarget 获取发生变化的类型
+func (e *YEvent) GetTarget() struts.AbstractTypeInterface {
+	return e.Target
+}
+
+// SetCurrentTarget 设置当前调用事件处理器的类型
+func (e *YEvent) SetCurrentTarget(t struts.AbstractTypeInterface) {
+	if currentTarget, ok := t.(AbstractTypeInterface); ok {
+		e.CurrentTarget = currentTarget
+		e.path = nil
+	}
+}
+
+// Path 返回从当前目标到发生变化的类型的路径，映射中的位置为键，列表中的位置为索引
+func (e *YEvent) Path() []interface{} {
+	if e.path == nil {
+		e.path = getPathTo(e.CurrentTarget, e.Target)
+	}
+	return e.path
+}
+
+// Deletes 检查项目是否在此事件的事务中被删除
+func (e *YEvent) Deletes(item *struts.Item) bool {
+	return e.Transaction.DeleteSet.IsDeleted(item.ID)
+}
+
+// Adds 检查项目是否在此事件的事务中被添加
+func (e *YEvent) Adds(item *struts.Item) bool {
+	return item.ID.Clock >= e.Transaction.BeforeState[item.ID.Client]
+}
+
+// DeltaOp 描述一次变化的操作，与 Quill Delta 格式兼容
+// Insert 不为 nil 时表示插入，Retain 与 Delete 大于 0 时分别表示保留与删除
+type DeltaOp struct {
+	Insert     interface{}            `json:"insert,omitempty"`     // Insert 插入的内容
+	Retain     int                    `json:"retain,omitempty"`     // Retain 保留的长度
+	Delete     int                    `json:"delete,omitempty"`     // Delete 删除的长度
+	Attributes map[string]interface{} `json:"attributes,omitempty"` // Attributes 格式化属性
+}
+
+// checkComputeChanges 变化依赖事务的状态，只能在事务清理期间计算
+func (e *YEvent) checkComputeChanges() {
+	if len(e.Transaction.Doc.TransactionCleanups) == 0 {
+		panic(util.ErrComputeChanges)
+	}
+}
+
+// lastContent 返回项目内容中的最后一个值
+func lastContent(item *struts.Item) interface{} {
+	content := item.Content.GetContent()
+	return content[len(content)-1]
+}
+
+// Keys 计算映射中发生变化的键及其旧值
+func (e *YEvent) Keys() map[string]*YEventKeyChange {
+	if e.keys != nil {
+		return e.keys
+	}
+	e.checkComputeChanges()
+	keys := make(map[string]*YEventKeyChange)
+	for key := range e.Transaction.Changed[e.Target] {
+		if key == "" {
+			continue
+		}
+		item := e.Target.GetDataMap()[key]
+		var change *YEventKeyChange
+		if e.Adds(item) {
+			prev := item.Left
+			for prev != nil && e.Adds(prev) {
+				prev = prev.Left
+			}
+			if e.Deletes(item) {
+				if prev != nil && e.Deletes(prev) {
+					change = &YEventKeyChange{Action: "delete", OldValue: lastContent(prev)}
+				}
+			} else if prev != nil && e.Deletes(prev) {
+				change = &YEventKeyChange{Action: "update", OldValue: lastContent(prev)}
+			} else {
+				change = &YEventKeyChange{Action: "add"}
+			}
+		} else if e.Deletes(item) {
+			change = &YEventKeyChange{Action: "delete", OldValue: lastContent(item)}
+		}
+		if change != nil {
+			keys[key] = change
+		}
+	}
+	e.keys = keys
+	return keys
+}
+
+// Delta 返回列表内容的变化
+func (e *YEvent) Delta() []DeltaOp {
+	return e.Changes().Delta
+}
+
+// Changes 计算类型在事务中新增与删除的项目、列表内容的变化以及发生变化的键
+func (e *YEvent) Changes() *YEventChanges {
+	if e.changes != nil {
+		return e.changes
+	}
+	e.checkComputeChanges()
+	changes := &YEventChanges{
+		Added:   make(map[*struts.Item]struct{}),
+		Deleted: make(map[*struts.Item]struct{}),
+		Delta:   make([]DeltaOp, 0),
+		Keys:    e.Keys(),
+	}
+	if _, ok := e.Transaction.Changed[e.Target][""]; ok {
+		var lastOp *DeltaOp
+		packOp := func() {
+			if lastOp != nil {
+				changes.Delta = append(changes.Delta, *lastOp)
+			}
+		}
+		for item := e.Target.GetStart(); item != nil; item = item.Right {
+			if item.GetDeleted() {
+				if e.Deletes(item) && !e.Adds(item) {
+					if lastOp == nil || lastOp.Delete == 0 {
+						packOp()
+						lastOp = &DeltaOp{}
+					}
+					lastOp.Delete += item.Length
+					changes.Deleted[item] = struct{}{}
+				}
+			} else if e.Adds(item) {
+				if lastOp == nil || lastOp.Insert == nil {
+					packOp()
+					lastOp = &DeltaOp{Insert: make([]interface{}, 0)}
+				}
+				lastOp.Insert = append(lastOp.Insert.([]interface{}), item.Content.GetContent()...)
+				changes.Added[item] = struct{}{}
+			} else {
+				if lastOp == nil || lastOp.Retain == 0 {
+					packOp()
+					lastOp = &DeltaOp{}
+				}
+				lastOp.Retain += item.Length
+			}
+		}
+		// 末尾的保留操作没有意义
+		if lastOp != nil && lastOp.Retain == 0 {
+			packOp()
+		}
+	}
+	e.changes = changes
+	return changes
+}
+
+// getPathTo 计算从 parent 到 child 的路径
+func getPathTo(parent AbstractTypeInterface, child struts.AbstractTypeInterface) []interface{} {
+	path := make([]interface{}, 0)
+	for child.GetItem() != nil && child != struts.AbstractTypeInterface(parent) {
+		item := child.GetItem()
+		if item.ParentSub != "" {
+			path = append([]interface{}{item.ParentSub}, path...)
+		} else {
+			i := 0
+			for c := item.Parent.GetStart(); c != item && c != nil; c = c.Right {
+				if !c.GetDeleted() && c.Countable() {
+					i += c.Length
+				}
+			}
+			path = append([]interface{}{i}, path...)
+		}
+		child = item.Parent
+	}
+	return path
+}
@@ -0,0 +1,226 @@
+package types
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/util"
+	"sort"
+)
+
+func init() {
+	struts.RegisterTypeRef(struts.YMapRefID, ReadYMap)
+}
+
+// YMapEvent 描述 YMap 变化的事件
+type YMapEvent struct {
+	*YEvent
+	KeysChanged map[string]struct{} // KeysChanged 发生变化的键
+}
+
+// NewYMapEvent 创建一个新的 YMapEvent 实例
+func NewYMapEvent(ymap *YMap, transaction *struts.Transaction, subs map[string]struct{}) *YMapEvent {
+	return &YMapEvent{
+		YEvent:      NewYEvent(ymap.outer, transaction),
+		KeysChanged: subs,
+	}
+}
+
+// YMap 共享映射类型，每个键的值由最后整合的项目决定
+type YMap struct {
+	*AbstractType
+	prelimContent map[string]interface{} // prelimContent 整合到文档之前设置的内容
+	outer         AbstractTypeInterface  // outer 嵌入此映射的外层类型，作为新项目的父类型
+}
+
+// YMap 需要满足类型层的接口
+var _ AbstractTypeInterface = (*YMap)(nil)
+
+// NewYMap 创建一个新的 YMap 实例
+func NewYMap() *YMap {
+	m := &YMap{
+		AbstractType:  NewAbstractType(),
+		prelimContent: make(map[string]interface{}),
+	}
+	m.outer = m
+	return m
+}
+
+// NewYMapFrom 使用给定的键值对创建 YMap
+func NewYMapFrom(entries map[string]interface{}) *YMap {
+	m := NewYMap()
+	for key, value := range entries {
+		m.prelimContent[key] = value
+	}
+	return m
+}
+
+// ReadYMap 从解码器读取 YMap
+func ReadYMap(decoder util.UpdateDecoderInterface) struts.AbstractTypeInterface {
+	return NewYMap()
+}
+
+// GetMap 获取文档中名为 name 的顶层 YMap
+func GetMap(doc *struts.Doc, name string) *YMap {
+	return doc.Get(name, func() struts.AbstractTypeInterface {
+		return NewYMap()
+	}).(*YMap)
+}
+
+// sortedKeys 返回排序后的键，保证遍历顺序确定
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Integrate 将此类型整合到文档中，并写入整合前设置的内容
+func (m *YMap) Integrate(y *struts.Doc, item *struts.Item) {
+	m.AbstractType.Integrate(y, item)
+	prelim := m.prelimContent
+	m.prelimContent = nil
+	for _, key := range sortedKeys(prelim) {
+		m.Set(key, prelim[key])
+	}
+}
+
+// Copy 返回一个空的 YMap
+func (m *YMap) Copy() struts.AbstractTypeInterface {
+	return NewYMap()
+}
+
+// Clone 返回此映射的副本，包含的共享类型也会被复制
+func (m *YMap) Clone() struts.AbstractTypeInterface {
+	clone := NewYMap()
+	m.ForEach(func(key string, value interface{}) {
+		if t, ok := value.(AbstractTypeInterface); ok {
+			clone.Set(key, t.Clone())
+		} else {
+			clone.Set(key, value)
+		}
+	})
+	return clone
+}
+
+// CallObserver 创建 YMapEvent 并调用所有类型观察者
+func (m *YMap) CallObserver(transaction *struts.Transaction, parentSubs map[string]struct{}) {
+	CallTypeObservers(m.outer, transaction, NewYMapEvent(m, transaction, parentSubs))
+}
+
+// entries 返回当前所有的键值对
+func (m *YMap) entries() map[string]interface{} {
+	if m.GetDoc() == nil {
+		return m.prelimContent
+	}
+	return typeMapGetAll(m.outer)
+}
+
+// ToJSON 返回此映射的 JSON 表示，包含的共享类型也会被转换
+func (m *YMap) ToJSON() interface{} {
+	result := make(map[string]interface{})
+	for key, value := range m.entries() {
+		if t, ok := value.(AbstractTypeInterface); ok {
+			result[key] = t.ToJSON()
+		} else {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// Size 返回键的数量
+func (m *YMap) Size() int {
+	return len(m.entries())
+}
+
+// Keys 返回所有的键，按字典序排列
+func (m *YMap) Keys() []string {
+	return sortedKeys(m.entries())
+}
+
+// Values 返回所有的值，顺序与 Keys 一致
+func (m *YMap) Values() []interface{} {
+	entries := m.entries()
+	values := make([]interface{}, 0, len(entries))
+	for _, key := range sortedKeys(entries) {
+		values = append(values, entries[key])
+	}
+	return values
+}
+
+// Entries 返回所有的键值对
+func (m *YMap) Entries() map[string]interface{} {
+	entries := m.entries()
+	result := make(map[string]interface{}, len(entries))
+	for key, value := range entries {
+		result[key] = value
+	}
+	return result
+}
+
+// ForEach 按键的顺序在每个键值对上执行一次提供的函数
+func (m *YMap) ForEach(f func(key string, value interface{})) {
+	entries := m.entries()
+	for _, key := range sortedKeys(entries) {
+		f(key, entries[key])
+	}
+}
+
+// Delete 删除指定的键
+func (m *YMap) Delete(key string) {
+	if doc := m.GetDoc(); doc != nil {
+		doc.Transact(func(transaction *struts.Transaction) {
+			typeMapDelete(transaction, m.outer, key)
+		}, nil, true)
+	} else {
+		delete(m.prelimContent, key)
+	}
+}
+
+// Set 设置键的值并返回该值
+func (m *YMap) Set(key string, value interface{}) interface{} {
+	if doc := m.GetDoc(); doc != nil {
+		doc.Transact(func(transaction *struts.Transaction) {
+			typeMapSet(transaction, m.outer, key, value)
+		}, nil, true)
+	} else {
+		m.prelimContent[key] = value
+	}
+	return value
+}
+
+// Get 返回键的值，不存在时返回 nil
+func (m *YMap) Get(key string) interface{} {
+	if m.GetDoc() == nil {
+		return m.prelimContent[key]
+	}
+	return typeMapGet(m.outer, key)
+}
+
+// Has 检查键是否存在
+func (m *YMap) Has(key string) bool {
+	if m.GetDoc() == nil {
+		_, exists := m.prelimContent[key]
+		return exists
+	}
+	return typeMapHas(m.outer, key)
+}
+
+// Clear 删除所有的键
+func (m *YMap) Clear() {
+	if doc := m.GetDoc(); doc != nil {
+		doc.Transact(func(transaction *struts.Transaction) {
+			for _, key := range m.Keys() {
+				typeMapDelete(transaction, m.outer, key)
+			}
+		}, nil, true)
+	} else {
+		m.prelimContent = make(map[string]interface{})
+	}
+}
+
+// Write 将此类型写入编码器
+func (m *YMap) Write(encoder util.EncoderInterface) {
+	encoder.WriteTypeRef(struts.YMapRefID)
+}
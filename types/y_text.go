@@ -0,0 +1,964 @@
+package types
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/util"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+func init() {
+	struts.RegisterTypeRef(struts.YTextRefID, ReadYText)
+	struts.RegisterFormattingCleanup(cleanupYTextAfterTransaction)
+}
+
+// equalAttrs 比较两个格式化属性值是否相等
+func equalAttrs(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// sortedAttributeKeys 返回排序后的属性名，保证插入格式化项目的顺序确定
+func sortedAttributeKeys(attributes map[string]interface{}) []string {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// copyAttributes 复制格式化属性
+func copyAttributes(attributes map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(attributes))
+	for key, value := range attributes {
+		res[key] = value
+	}
+	return res
+}
+
+// ItemTextListPosition 文本中的位置，记录左右项目、索引以及当前生效的格式化属性
+type ItemTextListPosition struct {
+	Left              *struts.Item
+	Right             *struts.Item
+	Index             int
+	CurrentAttributes map[string]interface{}
+}
+
+// NewItemTextListPosition 创建一个新的文本位置
+func NewItemTextListPosition(left, right *struts.Item, index int, currentAttributes map[string]interface{}) *ItemTextListPosition {
+	return &ItemTextListPosition{
+		Left:              left,
+		Right:             right,
+		Index:             index,
+		CurrentAttributes: currentAttributes,
+	}
+}
+
+// Forward 向右移动一个项目
+func (pos *ItemTextListPosition) Forward() {
+	if pos.Right == nil {
+		panic(util.ErrUnexpectedCase)
+	}
+	switch content := pos.Right.Content.(type) {
+	case *struts.ContentFormat:
+		if !pos.Right.GetDeleted() {
+			updateCurrentAttributes(pos.CurrentAttributes, content)
+		}
+	default:
+		if !pos.Right.GetDeleted() {
+			pos.Index += pos.Right.Length
+		}
+	}
+	pos.Left = pos.Right
+	pos.Right = pos.Right.Right
+}
+
+// findNextPosition 从 pos 开始向右移动 count 个可见字符，必要时分割项目
+func findNextPosition(transaction *struts.Transaction, pos *ItemTextListPosition, count int) *ItemTextListPosition {
+	for pos.Right != nil && count > 0 {
+		switch content := pos.Right.Content.(type) {
+		case *struts.ContentFormat:
+			if !pos.Right.GetDeleted() {
+				updateCurrentAttributes(pos.CurrentAttributes, content)
+			}
+		default:
+			if !pos.Right.GetDeleted() {
+				if count < pos.Right.Length {
+					// 分割右侧项目
+					struts.GetItemCleanStart(transaction, util.NewID(pos.Right.ID.Client, pos.Right.ID.Clock+count))
+				}
+				pos.Index += pos.Right.Length
+				count -= pos.Right.Length
+			}
+		}
+		pos.Left = pos.Right
+		pos.Right = pos.Right.Right
+	}
+	return pos
+}
+
+// findPosition 查找索引对应的位置
+func findPosition(transaction *struts.Transaction, parent AbstractTypeInterface, index int, useSearchMarker bool) *ItemTextListPosition {
+	currentAttributes := make(map[string]interface{})
+	var marker *ArraySearchMarker
+	if useSearchMarker {
+		marker = FindMarker(parent, index)
+	}
+	if marker != nil {
+		pos := NewItemTextListPosition(marker.P.Left, marker.P, marker.Index, currentAttributes)
+		return findNextPosition(transaction, pos, index-marker.Index)
+	}
+	pos := NewItemTextListPosition(nil, parent.GetStart(), 0, currentAttributes)
+	return findNextPosition(transaction, pos, index)
+}
+
+// insertNegatedAttributes 在插入的内容之后恢复被覆盖的格式化属性
+func insertNegatedAttributes(transaction *struts.Transaction, parent AbstractTypeInterface, currPos *ItemTextListPosition, negatedAttributes map[string]interface{}) {
+	// 检查是否真的需要移除属性
+	for currPos.Right != nil {
+		if !currPos.Right.GetDeleted() {
+			format, ok := currPos.Right.Content.(*struts.ContentFormat)
+			if !ok {
+				break
+			}
+			negated, exists := negatedAttributes[format.Key]
+			if !exists || !equalAttrs(negated, format.Value) {
+				break
+			}
+			delete(negatedAttributes, format.Key)
+		}
+		currPos.Forward()
+	}
+	doc := transaction.Doc
+	ownClientId := doc.ClientID
+	for _, key := range sortedAttributeKeys(negatedAttributes) {
+		left := currPos.Left
+		right := currPos.Right
+		nextFormat := struts.NewItem(util.NewID(ownClientId, struts.GetState(doc.Store, ownClientId)), left, lastIDOf(left), right, idOf(right), parent, "", struts.NewContentFormat(key, negatedAttributes[key]))
+		nextFormat.Integrate(transaction, 0)
+		currPos.Right = nextFormat
+		currPos.Forward()
+	}
+}
+
+// lastIDOf 返回项目的最后一个 ID，项目为 nil 时返回 nil
+func lastIDOf(item *struts.Item) *util.ID {
+	if item == nil {
+		return nil
+	}
+	return item.LastId()
+}
+
+// idOf 返回项目的 ID，项目为 nil 时返回 nil
+func idOf(item *struts.Item) *util.ID {
+	if item == nil {
+		return nil
+	}
+	return item.ID
+}
+
+// updateCurrentAttributes 根据格式化内容更新当前属性，值为 nil 表示移除属性
+func updateCurrentAttributes(currentAttributes map[string]interface{}, format *struts.ContentFormat) {
+	if format.Value == nil {
+		delete(currentAttributes, format.Key)
+	} else {
+		currentAttributes[format.Key] = format.Value
+	}
+}
+
+// minimizeAttributeChanges 跳过已经与目标属性一致的格式化项目
+func minimizeAttributeChanges(currPos *ItemTextListPosition, attributes map[string]interface{}) {
+	for currPos.Right != nil {
+		if !currPos.Right.GetDeleted() {
+			format, ok := currPos.Right.Content.(*struts.ContentFormat)
+			if !ok || !equalAttrs(attributes[format.Key], format.Value) {
+				break
+			}
+		}
+		currPos.Forward()
+	}
+}
+
+// insertAttributes 插入与当前属性不同的格式化项目，返回需要在之后恢复的属性
+func insertAttributes(transaction *struts.Transaction, parent AbstractTypeInterface, currPos *ItemTextListPosition, attributes map[string]interface{}) map[string]interface{} {
+	doc := transaction.Doc
+	ownClientId := doc.ClientID
+	negatedAttributes := make(map[string]interface{})
+	for _, key := range sortedAttributeKeys(attributes) {
+		val := attributes[key]
+		currentVal := currPos.CurrentAttributes[key]
+		if !equalAttrs(currentVal, val) {
+			// 保存被覆盖的属性，之后需要恢复
+			negatedAttributes[key] = currentVal
+			left := currPos.Left
+			right := currPos.Right
+			currPos.Right = struts.NewItem(util.NewID(ownClientId, struts.GetState(doc.Store, ownClientId)), left, lastIDOf(left), right, idOf(right), parent, "", struts.NewContentFormat(key, val))
+			currPos.Right.Integrate(transaction, 0)
+			currPos.Forward()
+		}
+	}
+	return negatedAttributes
+}
+
+// insertText 在位置处插入文本、嵌入对象或共享类型，并应用格式化属性
+func insertText(transaction *struts.Transaction, parent AbstractTypeInterface, currPos *ItemTextListPosition, text interface{}, attributes map[string]interface{}) {
+	attributes = copyAttributes(attributes)
+	for key := range currPos.CurrentAttributes {
+		if _, exists := attributes[key]; !exists {
+			attributes[key] = nil
+		}
+	}
+	doc := transaction.Doc
+	ownClientId := doc.ClientID
+	minimizeAttributeChanges(currPos, attributes)
+	negatedAttributes := insertAttributes(transaction, parent, currPos, attributes)
+	// 插入内容
+	var content struts.AbstractContentInterface
+	switch v := text.(type) {
+	case string:
+		content = struts.NewContentString(v)
+	case AbstractTypeInterface:
+		content = struts.NewContentType(v)
+	default:
+		content = struts.NewContentEmbed(v)
+	}
+	left := currPos.Left
+	right := currPos.Right
+	index := currPos.Index
+	if parent.GetSearchMarker() != nil {
+		UpdateMarkerChanges(parent.GetSearchMarker(), currPos.Index, content.GetLength())
+	}
+	right = struts.NewItem(util.NewID(ownClientId, struts.GetState(doc.Store, ownClientId)), left, lastIDOf(left), right, idOf(right), parent, "", content)
+	right.Integrate(transaction, 0)
+	currPos.Right = right
+	currPos.Index = index
+	currPos.Forward()
+	insertNegatedAttributes(transaction, parent, currPos, negatedAttributes)
+}
+
+// formatText 为位置之后 length 个字符应用格式化属性
+func formatText(transaction *struts.Transaction, parent AbstractTypeInterface, currPos *ItemTextListPosition, length int, attributes map[string]interface{}) {
+	doc := transaction.Doc
+	ownClientId := doc.ClientID
+	minimizeAttributeChanges(currPos, attributes)
+	negatedAttributes := insertAttributes(transaction, parent, currPos, attributes)
+	// 删除范围内被覆盖的格式化项目，同时检查范围之后的格式化项目，避免插入多余的恢复属性
+iterationLoop:
+	for currPos.Right != nil && (length > 0 || (len(negatedAttributes) > 0 && (currPos.Right.GetDeleted() || isFormat(currPos.Right)))) {
+		if !currPos.Right.GetDeleted() {
+			switch content := currPos.Right.Content.(type) {
+			case *struts.ContentFormat:
+				if attr, exists := attributes[content.Key]; exists {
+					if equalAttrs(attr, content.Value) {
+						delete(negatedAttributes, content.Key)
+					} else {
+						if length == 0 {
+							// 无需继续扩展 negatedAttributes
+							break iterationLoop
+						}
+						negatedAttributes[content.Key] = content.Value
+					}
+					currPos.Right.Delete(transaction)
+				} else {
+					currPos.CurrentAttributes[content.Key] = content.Value
+				}
+			default:
+				if length < currPos.Right.Length {
+					struts.GetItemCleanStart(transaction, util.NewID(currPos.Right.ID.Client, currPos.Right.ID.Clock+length))
+				}
+				length -= currPos.Right.Length
+			}
+		}
+		currPos.Forward()
+	}
+	// Quill 假设文本总是以换行符结尾，只有当格式化的范围超出文本长度时才插入换行符
+	if length > 0 {
+		newlines := strings.Repeat("\n", length)
+		currPos.Right = struts.NewItem(util.NewID(ownClientId, struts.GetState(doc.Store, ownClientId)), currPos.Left, lastIDOf(currPos.Left), currPos.Right, idOf(currPos.Right), parent, "", struts.NewContentString(newlines))
+		currPos.Right.Integrate(transaction, 0)
+		currPos.Forward()
+	}
+	insertNegatedAttributes(transaction, parent, currPos, negatedAttributes)
+}
+
+// isFormat 检查项目是否为格式化项目
+func isFormat(item *struts.Item) bool {
+	_, ok := item.Content.(*struts.ContentFormat)
+	return ok
+}
+
+// cleanupFormattingGap 清理 start 与 curr 之后第一个内容项目之间多余的格式化项目，返回清理的数量
+func cleanupFormattingGap(transaction *struts.Transaction, start *struts.Item, curr *struts.Item, startAttributes map[string]interface{}, currAttributes map[string]interface{}) int {
+	end := start
+	endFormats := make(map[string]*struts.ContentFormat)
+	for end != nil && (!end.Countable() || end.GetDeleted()) {
+		if format, ok := end.Content.(*struts.ContentFormat); ok && !end.GetDeleted() {
+			endFormats[format.Key] = format
+		}
+		end = end.Right
+	}
+	cleanups := 0
+	reachedCurr := false
+	for start != end {
+		if curr == start {
+			reachedCurr = true
+		}
+		if !start.GetDeleted() {
+			if format, ok := start.Content.(*struts.ContentFormat); ok {
+				startAttrValue := startAttributes[format.Key]
+				if endFormats[format.Key] != format || equalAttrs(startAttrValue, format.Value) {
+					// 格式化项目被覆盖，或属性已经存在，不再需要
+					start.Delete(transaction)
+					cleanups++
+					if !reachedCurr && equalAttrs(currAttributes[format.Key], format.Value) && !equalAttrs(startAttrValue, format.Value) {
+						if startAttrValue == nil {
+							delete(currAttributes, format.Key)
+						} else {
+							currAttributes[format.Key] = startAttrValue
+						}
+					}
+				}
+				if !reachedCurr && !start.GetDeleted() {
+					updateCurrentAttributes(currAttributes, format)
+				}
+			}
+		}
+		start = start.Right
+	}
+	return cleanups
+}
+
+// cleanupContextlessFormattingGap 无需计算当前属性的清理，删除同一个间隙中重复的格式化项目
+func cleanupContextlessFormattingGap(transaction *struts.Transaction, item *struts.Item) {
+	// 向右移动到下一个内容项目之前
+	for item != nil && item.Right != nil && (item.Right.GetDeleted() || !item.Right.Countable()) {
+		item = item.Right
+	}
+	attrs := make(map[string]struct{})
+	// 向左遍历直到遇到内容项目
+	for item != nil && (item.GetDeleted() || !item.Countable()) {
+		if format, ok := item.Content.(*struts.ContentFormat); ok && !item.GetDeleted() {
+			if _, exists := attrs[format.Key]; exists {
+				item.Delete(transaction)
+			} else {
+				attrs[format.Key] = struct{}{}
+			}
+		}
+		item = item.Left
+	}
+}
+
+// cleanupYTextFormatting 清理整个文本中多余的格式化项目，返回清理的数量
+func cleanupYTextFormatting(t *YText) int {
+	res := 0
+	t.GetDoc().Transact(func(transaction *struts.Transaction) {
+		start := t.GetStart()
+		end := t.GetStart()
+		startAttributes := make(map[string]interface{})
+		currentAttributes := copyAttributes(startAttributes)
+		for end != nil {
+			if !end.GetDeleted() {
+				if format, ok := end.Content.(*struts.ContentFormat); ok {
+					updateCurrentAttributes(currentAttributes, format)
+				} else {
+					res += cleanupFormattingGap(transaction, start, end, startAttributes, currentAttributes)
+					startAttributes = copyAttributes(currentAttributes)
+					start = end
+				}
+			}
+			end = end.Right
+		}
+	}, nil, true)
+	return res
+}
+
+// cleanupYTextAfterTransaction 远程事务修改了格式化属性后，清理并发格式化产生的重复项目
+func cleanupYTextAfterTransaction(transaction *struts.Transaction) {
+	needFullCleanup := make(map[*YText]struct{})
+	var fullCleanupOrder []*YText
+	addFullCleanup := func(t *YText) {
+		if _, exists := needFullCleanup[t]; !exists {
+			needFullCleanup[t] = struct{}{}
+			fullCleanupOrder = append(fullCleanupOrder, t)
+		}
+	}
+	doc := transaction.Doc
+	// 新插入格式化项目的文本需要完整清理
+	for client, afterClock := range transaction.AfterState {
+		clock := transaction.BeforeState[client]
+		if afterClock == clock {
+			continue
+		}
+		struts.IterateStructs(transaction, doc.Store.Clients[client], clock, afterClock, func(s struts.AbstractStructInterface) {
+			if item, ok := s.(*struts.Item); ok && !item.GetDeleted() && isFormat(item) {
+				if owner, ok := item.Parent.(yTextOwner); ok {
+					addFullCleanup(owner.text())
+				}
+			}
+		})
+	}
+	// 在新的事务中清理
+	doc.Transact(func(t *struts.Transaction) {
+		struts.IterateDeletedStructs(transaction, transaction.DeleteSet, func(s struts.AbstractStructInterface) {
+			item, ok := s.(*struts.Item)
+			if !ok {
+				return
+			}
+			owner, ok := item.Parent.(yTextOwner)
+			if !ok || !owner.text().hasFormatting {
+				return
+			}
+			parent := owner.text()
+			if _, exists := needFullCleanup[parent]; exists {
+				return
+			}
+			if isFormat(item) {
+				addFullCleanup(parent)
+			} else {
+				// 没有插入或删除格式化属性时，只需要清理上下文无关的间隙
+				cleanupContextlessFormattingGap(t, item)
+			}
+		})
+		// 插入了格式化项目时直接清理整个文本
+		for _, yText := range fullCleanupOrder {
+			cleanupYTextFormatting(yText)
+		}
+	}, nil, true)
+}
+
+// deleteText 从位置处删除 length 个字符，并清理删除范围内多余的格式化项目
+func deleteText(transaction *struts.Transaction, currPos *ItemTextListPosition, length int) *ItemTextListPosition {
+	startLength := length
+	startAttrs := copyAttributes(currPos.CurrentAttributes)
+	start := currPos.Right
+	for length > 0 && currPos.Right != nil {
+		if !currPos.Right.GetDeleted() {
+			switch currPos.Right.Content.(type) {
+			case *struts.ContentType, *struts.ContentEmbed, *struts.ContentString:
+				if length < currPos.Right.Length {
+					struts.GetItemCleanStart(transaction, util.NewID(currPos.Right.ID.Client, currPos.Right.ID.Clock+length))
+				}
+				length -= currPos.Right.Length
+				currPos.Right.Delete(transaction)
+			}
+		}
+		currPos.Forward()
+	}
+	if start != nil {
+		cleanupFormattingGap(transaction, start, currPos.Right, startAttrs, currPos.CurrentAttributes)
+	}
+	item := currPos.Left
+	if item == nil {
+		item = currPos.Right
+	}
+	if item != nil {
+		if parent, ok := item.Parent.(AbstractTypeInterface); ok && parent.GetSearchMarker() != nil {
+			UpdateMarkerChanges(parent.GetSearchMarker(), currPos.Index, -startLength+length)
+		}
+	}
+	return currPos
+}
+
+// yTextOwner 以 YText 为基础的类型，YXmlText 通过嵌入 YText 满足此接口
+type yTextOwner interface {
+	text() *YText
+}
+
+// text 返回基础的 YText
+func (t *YText) text() *YText {
+	return t
+}
+
+// YTextEvent 描述 YText 变化的事件
+type YTextEvent struct {
+	*YEvent
+	KeysChanged      map[string]struct{} // KeysChanged 发生变化的文本属性
+	ChildListChanged bool                // ChildListChanged 文本内容是否发生变化
+	delta            []DeltaOp           // delta 缓存的富文本变化
+}
+
+// NewYTextEvent 创建一个新的 YTextEvent 实例
+func NewYTextEvent(ytext *YText, transaction *struts.Transaction, subs map[string]struct{}) *YTextEvent {
+	event := &YTextEvent{
+		YEvent:      NewYEvent(ytext.outer, transaction),
+		KeysChanged: make(map[string]struct{}),
+	}
+	for sub := range subs {
+		if sub == "" {
+			event.ChildListChanged = true
+		} else {
+			event.KeysChanged[sub] = struct{}{}
+		}
+	}
+	return event
+}
+
+// Changes 文本的变化只通过 Delta 描述，不记录新增与删除的项目
+func (e *YTextEvent) Changes() *YEventChanges {
+	if e.YEvent.changes == nil {
+		e.YEvent.changes = &YEventChanges{
+			Added:   make(map[*struts.Item]struct{}),
+			Deleted: make(map[*struts.Item]struct{}),
+			Delta:   e.Delta(),
+			Keys:    e.Keys(),
+		}
+	}
+	return e.YEvent.changes
+}
+
+// Delta 返回 Quill Delta 格式的文本变化，包括格式化属性的变化
+// 计算过程中会删除多余的格式化项目
+func (e *YTextEvent) Delta() []DeltaOp {
+	if e.delta != nil {
+		return e.delta
+	}
+	e.checkComputeChanges()
+	delta := make([]DeltaOp, 0)
+	e.Target.GetDoc().Transact(func(transaction *struts.Transaction) {
+		currentAttributes := make(map[string]interface{}) // 插入内容当前的属性
+		oldAttributes := make(map[string]interface{})     // 变化之前的属性
+		attributes := make(map[string]interface{})        // 保留内容新增或移除的属性
+		action := ""
+		var insert interface{}
+		var sb strings.Builder
+		retain := 0
+		deleteLen := 0
+		addOp := func() {
+			if action == "" {
+				return
+			}
+			var op *DeltaOp
+			switch action {
+			case "delete":
+				if deleteLen > 0 {
+					op = &DeltaOp{Delete: deleteLen}
+				}
+				deleteLen = 0
+			case "insert":
+				if insert == nil && sb.Len() > 0 {
+					insert = sb.String()
+				}
+				if insert != nil {
+					op = &DeltaOp{Insert: insert}
+					if len(currentAttributes) > 0 {
+						op.Attributes = copyAttributes(currentAttributes)
+					}
+				}
+				insert = nil
+				sb.Reset()
+			case "retain":
+				if retain > 0 {
+					op = &DeltaOp{Retain: retain}
+					if len(attributes) > 0 {
+						op.Attributes = copyAttributes(attributes)
+					}
+				}
+				retain = 0
+			}
+			if op != nil {
+				delta = append(delta, *op)
+			}
+			action = ""
+		}
+		for item := e.Target.GetStart(); item != nil; item = item.Right {
+			switch content := item.Content.(type) {
+			case *struts.ContentType, *struts.ContentEmbed:
+				if e.Adds(item) {
+					if !e.Deletes(item) {
+						addOp()
+						action = "insert"
+						insert = content.GetContent()[0]
+						addOp()
+					}
+				} else if e.Deletes(item) {
+					if action != "delete" {
+						addOp()
+						action = "delete"
+					}
+					deleteLen++
+				} else if !item.GetDeleted() {
+					if action != "retain" {
+						addOp()
+						action = "retain"
+					}
+					retain++
+				}
+			case *struts.ContentString:
+				if e.Adds(item) {
+					if !e.Deletes(item) {
+						if action != "insert" {
+							addOp()
+							action = "insert"
+						}
+						sb.WriteString(content.Str)
+					}
+				} else if e.Deletes(item) {
+					if action != "delete" {
+						addOp()
+						action = "delete"
+					}
+					deleteLen += item.Length
+				} else if !item.GetDeleted() {
+					if action != "retain" {
+						addOp()
+						action = "retain"
+					}
+					retain += item.Length
+				}
+			case *struts.ContentFormat:
+				key, value := content.Key, content.Value
+				if e.Adds(item) {
+					if !e.Deletes(item) {
+						if !equalAttrs(currentAttributes[key], value) {
+							if action == "retain" {
+								addOp()
+							}
+							if equalAttrs(value, oldAttributes[key]) {
+								delete(attributes, key)
+							} else {
+								attributes[key] = value
+							}
+						} else if value != nil {
+							item.Delete(transaction)
+						}
+					}
+				} else if e.Deletes(item) {
+					oldAttributes[key] = value
+					if curVal := currentAttributes[key]; !equalAttrs(curVal, value) {
+						if action == "retain" {
+							addOp()
+						}
+						attributes[key] = curVal
+					}
+				} else if !item.GetDeleted() {
+					oldAttributes[key] = value
+					if attr, ok := attributes[key]; ok {
+						if !equalAttrs(attr, value) {
+							if action == "retain" {
+								addOp()
+							}
+							if value == nil {
+								delete(attributes, key)
+							} else {
+								attributes[key] = value
+							}
+						} else if attr != nil {
+							// 多余的格式化项目会在之后的清理中合并
+							item.Delete(transaction)
+						}
+					}
+				}
+				if !item.GetDeleted() {
+					if action == "insert" {
+						addOp()
+					}
+					updateCurrentAttributes(currentAttributes, content)
+				}
+			}
+		}
+		addOp()
+		// 末尾不带属性的保留操作没有意义
+		for len(delta) > 0 {
+			last := delta[len(delta)-1]
+			if last.Retain == 0 || last.Attributes != nil {
+				break
+			}
+			delta = delta[:len(delta)-1]
+		}
+	}, nil, true)
+	e.delta = delta
+	return delta
+}
+
+// YText 共享文本类型，支持富文本格式化
+type YText struct {
+	*AbstractType
+	pending       []func()              // pending 整合到文档之前的操作
+	hasFormatting bool                  // hasFormatting 文本中是否包含格式化属性
+	outer         AbstractTypeInterface // outer 嵌入此文本的外层类型，作为新项目的父类型
+}
+
+// YText 需要满足类型层的接口
+var _ AbstractTypeInterface = (*YText)(nil)
+
+// NewYText 创建一个新的 YText 实例
+func NewYText(str string) *YText {
+	t := &YText{
+		AbstractType: NewAbstractType(),
+		pending:      make([]func(), 0),
+	}
+	t.outer = t
+	t.SetSearchMarker(&[]*ArraySearchMarker{})
+	if str != "" {
+		t.pending = append(t.pending, func() {
+			t.Insert(0, str, nil)
+		})
+	}
+	return t
+}
+
+// ReadYText 从解码器读取 YText
+func ReadYText(decoder util.UpdateDecoderInterface) struts.AbstractTypeInterface {
+	return NewYText("")
+}
+
+// GetText 获取文档中名为 name 的顶层 YText
+func GetText(doc *struts.Doc, name string) *YText {
+	return doc.Get(name, func() struts.AbstractTypeInterface {
+		return NewYText("")
+	}).(*YText)
+}
+
+// SetHasFormatting 标记文本中包含格式化属性
+func (t *YText) SetHasFormatting(hasFormatting bool) {
+	t.hasFormatting = hasFormatting
+}
+
+// Length 返回文本的长度，以 UTF-16 码元计算
+func (t *YText) Length() int {
+	return t.GetLength()
+}
+
+// Integrate 将此类型整合到文档中，并执行整合前的操作
+func (t *YText) Integrate(y *struts.Doc, item *struts.Item) {
+	t.AbstractType.Integrate(y, item)
+	pending := t.pending
+	t.pending = nil
+	for _, f := range pending {
+		f()
+	}
+}
+
+// Copy 返回一个空的 YText
+func (t *YText) Copy() struts.AbstractTypeInterface {
+	return NewYText("")
+}
+
+// Clone 返回此文本的副本
+func (t *YText) Clone() struts.AbstractTypeInterface {
+	text := NewYText("")
+	text.ApplyDelta(t.ToDelta())
+	return text
+}
+
+// CallObserver 创建 YTextEvent 并调用所有类型观察者
+func (t *YText) CallObserver(transaction *struts.Transaction, parentSubs map[string]struct{}) {
+	t.AbstractType.CallObserver(transaction, parentSubs)
+	CallTypeObservers(t.outer, transaction, NewYTextEvent(t, transaction, parentSubs))
+	// 远程修改后可能产生重复的格式化属性，需要在事务结束时清理
+	if !transaction.Local && t.hasFormatting {
+		transaction.NeedFormattingCleanup = true
+	}
+}
+
+// ToString 返回不含格式化信息的文本
+func (t *YText) ToString() string {
+	var sb strings.Builder
+	for n := t.GetStart(); n != nil; n = n.Right {
+		if !n.GetDeleted() && n.Countable() {
+			if content, ok := n.Content.(*struts.ContentString); ok {
+				sb.WriteString(content.Str)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// String 实现 fmt.Stringer
+func (t *YText) String() string {
+	return t.ToString()
+}
+
+// ToJSON 返回此文本的 JSON 表示
+func (t *YText) ToJSON() interface{} {
+	return t.ToString()
+}
+
+// ApplyDelta 应用 Quill Delta 格式的变化
+func (t *YText) ApplyDelta(delta []DeltaOp) {
+	doc := t.GetDoc()
+	if doc == nil {
+		t.pending = append(t.pending, func() {
+			t.ApplyDelta(delta)
+		})
+		return
+	}
+	doc.Transact(func(transaction *struts.Transaction) {
+		currPos := NewItemTextListPosition(nil, t.GetStart(), 0, make(map[string]interface{}))
+		for _, op := range delta {
+			attributes := op.Attributes
+			if attributes == nil {
+				attributes = make(map[string]interface{})
+			}
+			if op.Insert != nil {
+				if str, ok := op.Insert.(string); !ok || len(str) > 0 {
+					insertText(transaction, t.outer, currPos, op.Insert, attributes)
+				}
+			} else if op.Retain > 0 {
+				formatText(transaction, t.outer, currPos, op.Retain, attributes)
+			} else if op.Delete > 0 {
+				deleteText(transaction, currPos, op.Delete)
+			}
+		}
+	}, nil, true)
+}
+
+// ToDelta 返回 Quill Delta 格式的文本内容
+func (t *YText) ToDelta() []DeltaOp {
+	ops := make([]DeltaOp, 0)
+	currentAttributes := make(map[string]interface{})
+	var sb strings.Builder
+	packStr := func() {
+		if sb.Len() > 0 {
+			op := DeltaOp{Insert: sb.String()}
+			if len(currentAttributes) > 0 {
+				op.Attributes = copyAttributes(currentAttributes)
+			}
+			ops = append(ops, op)
+			sb.Reset()
+		}
+	}
+	for n := t.GetStart(); n != nil; n = n.Right {
+		if n.GetDeleted() {
+			continue
+		}
+		switch content := n.Content.(type) {
+		case *struts.ContentString:
+			sb.WriteString(content.Str)
+		case *struts.ContentType, *struts.ContentEmbed:
+			packStr()
+			op := DeltaOp{Insert: content.GetContent()[0]}
+			if len(currentAttributes) > 0 {
+				op.Attributes = copyAttributes(currentAttributes)
+			}
+			ops = append(ops, op)
+		case *struts.ContentFormat:
+			packStr()
+			updateCurrentAttributes(currentAttributes, content)
+		}
+	}
+	packStr()
+	return ops
+}
+
+// Insert 在指定索引处插入文本
+// attributes 为 nil 时沿用插入位置的格式化属性
+func (t *YText) Insert(index int, text string, attributes map[string]interface{}) {
+	if len(text) == 0 {
+		return
+	}
+	doc := t.GetDoc()
+	if doc == nil {
+		t.pending = append(t.pending, func() {
+			t.Insert(index, text, attributes)
+		})
+		return
+	}
+	doc.Transact(func(transaction *struts.Transaction) {
+		pos := findPosition(transaction, t.outer, index, attributes == nil)
+		if attributes == nil {
+			attributes = copyAttributes(pos.CurrentAttributes)
+		}
+		insertText(transaction, t.outer, pos, text, attributes)
+	}, nil, true)
+}
+
+// InsertEmbed 在指定索引处插入嵌入对象或共享类型
+func (t *YText) InsertEmbed(index int, embed interface{}, attributes map[string]interface{}) {
+	doc := t.GetDoc()
+	if doc == nil {
+		t.pending = append(t.pending, func() {
+			t.InsertEmbed(index, embed, attributes)
+		})
+		return
+	}
+	doc.Transact(func(transaction *struts.Transaction) {
+		pos := findPosition(transaction, t.outer, index, attributes == nil)
+		if attributes == nil {
+			attributes = make(map[string]interface{})
+		}
+		insertText(transaction, t.outer, pos, embed, attributes)
+	}, nil, true)
+}
+
+// Delete 从指定索引开始删除 length 个字符
+func (t *YText) Delete(index int, length int) {
+	if length == 0 {
+		return
+	}
+	doc := t.GetDoc()
+	if doc == nil {
+		t.pending = append(t.pending, func() {
+			t.Delete(index, length)
+		})
+		return
+	}
+	doc.Transact(func(transaction *struts.Transaction) {
+		deleteText(transaction, findPosition(transaction, t.outer, index, true), length)
+	}, nil, true)
+}
+
+// Format 为指定范围的文本设置格式化属性，属性值为 nil 表示移除该属性
+func (t *YText) Format(index int, length int, attributes map[string]interface{}) {
+	if length == 0 {
+		return
+	}
+	doc := t.GetDoc()
+	if doc == nil {
+		t.pending = append(t.pending, func() {
+			t.Format(index, length, attributes)
+		})
+		return
+	}
+	doc.Transact(func(transaction *struts.Transaction) {
+		pos := findPosition(transaction, t.outer, index, false)
+		if pos.Right == nil {
+			return
+		}
+		formatText(transaction, t.outer, pos, length, attributes)
+	}, nil, true)
+}
+
+// RemoveAttribute 移除文本本身的属性
+func (t *YText) RemoveAttribute(attributeName string) {
+	doc := t.GetDoc()
+	if doc == nil {
+		t.pending = append(t.pending, func() {
+			t.RemoveAttribute(attributeName)
+		})
+		return
+	}
+	doc.Transact(func(transaction *struts.Transaction) {
+		typeMapDelete(transaction, t.outer, attributeName)
+	}, nil, true)
+}
+
+// SetAttribute 设置文本本身的属性
+func (t *YText) SetAttribute(attributeName string, attributeValue interface{}) {
+	doc := t.GetDoc()
+	if doc == nil {
+		t.pending = append(t.pending, func() {
+			t.SetAttribute(attributeName, attributeValue)
+		})
+		return
+	}
+	doc.Transact(func(transaction *struts.Transaction) {
+		typeMapSet(transaction, t.outer, attributeName, attributeValue)
+	}, nil, true)
+}
+
+// GetAttribute 获取文本本身的属性
+func (t *YText) GetAttribute(attributeName string) interface{} {
+	return typeMapGet(t.outer, attributeName)
+}
+
+// GetAttributes 获取文本本身的所有属性
+func (t *YText) GetAttributes() map[string]interface{} {
+	return typeMapGetAll(t.outer)
+}
+
+// Write 将此类型写入编码器
+func (t *YText) Write(encoder util.EncoderInterface) {
+	encoder.WriteTypeRef(struts.YTextRefID)
+}
@@ -0,0 +1,166 @@
+package types
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/util"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	struts.RegisterTypeRef(struts.YXmlElementRefID, ReadYXmlElement)
+}
+
+// YXmlElement XML 元素，属性保存在 DataMap 中，子节点保存在列表中
+type YXmlElement struct {
+	*YXmlFragment
+	NodeName    string                 // NodeName 元素的节点名
+	prelimAttrs map[string]interface{} // prelimAttrs 整合到文档之前设置的属性
+}
+
+// YXmlElement 需要满足类型层的接口
+var _ AbstractTypeInterface = (*YXmlElement)(nil)
+
+// NewYXmlElement 创建一个新的 YXmlElement 实例
+func NewYXmlElement(nodeName string) *YXmlElement {
+	el := &YXmlElement{
+		YXmlFragment: NewYXmlFragment(),
+		NodeName:     nodeName,
+		prelimAttrs:  make(map[string]interface{}),
+	}
+	el.outer = el
+	return el
+}
+
+// ReadYXmlElement 从解码器读取 YXmlElement
+func ReadYXmlElement(decoder util.UpdateDecoderInterface) struts.AbstractTypeInterface {
+	return NewYXmlElement(decoder.ReadKey())
+}
+
+// NextSibling 返回下一个兄弟节点，不存在时返回 nil
+func (el *YXmlElement) NextSibling() AbstractTypeInterface {
+	return siblingType(el.GetItem(), (*struts.Item).Next)
+}
+
+// PrevSibling 返回上一个兄弟节点，不存在时返回 nil
+func (el *YXmlElement) PrevSibling() AbstractTypeInterface {
+	return siblingType(el.GetItem(), (*struts.Item).Prev)
+}
+
+// siblingType 返回项目的兄弟项目中的共享类型
+func siblingType(item *struts.Item, sibling func(*struts.Item) *struts.Item) AbstractTypeInterface {
+	if item == nil {
+		return nil
+	}
+	n := sibling(item)
+	if n == nil {
+		return nil
+	}
+	return itemType(n)
+}
+
+// Integrate 将此类型整合到文档中，并写入整合前设置的属性
+func (el *YXmlElement) Integrate(y *struts.Doc, item *struts.Item) {
+	el.YXmlFragment.Integrate(y, item)
+	attrs := el.prelimAttrs
+	el.prelimAttrs = nil
+	for _, key := range sortedKeys(attrs) {
+		el.SetAttribute(key, attrs[key])
+	}
+}
+
+// Copy 返回一个具有相同节点名的空元素
+func (el *YXmlElement) Copy() struts.AbstractTypeInterface {
+	return NewYXmlElement(el.NodeName)
+}
+
+// Clone 返回此元素的副本，属性与子节点也会被复制
+func (el *YXmlElement) Clone() struts.AbstractTypeInterface {
+	clone := NewYXmlElement(el.NodeName)
+	for key, value := range el.GetAttributes() {
+		clone.SetAttribute(key, value)
+	}
+	clone.Insert(0, cloneChildren(el.ToArray()))
+	return clone
+}
+
+// ToString 返回此元素的 XML 字符串，属性按名称排序
+func (el *YXmlElement) ToString() string {
+	attrs := el.GetAttributes()
+	var sb strings.Builder
+	sb.WriteString("<" + el.NodeName)
+	for _, key := range sortedKeys(attrs) {
+		sb.WriteString(fmt.Sprintf(" %s=\"%v\"", key, attrs[key]))
+	}
+	sb.WriteString(">")
+	sb.WriteString(el.YXmlFragment.ToString())
+	sb.WriteString("</" + el.NodeName + ">")
+	return sb.String()
+}
+
+// String 实现 fmt.Stringer
+func (el *YXmlElement) String() string {
+	return el.ToString()
+}
+
+// ToJSON 返回此元素的 XML 字符串
+func (el *YXmlElement) ToJSON() interface{} {
+	return el.ToString()
+}
+
+// RemoveAttribute 移除属性
+func (el *YXmlElement) RemoveAttribute(attributeName string) {
+	if doc := el.GetDoc(); doc != nil {
+		doc.Transact(func(transaction *struts.Transaction) {
+			typeMapDelete(transaction, el, attributeName)
+		}, nil, true)
+	} else {
+		delete(el.prelimAttrs, attributeName)
+	}
+}
+
+// SetAttribute 设置属性
+func (el *YXmlElement) SetAttribute(attributeName string, attributeValue interface{}) {
+	if doc := el.GetDoc(); doc != nil {
+		doc.Transact(func(transaction *struts.Transaction) {
+			typeMapSet(transaction, el, attributeName, attributeValue)
+		}, nil, true)
+	} else {
+		el.prelimAttrs[attributeName] = attributeValue
+	}
+}
+
+// GetAttribute 获取属性，不存在时返回 nil
+func (el *YXmlElement) GetAttribute(attributeName string) interface{} {
+	if el.GetDoc() == nil {
+		return el.prelimAttrs[attributeName]
+	}
+	return typeMapGet(el, attributeName)
+}
+
+// HasAttribute 检查属性是否存在
+func (el *YXmlElement) HasAttribute(attributeName string) bool {
+	if el.GetDoc() == nil {
+		_, exists := el.prelimAttrs[attributeName]
+		return exists
+	}
+	return typeMapHas(el, attributeName)
+}
+
+// GetAttributes 获取所有属性
+func (el *YXmlElement) GetAttributes() map[string]interface{} {
+	if el.GetDoc() == nil {
+		attrs := make(map[string]interface{}, len(el.prelimAttrs))
+		for key, value := range el.prelimAttrs {
+			attrs[key] = value
+		}
+		return attrs
+	}
+	return typeMapGetAll(el)
+}
+
+// Write 将此类型写入编码器
+func (el *YXmlElement) Write(encoder util.EncoderInterface) {
+	encoder.WriteTypeRef(struts.YXmlElementRefID)
+	encoder.WriteKey(el.NodeName)
+}
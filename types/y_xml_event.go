@@ -0,0 +1,28 @@
+package types
+
+import (
+	"CollabEdit/struts"
+)
+
+// YXmlEvent 描述 XML 类型变化的事件
+type YXmlEvent struct {
+	*YEvent
+	ChildListChanged  bool                // ChildListChanged 子节点是否发生变化
+	AttributesChanged map[string]struct{} // AttributesChanged 发生变化的属性
+}
+
+// NewYXmlEvent 创建一个新的 YXmlEvent 实例
+func NewYXmlEvent(target AbstractTypeInterface, subs map[string]struct{}, transaction *struts.Transaction) *YXmlEvent {
+	event := &YXmlEvent{
+		YEvent:            NewYEvent(target, transaction),
+		AttributesChanged: make(map[string]struct{}),
+	}
+	for sub := range subs {
+		if sub == "" {
+			event.ChildListChanged = true
+		} else {
+			event.AttributesChanged[sub] = struct{}{}
+		}
+	}
+	return event
+}
@@ -0,0 +1,343 @@
+package types
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/util"
+	"strings"
+)
+
+func init() {
+	struts.RegisterTypeRef(struts.YXmlFragmentRefID, ReadYXmlFragment)
+}
+
+// xmlStringer 可以序列化为 XML 字符串的类型
+type xmlStringer interface {
+	ToString() string
+}
+
+// YXmlTreeWalker 按深度优先顺序遍历 XML 树中满足过滤条件的节点
+type YXmlTreeWalker struct {
+	filter      func(t AbstractTypeInterface) bool // filter 过滤函数
+	root        AbstractTypeInterface              // root 遍历的根节点
+	currentNode *struts.Item                       // currentNode 当前节点所在的项目
+	firstCall   bool                               // firstCall 是否为第一次调用 Next
+}
+
+// NewYXmlTreeWalker 创建一个从 root 开始遍历的树遍历器
+func NewYXmlTreeWalker(root AbstractTypeInterface, filter func(t AbstractTypeInterface) bool) *YXmlTreeWalker {
+	if filter == nil {
+		filter = func(t AbstractTypeInterface) bool { return true }
+	}
+	return &YXmlTreeWalker{
+		filter:      filter,
+		root:        root,
+		currentNode: root.GetStart(),
+		firstCall:   true,
+	}
+}
+
+// itemType 返回项目中的共享类型，项目不包含共享类型时返回 nil
+func itemType(item *struts.Item) AbstractTypeInterface {
+	if content, ok := item.Content.(*struts.ContentType); ok {
+		if t, ok := content.Type.(AbstractTypeInterface); ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// accept 检查项目是否为满足过滤条件的节点
+func (w *YXmlTreeWalker) accept(item *struts.Item) bool {
+	t := itemType(item)
+	return t != nil && w.filter(t)
+}
+
+// Next 返回下一个满足过滤条件的节点，遍历结束时第二个返回值为 false
+func (w *YXmlTreeWalker) Next() (AbstractTypeInterface, bool) {
+	n := w.currentNode
+	if n != nil && (!w.firstCall || n.GetDeleted() || !w.accept(n)) {
+		for {
+			t := itemType(n)
+			if !n.GetDeleted() && isXmlContainer(t) && t.GetStart() != nil {
+				// 向下遍历子节点
+				n = t.GetStart()
+			} else {
+				// 向右或向上遍历
+				for n != nil {
+					if n.Right != nil {
+						n = n.Right
+						break
+					} else if n.Parent == w.root {
+						n = nil
+					} else {
+						n = n.Parent.GetItem()
+					}
+				}
+			}
+			if n == nil || (!n.GetDeleted() && w.accept(n)) {
+				break
+			}
+		}
+	}
+	w.firstCall = false
+	if n == nil {
+		return nil, false
+	}
+	w.currentNode = n
+	return itemType(n), true
+}
+
+// isXmlContainer 检查类型是否可以包含子节点
+func isXmlContainer(t AbstractTypeInterface) bool {
+	switch t.(type) {
+	case *YXmlElement, *YXmlFragment:
+		return true
+	}
+	return false
+}
+
+// YXmlFragment XML 片段，包含有序的子节点
+type YXmlFragment struct {
+	*AbstractType
+	prelimContent []interface{}         // prelimContent 整合到文档之前插入的子节点
+	outer         AbstractTypeInterface // outer 嵌入此片段的外层类型，作为新项目的父类型
+}
+
+// YXmlFragment 需要满足类型层的接口
+var _ AbstractTypeInterface = (*YXmlFragment)(nil)
+
+// NewYXmlFragment 创建一个新的 YXmlFragment 实例
+func NewYXmlFragment() *YXmlFragment {
+	f := &YXmlFragment{
+		AbstractType:  NewAbstractType(),
+		prelimContent: make([]interface{}, 0),
+	}
+	f.outer = f
+	return f
+}
+
+// ReadYXmlFragment 从解码器读取 YXmlFragment
+func ReadYXmlFragment(decoder util.UpdateDecoderInterface) struts.AbstractTypeInterface {
+	return NewYXmlFragment()
+}
+
+// GetXmlFragment 获取文档中名为 name 的顶层 YXmlFragment
+func GetXmlFragment(doc *struts.Doc, name string) *YXmlFragment {
+	return doc.Get(name, func() struts.AbstractTypeInterface {
+		return NewYXmlFragment()
+	}).(*YXmlFragment)
+}
+
+// FirstChild 返回第一个子节点，没有子节点时返回 nil
+func (f *YXmlFragment) FirstChild() interface{} {
+	first := f.First()
+	if first == nil {
+		return nil
+	}
+	return first.Content.GetContent()[0]
+}
+
+// Integrate 将此类型整合到文档中，并插入整合前的子节点
+func (f *YXmlFragment) Integrate(y *struts.Doc, item *struts.Item) {
+	f.AbstractType.Integrate(y, item)
+	content := f.prelimContent
+	f.prelimContent = nil
+	f.Insert(0, content)
+}
+
+// Copy 返回一个空的 YXmlFragment
+func (f *YXmlFragment) Copy() struts.AbstractTypeInterface {
+	return NewYXmlFragment()
+}
+
+// Clone 返回此片段的副本，子节点也会被复制
+func (f *YXmlFragment) Clone() struts.AbstractTypeInterface {
+	el := NewYXmlFragment()
+	el.Insert(0, cloneChildren(f.ToArray()))
+	return el
+}
+
+// cloneChildren 复制子节点中的共享类型
+func cloneChildren(children []interface{}) []interface{} {
+	content := make([]interface{}, 0, len(children))
+	for _, c := range children {
+		if t, ok := c.(AbstractTypeInterface); ok {
+			content = append(content, t.Clone())
+		} else {
+			content = append(content, c)
+		}
+	}
+	return content
+}
+
+// Length 返回子节点的数量
+func (f *YXmlFragment) Length() int {
+	if f.GetDoc() == nil {
+		return len(f.prelimContent)
+	}
+	return f.GetLength()
+}
+
+// CreateTreeWalker 创建遍历此片段所有后代节点的树遍历器
+func (f *YXmlFragment) CreateTreeWalker(filter func(t AbstractTypeInterface) bool) *YXmlTreeWalker {
+	return NewYXmlTreeWalker(f.outer, filter)
+}
+
+// QuerySelector 返回第一个节点名与 query 相同的后代元素，不区分大小写
+func (f *YXmlFragment) QuerySelector(query string) *YXmlElement {
+	walker := f.CreateTreeWalker(nodeNameFilter(query))
+	if t, ok := walker.Next(); ok {
+		return t.(*YXmlElement)
+	}
+	return nil
+}
+
+// QuerySelectorAll 返回所有节点名与 query 相同的后代元素，不区分大小写
+func (f *YXmlFragment) QuerySelectorAll(query string) []*YXmlElement {
+	walker := f.CreateTreeWalker(nodeNameFilter(query))
+	res := make([]*YXmlElement, 0)
+	for t, ok := walker.Next(); ok; t, ok = walker.Next() {
+		res = append(res, t.(*YXmlElement))
+	}
+	return res
+}
+
+// nodeNameFilter 返回按节点名过滤元素的函数
+func nodeNameFilter(query string) func(t AbstractTypeInterface) bool {
+	query = strings.ToUpper(query)
+	return func(t AbstractTypeInterface) bool {
+		el, ok := t.(*YXmlElement)
+		return ok && strings.ToUpper(el.NodeName) == query
+	}
+}
+
+// CallObserver 创建 YXmlEvent 并调用所有类型观察者
+func (f *YXmlFragment) CallObserver(transaction *struts.Transaction, parentSubs map[string]struct{}) {
+	f.AbstractType.CallObserver(transaction, parentSubs)
+	CallTypeObservers(f.outer, transaction, NewYXmlEvent(f.outer, parentSubs, transaction))
+}
+
+// ToString 返回所有子节点的 XML 字符串
+func (f *YXmlFragment) ToString() string {
+	var sb strings.Builder
+	for _, c := range f.ToArray() {
+		if s, ok := c.(xmlStringer); ok {
+			sb.WriteString(s.ToString())
+		}
+	}
+	return sb.String()
+}
+
+// String 实现 fmt.Stringer
+func (f *YXmlFragment) String() string {
+	return f.ToString()
+}
+
+// ToJSON 返回此片段的 XML 字符串
+func (f *YXmlFragment) ToJSON() interface{} {
+	return f.ToString()
+}
+
+// Insert 在指定索引处插入子节点
+func (f *YXmlFragment) Insert(index int, content []interface{}) {
+	if doc := f.GetDoc(); doc != nil {
+		doc.Transact(func(transaction *struts.Transaction) {
+			typeListInsertGenerics(transaction, f.outer, index, content)
+		}, nil, true)
+	} else {
+		if index > len(f.prelimContent) {
+			panic(util.ErrLengthExceeded)
+		}
+		prelim := append([]interface{}{}, f.prelimContent[:index]...)
+		prelim = append(prelim, content...)
+		f.prelimContent = append(prelim, f.prelimContent[index:]...)
+	}
+}
+
+// InsertAfter 在参考节点之后插入子节点，ref 为 nil 时插入到开头
+func (f *YXmlFragment) InsertAfter(ref AbstractTypeInterface, content []interface{}) {
+	if doc := f.GetDoc(); doc != nil {
+		doc.Transact(func(transaction *struts.Transaction) {
+			var refItem *struts.Item
+			if ref != nil {
+				refItem = ref.GetItem()
+			}
+			typeListInsertGenericsAfter(transaction, f.outer, refItem, content)
+		}, nil, true)
+	} else {
+		index := 0
+		if ref != nil {
+			index = -1
+			for i, c := range f.prelimContent {
+				if c == ref {
+					index = i + 1
+					break
+				}
+			}
+			if index < 0 {
+				panic(util.ErrUnexpectedCase)
+			}
+		}
+		f.Insert(index, content)
+	}
+}
+
+// Delete 从指定索引开始删除 length 个子节点
+func (f *YXmlFragment) Delete(index int, length int) {
+	if doc := f.GetDoc(); doc != nil {
+		doc.Transact(func(transaction *struts.Transaction) {
+			typeListDelete(transaction, f.outer, index, length)
+		}, nil, true)
+	} else {
+		if index+length > len(f.prelimContent) {
+			panic(util.ErrLengthExceeded)
+		}
+		f.prelimContent = append(f.prelimContent[:index], f.prelimContent[index+length:]...)
+	}
+}
+
+// ToArray 返回所有子节点
+func (f *YXmlFragment) ToArray() []interface{} {
+	if f.GetDoc() == nil {
+		return append([]interface{}{}, f.prelimContent...)
+	}
+	return TypeListToArray(f.outer)
+}
+
+// Push 在末尾追加子节点
+func (f *YXmlFragment) Push(content []interface{}) {
+	f.Insert(f.Length(), content)
+}
+
+// Unshift 在开头插入子节点
+func (f *YXmlFragment) Unshift(content []interface{}) {
+	f.Insert(0, content)
+}
+
+// Get 返回指定索引处的子节点
+func (f *YXmlFragment) Get(index int) interface{} {
+	if f.GetDoc() == nil {
+		if index < 0 || index >= len(f.prelimContent) {
+			return nil
+		}
+		return f.prelimContent[index]
+	}
+	return typeListGet(f.outer, index)
+}
+
+// Slice 返回 [start, end) 范围内的子节点，负数索引从末尾开始计算
+func (f *YXmlFragment) Slice(start, end int) []interface{} {
+	return TypeListSlice(f.outer, start, end)
+}
+
+// ForEach 在每个子节点上执行一次提供的函数
+func (f *YXmlFragment) ForEach(fn func(c interface{}, index int)) {
+	for i, c := range f.ToArray() {
+		fn(c, i)
+	}
+}
+
+// Write 将此类型写入编码器
+func (f *YXmlFragment) Write(encoder util.EncoderInterface) {
+	encoder.WriteTypeRef(struts.YXmlFragmentRefID)
+}
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/util"
+)
+
+func init() {
+	struts.RegisterTypeRef(struts.YXmlHookRefID, ReadYXmlHook)
+}
+
+// YXmlHook 可以在 XML 树中保存自定义数据的映射，由编辑器通过钩子名称渲染
+type YXmlHook struct {
+	*YMap
+	HookName string // HookName 钩子名称
+}
+
+// YXmlHook 需要满足类型层的接口
+var _ AbstractTypeInterface = (*YXmlHook)(nil)
+
+// NewYXmlHook 创建一个新的 YXmlHook 实例
+func NewYXmlHook(hookName string) *YXmlHook {
+	h := &YXmlHook{
+		YMap:     NewYMap(),
+		HookName: hookName,
+	}
+	h.outer = h
+	return h
+}
+
+// ReadYXmlHook 从解码器读取 YXmlHook
+func ReadYXmlHook(decoder util.UpdateDecoderInterface) struts.AbstractTypeInterface {
+	return NewYXmlHook(decoder.ReadKey())
+}
+
+// Copy 返回一个具有相同钩子名称的空钩子
+func (h *YXmlHook) Copy() struts.AbstractTypeInterface {
+	return NewYXmlHook(h.HookName)
+}
+
+// Clone 返回此钩子的副本
+func (h *YXmlHook) Clone() struts.AbstractTypeInterface {
+	el := NewYXmlHook(h.HookName)
+	h.ForEach(func(key string, value interface{}) {
+		el.Set(key, value)
+	})
+	return el
+}
+
+// Write 将此类型写入编码器
+func (h *YXmlHook) Write(encoder util.EncoderInterface) {
+	encoder.WriteTypeRef(struts.YXmlHookRefID)
+	encoder.WriteKey(h.HookName)
+}
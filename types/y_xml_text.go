@@ -0,0 +1,97 @@
+package types
+
+import (
+	"CollabEdit/struts"
+	"CollabEdit/util"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func init() {
+	struts.RegisterTypeRef(struts.YXmlTextRefID, ReadYXmlText)
+}
+
+// YXmlText XML 文本节点，格式化属性序列化为嵌套的 XML 元素
+type YXmlText struct {
+	*YText
+}
+
+// YXmlText 需要满足类型层的接口
+var _ AbstractTypeInterface = (*YXmlText)(nil)
+
+// NewYXmlText 创建一个新的 YXmlText 实例
+func NewYXmlText(str string) *YXmlText {
+	t := &YXmlText{YText: NewYText(str)}
+	t.outer = t
+	return t
+}
+
+// ReadYXmlText 从解码器读取 YXmlText
+func ReadYXmlText(decoder util.UpdateDecoderInterface) struts.AbstractTypeInterface {
+	return NewYXmlText("")
+}
+
+// NextSibling 返回下一个兄弟节点，不存在时返回 nil
+func (t *YXmlText) NextSibling() AbstractTypeInterface {
+	return siblingType(t.GetItem(), (*struts.Item).Next)
+}
+
+// PrevSibling 返回上一个兄弟节点，不存在时返回 nil
+func (t *YXmlText) PrevSibling() AbstractTypeInterface {
+	return siblingType(t.GetItem(), (*struts.Item).Prev)
+}
+
+// Copy 返回一个空的 YXmlText
+func (t *YXmlText) Copy() struts.AbstractTypeInterface {
+	return NewYXmlText("")
+}
+
+// Clone 返回此文本节点的副本
+func (t *YXmlText) Clone() struts.AbstractTypeInterface {
+	text := NewYXmlText("")
+	text.ApplyDelta(t.ToDelta())
+	return text
+}
+
+// ToString 返回此文本节点的 XML 字符串
+// 每个格式化属性序列化为一个元素，属性值为映射时其中的键值对序列化为元素的属性
+func (t *YXmlText) ToString() string {
+	var sb strings.Builder
+	for _, op := range t.ToDelta() {
+		nodeNames := make([]string, 0, len(op.Attributes))
+		for nodeName := range op.Attributes {
+			nodeNames = append(nodeNames, nodeName)
+		}
+		sort.Strings(nodeNames)
+		for _, nodeName := range nodeNames {
+			sb.WriteString("<" + nodeName)
+			if attrs, ok := op.Attributes[nodeName].(map[string]interface{}); ok {
+				for _, key := range sortedKeys(attrs) {
+					sb.WriteString(fmt.Sprintf(" %s=\"%v\"", key, attrs[key]))
+				}
+			}
+			sb.WriteString(">")
+		}
+		sb.WriteString(fmt.Sprint(op.Insert))
+		for i := len(nodeNames) - 1; i >= 0; i-- {
+			sb.WriteString("</" + nodeNames[i] + ">")
+		}
+	}
+	return sb.String()
+}
+
+// String 实现 fmt.Stringer
+func (t *YXmlText) String() string {
+	return t.ToString()
+}
+
+// ToJSON 返回此文本节点的 XML 字符串
+func (t *YXmlText) ToJSON() interface{} {
+	return t.ToString()
+}
+
+// Write 将此类型写入编码器
+func (t *YXmlText) Write(encoder util.EncoderInterface) {
+	encoder.WriteTypeRef(struts.YXmlTextRefID)
+}
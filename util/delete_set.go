@@ -1,6 +1,9 @@
 package util
 
-import "math"
+import (
+	"math"
+	"sort"
+)
 
 type DeleteItem struct {
 	Clock int
@@ -11,6 +14,13 @@ type DeleteSet struct {
 	Clients map[int]*[]DeleteItem
 }
 
+// NewDeleteSet 创建一个空的删除集合
+func NewDeleteSet() *DeleteSet {
+	return &DeleteSet{
+		Clients: make(map[int]*[]DeleteItem),
+	}
+}
+
 // IsDeleted 函数检查节点是否被删除
 func (ds *DeleteSet) IsDeleted(id *ID) bool {
 	items, exists := ds.Clients[id.Client]
@@ -36,3 +46,116 @@ func FindIndexDS(dis *[]DeleteItem, clock int) *int {
 	}
 	return nil
 }
+
+// SortAndMergeDeleteSet 对删除集合中每个客户端的删除项按时钟排序，并合并相邻或重叠的删除项
+func SortAndMergeDeleteSet(ds *DeleteSet) {
+	for _, dels := range ds.Clients {
+		sort.Slice(*dels, func(a, b int) bool {
+			return (*dels)[a].Clock < (*dels)[b].Clock
+		})
+		// 合并相邻或重叠的删除项
+		i, j := 1, 1
+		for ; i < len(*dels); i++ {
+			left := &(*dels)[j-1]
+			right := (*dels)[i]
+			if left.Clock+left.Len >= right.Clock {
+				left.Len = int(math.Max(float64(left.Len), float64(right.Clock+right.Len-left.Clock)))
+			} else {
+				if j < i {
+					(*dels)[j] = right
+				}
+				j++
+			}
+		}
+		if len(*dels) > 0 {
+			*dels = (*dels)[:j]
+		}
+	}
+}
+
+// AddToDeleteSet 向删除集合添加一个删除项，不会排序或合并
+func AddToDeleteSet(ds *DeleteSet, client int, clock int, length int) {
+	dels, exists := ds.Clients[client]
+	if !exists {
+		dels = &[]DeleteItem{}
+		ds.Clients[client] = dels
+	}
+	*dels = append(*dels, DeleteItem{Clock: clock, Len: length})
+}
+
+// MergeDeleteSets 合并多个删除集合，返回排序并合并后的新删除集合
+func MergeDeleteSets(dss []*DeleteSet) *DeleteSet {
+	merged := NewDeleteSet()
+	for dssI, ds := range dss {
+		for client, delsLeft := range ds.Clients {
+			if _, exists := merged.Clients[client]; exists {
+				continue
+			}
+			dels := append([]DeleteItem{}, *delsLeft...)
+			for i := dssI + 1; i < len(dss); i++ {
+				if other, ok := dss[i].Clients[client]; ok {
+					dels = append(dels, *other...)
+				}
+			}
+			merged.Clients[client] = &dels
+		}
+	}
+	SortAndMergeDeleteSet(merged)
+	return merged
+}
+
+// WriteDeleteSet 将删除集合写入编码器，客户端按ID降序写入以保证结果确定
+func WriteDeleteSet(encoder DSEncoderInterface, ds *DeleteSet) {
+	restEncoder := encoder.RestEncoder()
+	restEncoder.WriteVarUint(uint(len(ds.Clients)))
+	clients := make([]int, 0, len(ds.Clients))
+	for client := range ds.Clients {
+		clients = append(clients, client)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(clients)))
+	for _, client := range clients {
+		dsItems := *ds.Clients[client]
+		encoder.ResetDsCurVal()
+		restEncoder.WriteVarUint(uint(client))
+		restEncoder.WriteVarUint(uint(len(dsItems)))
+		for _, item := range dsItems {
+			encoder.WriteDsClock(item.Clock)
+			encoder.WriteDsLen(item.Len)
+		}
+	}
+}
+
+// ReadDeleteSet 从解码器读取删除集合
+func ReadDeleteSet(decoder DSDecoderInterface) *DeleteSet {
+	ds := NewDeleteSet()
+	restDecoder := decoder.RestDecoder()
+	numClients := int(restDecoder.ReadVarUint())
+	for i := 0; i < numClients; i++ {
+		decoder.ResetDsCurVal()
+		client := int(restDecoder.ReadVarUint())
+		numberOfDeletes := int(restDecoder.ReadVarUint())
+		for j := 0; j < numberOfDeletes; j++ {
+			AddToDeleteSet(ds, client, decoder.ReadDsClock(), decoder.ReadDsLen())
+		}
+	}
+	return ds
+}
+
+// EqualDeleteSets 判断两个删除集合是否包含相同的删除项
+func EqualDeleteSets(ds1, ds2 *DeleteSet) bool {
+	if len(ds1.Clients) != len(ds2.Clients) {
+		return false
+	}
+	for client, items1 := range ds1.Clients {
+		items2, exists := ds2.Clients[client]
+		if !exists || len(*items1) != len(*items2) {
+			return false
+		}
+		for i, item1 := range *items1 {
+			if item1.Clock != (*items2)[i].Clock || item1.Len != (*items2)[i].Len {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -7,46 +7,55 @@ import (
 
 // EventHandler 通用事件处理器
 type EventHandler struct {
-	Events []func(arg0 interface{}, arg1 interface{})
+	Events    []func(arg0 interface{}, arg1 interface{})
+	listeners []uintptr // 与 Events 一一对应，移除事件时用于识别注册的函数
 }
 
 // NewEventHandler 创建新的EventHandler实力
 func NewEventHandler() *EventHandler {
 	return &EventHandler{
-		Events: make([]func(arg0 interface{}, arg1 interface{}), 0),
+		Events:    make([]func(arg0 interface{}, arg1 interface{}), 0),
+		listeners: make([]uintptr, 0),
 	}
 }
 
 // AddEvent 添加一个事件
 func (eh *EventHandler) AddEvent(event func(arg0 interface{}, arg1 interface{})) {
+	eh.AddEventFor(event, event)
+}
+
+// AddEventFor 添加一个事件，移除时通过 listener 识别
+// 当 event 是对 listener 的包装时使用，这样调用方可以用原始函数取消注册
+func (eh *EventHandler) AddEventFor(listener interface{}, event func(arg0 interface{}, arg1 interface{})) {
 	eh.Events = append(eh.Events, event)
+	eh.listeners = append(eh.listeners, reflect.ValueOf(listener).Pointer())
 }
 
-// RemoveEvent 移除一个事件
-func (eh *EventHandler) RemoveEvent(event func(arg0 interface{}, arg1 interface{})) {
-	newEvents := make([]func(arg0 interface{}, arg1 interface{}), 0)
-	for _, l := range eh.Events {
-		// 通过反射比较函数指针
-		lPtr := reflect.ValueOf(l).Pointer()
-		eventPtr := reflect.ValueOf(event).Pointer()
-		if lPtr != eventPtr {
-			newEvents = append(newEvents, l)
+// RemoveEvent 移除一个事件，listener 为注册时使用的函数
+func (eh *EventHandler) RemoveEvent(listener interface{}) {
+	// 通过反射比较函数指针
+	ptr := reflect.ValueOf(listener).Pointer()
+	for i, l := range eh.listeners {
+		if l == ptr {
+			eh.Events = append(eh.Events[:i:i], eh.Events[i+1:]...)
+			eh.listeners = append(eh.listeners[:i:i], eh.listeners[i+1:]...)
+			return
 		}
 	}
-	if len(newEvents) == len(eh.Events) {
-		log.Fatalf("[CollabEdit] 尝试异常的事件不存在。")
-	}
-	eh.Events = newEvents
+	log.Printf("[CollabEdit] 尝试移除的事件不存在。")
 }
 
 // RemoveAllEvent 移除所有事件
 func (eh *EventHandler) RemoveAllEvent() {
 	eh.Events = make([]func(arg0 interface{}, arg1 interface{}), 0)
+	eh.listeners = make([]uintptr, 0)
 }
 
 // CallEvents 调用所有事件监听器
 func (eh *EventHandler) CallEvents(arg0 interface{}, arg1 interface{}) {
-	for _, event := range eh.Events {
+	// 复制一份，监听器中可能会移除自身
+	events := append([]func(arg0 interface{}, arg1 interface{}){}, eh.Events...)
+	for _, event := range events {
 		event(arg0, arg1)
 	}
 }
@@ -4,8 +4,8 @@ import "CollabEdit/core"
 
 // ID 结构体定义
 type ID struct {
-	Client int //客户端id
-	Clock  int //每一个客户端连续编号
+	Client int `json:"client"` //客户端id
+	Clock  int `json:"clock"`  //每一个客户端连续编号
 }
 
 // NewID 创建一个新的ID实例
@@ -22,13 +22,13 @@ func CompareIDs(a, b *ID) bool {
 }
 
 // WriteID 将ID写入编码器
-func (id *ID) WriteID(encoder core.Encoder) {
+func (id *ID) WriteID(encoder *core.Encoder) {
 	encoder.WriteVarUint(uint(id.Client))
 	encoder.WriteVarUint(uint(id.Clock))
 }
 
 // ReadID 从解码器读取ID
-func (id *ID) ReadID(decoder core.Decoder) *ID {
+func ReadID(decoder *core.Decoder) *ID {
 	client := decoder.ReadVarUint()
 	clock := decoder.ReadVarUint()
 	return NewID(int(client), int(clock))
@@ -12,3 +12,21 @@ func NewSnapshot(set *DeleteSet, sv map[int]int) *Snapshot {
 		Sv: sv,
 	}
 }
+
+// EmptySnapshot 创建空快照
+func EmptySnapshot() *Snapshot {
+	return NewSnapshot(NewDeleteSet(), make(map[int]int))
+}
+
+// EqualSnapshots 判断两个快照的状态向量与删除集合是否相同
+func EqualSnapshots(snap1, snap2 *Snapshot) bool {
+	if len(snap1.Sv) != len(snap2.Sv) {
+		return false
+	}
+	for client, clock := range snap1.Sv {
+		if c, exists := snap2.Sv[client]; !exists || c != clock {
+			return false
+		}
+	}
+	return EqualDeleteSets(snap1.Ds, snap2.Ds)
+}
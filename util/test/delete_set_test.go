@@ -0,0 +1,66 @@
+package test
+
+import (
+	"CollabEdit/core"
+	"CollabEdit/util"
+	"reflect"
+	"testing"
+)
+
+func newTestDeleteSet() *util.DeleteSet {
+	ds := util.NewDeleteSet()
+	util.AddToDeleteSet(ds, 1, 5, 2)
+	util.AddToDeleteSet(ds, 1, 0, 3)
+	util.AddToDeleteSet(ds, 1, 3, 1)
+	util.AddToDeleteSet(ds, 300, 200, 1)
+	util.SortAndMergeDeleteSet(ds)
+	return ds
+}
+
+func TestWriteAndReadDeleteSet(t *testing.T) {
+	ds := newTestDeleteSet()
+	expected := map[int][]util.DeleteItem{
+		1:   {{Clock: 0, Len: 4}, {Clock: 5, Len: 2}},
+		300: {{Clock: 200, Len: 1}},
+	}
+
+	encoderV1 := util.NewDSEncoderV1()
+	util.WriteDeleteSet(encoderV1, ds)
+	encoderV2 := util.NewDSEncoderV2()
+	util.WriteDeleteSet(encoderV2, ds)
+
+	decoders := []util.DSDecoderInterface{
+		util.NewDSDecoderV1(core.CreateDecoder(encoderV1.ToBytes())),
+		util.NewDSDecoderV2(core.CreateDecoder(encoderV2.ToBytes())),
+	}
+	for _, decoder := range decoders {
+		decoded := util.ReadDeleteSet(decoder)
+		if len(decoded.Clients) != len(expected) {
+			t.Fatalf("期望 %d 个客户端, 但得到 %d", len(expected), len(decoded.Clients))
+		}
+		for client, items := range expected {
+			if got := *decoded.Clients[client]; !reflect.DeepEqual(got, items) {
+				t.Errorf("客户端 %d 期望 %v, 但得到 %v", client, items, got)
+			}
+		}
+	}
+}
+
+func TestMergeDeleteSets(t *testing.T) {
+	ds1 := util.NewDeleteSet()
+	util.AddToDeleteSet(ds1, 1, 0, 2)
+	ds2 := util.NewDeleteSet()
+	util.AddToDeleteSet(ds2, 1, 2, 2)
+	util.AddToDeleteSet(ds2, 2, 10, 1)
+
+	merged := util.MergeDeleteSets([]*util.DeleteSet{ds1, ds2})
+	if got := *merged.Clients[1]; !reflect.DeepEqual(got, []util.DeleteItem{{Clock: 0, Len: 4}}) {
+		t.Errorf("客户端 1 的删除项未被合并: %v", got)
+	}
+	if !merged.IsDeleted(util.NewID(2, 10)) || merged.IsDeleted(util.NewID(2, 11)) {
+		t.Errorf("客户端 2 的删除项不正确")
+	}
+	if len(*ds1.Clients[1]) != 1 || (*ds1.Clients[1])[0].Len != 2 {
+		t.Errorf("合并不应修改原删除集合")
+	}
+}
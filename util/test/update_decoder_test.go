@@ -0,0 +1,90 @@
+package test
+
+import (
+	"CollabEdit/core"
+	"CollabEdit/util"
+	"reflect"
+	"testing"
+)
+
+// TestUpdateDecoderV2RoundTrip 使用 V2 编码器写入的每个字段都能被 V2 解码器按顺序读回
+func TestUpdateDecoderV2RoundTrip(t *testing.T) {
+	encoder := util.NewUpdateEncoderV2()
+	encoder.RestEncoder().WriteVarUint(7)
+	for i := 0; i < 3; i++ {
+		encoder.WriteClient(5)
+		encoder.WriteInfo(4)
+		encoder.WriteLeftID(util.ID{Client: 5, Clock: i * 3})
+		encoder.WriteRightID(util.ID{Client: 9, Clock: 10 - i})
+		encoder.WriteParentInfo(i == 0)
+		encoder.WriteString("héllo😀")
+		encoder.WriteTypeRef(byte(i))
+		encoder.WriteLen(i)
+		encoder.WriteKey("bold")
+		encoder.WriteJSON(map[string]interface{}{"i": i})
+		encoder.WriteAny("any")
+		encoder.WriteBuf([]byte{1, 2})
+	}
+
+	decoder := util.NewUpdateDecoderV2(core.CreateDecoder(encoder.ToBytes()))
+	if got := decoder.RestDecoder().ReadVarUint(); got != 7 {
+		t.Fatalf("期望 rest 数据 7, 但得到 %d", got)
+	}
+	for i := 0; i < 3; i++ {
+		if got := decoder.ReadClient(); got != 5 {
+			t.Fatalf("期望客户端 5, 但得到 %d", got)
+		}
+		if got := decoder.ReadInfo(); got != 4 {
+			t.Fatalf("期望信息 4, 但得到 %d", got)
+		}
+		if got := decoder.ReadLeftID(); *got != (util.ID{Client: 5, Clock: i * 3}) {
+			t.Fatalf("左侧 ID 不正确: %v", got)
+		}
+		if got := decoder.ReadRightID(); *got != (util.ID{Client: 9, Clock: 10 - i}) {
+			t.Fatalf("右侧 ID 不正确: %v", got)
+		}
+		if got := decoder.ReadParentInfo(); got != (i == 0) {
+			t.Fatalf("父信息不正确: %v", got)
+		}
+		if got := decoder.ReadString(); got != "héllo😀" {
+			t.Fatalf("字符串不正确: %q", got)
+		}
+		if got := decoder.ReadTypeRef(); got != i {
+			t.Fatalf("类型引用不正确: %d", got)
+		}
+		if got := decoder.ReadLen(); got != i {
+			t.Fatalf("长度不正确: %d", got)
+		}
+		if got := decoder.ReadKey(); got != "bold" {
+			t.Fatalf("键不正确: %q", got)
+		}
+		if got := decoder.ReadJSON(); !reflect.DeepEqual(got, map[string]interface{}{"i": i}) {
+			t.Fatalf("JSON 数据不正确: %v", got)
+		}
+		if got := decoder.ReadAny(); got != "any" {
+			t.Fatalf("Any 数据不正确: %v", got)
+		}
+		if got := decoder.ReadBuf(); !reflect.DeepEqual(got, []byte{1, 2}) {
+			t.Fatalf("缓冲区不正确: %v", got)
+		}
+	}
+}
+
+func TestUpdateDecoderV1RoundTrip(t *testing.T) {
+	encoder := util.NewUpdateEncoderV1()
+	encoder.WriteLeftID(util.ID{Client: 1, Clock: 2})
+	encoder.WriteParentInfo(true)
+	encoder.WriteKey("key")
+	encoder.WriteJSON([]interface{}{"a", 1.5})
+
+	decoder := util.NewUpdateDecoderV1(core.CreateDecoder(encoder.ToBytes()))
+	if got := decoder.ReadLeftID(); *got != (util.ID{Client: 1, Clock: 2}) {
+		t.Errorf("左侧 ID 不正确: %v", got)
+	}
+	if !decoder.ReadParentInfo() || decoder.ReadKey() != "key" {
+		t.Errorf("父信息或键不正确")
+	}
+	if got := decoder.ReadJSON(); !reflect.DeepEqual(got, []interface{}{"a", 1.5}) {
+		t.Errorf("JSON 数据不正确: %v", got)
+	}
+}
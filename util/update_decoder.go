@@ -0,0 +1,279 @@
+package util
+
+import (
+	"CollabEdit/core"
+	"encoding/json"
+)
+
+// DSDecoderInterface 删除集合解码器接口
+type DSDecoderInterface interface {
+	RestDecoder() *core.Decoder //获取底层解码器
+	ResetDsCurVal()             //重置当前值
+	ReadDsClock() int           //读取时钟值
+	ReadDsLen() int             //读取长度值
+}
+
+// DSDecoderV1 与 DSEncoderV1 对应的删除集合解码器
+type DSDecoderV1 struct {
+	*core.Decoder //rest解码器
+}
+
+// NewDSDecoderV1 创建DS解码器
+func NewDSDecoderV1(decoder *core.Decoder) *DSDecoderV1 {
+	return &DSDecoderV1{
+		Decoder: decoder,
+	}
+}
+
+// RestDecoder 获取底层解码器
+func (d *DSDecoderV1) RestDecoder() *core.Decoder {
+	return d.Decoder
+}
+
+// ResetDsCurVal 重置当前值
+func (d *DSDecoderV1) ResetDsCurVal() {
+
+}
+
+// ReadDsClock 读取时钟值
+func (d *DSDecoderV1) ReadDsClock() int {
+	return int(d.ReadVarUint())
+}
+
+// ReadDsLen 读取长度值
+func (d *DSDecoderV1) ReadDsLen() int {
+	return int(d.ReadVarUint())
+}
+
+// DSDecoderV2 与 DSEncoderV2 对应的删除集合解码器
+type DSDecoderV2 struct {
+	*core.Decoder
+	dsCurrVal int
+}
+
+// NewDSDecoderV2 创建一个新的 DSDecoderV2 实例
+func NewDSDecoderV2(decoder *core.Decoder) *DSDecoderV2 {
+	return &DSDecoderV2{
+		Decoder:   decoder,
+		dsCurrVal: 0,
+	}
+}
+
+// RestDecoder 获取底层解码器
+func (d *DSDecoderV2) RestDecoder() *core.Decoder {
+	return d.Decoder
+}
+
+// ResetDsCurVal 重置当前值
+func (d *DSDecoderV2) ResetDsCurVal() {
+	d.dsCurrVal = 0
+}
+
+// ReadDsClock 读取时钟值，编码的是与上一个值的差
+func (d *DSDecoderV2) ReadDsClock() int {
+	d.dsCurrVal += int(d.ReadVarUint())
+	return d.dsCurrVal
+}
+
+// ReadDsLen 读取长度值，编码的是长度减一
+func (d *DSDecoderV2) ReadDsLen() int {
+	diff := int(d.ReadVarUint()) + 1
+	d.dsCurrVal += diff
+	return diff
+}
+
+// UpdateDecoderInterface 更新解码器接口
+type UpdateDecoderInterface interface {
+	DSDecoderInterface
+	ReadLeftID() *ID       //读取左侧 ID
+	ReadRightID() *ID      //读取右侧 ID
+	ReadClient() int       //读取客户端 ID
+	ReadInfo() byte        //读取信息
+	ReadString() string    //读取字符串
+	ReadParentInfo() bool  //读取父信息
+	ReadTypeRef() int      //读取类型引用
+	ReadLen() int          //读取长度值
+	ReadAny() interface{}  //读取任意数据
+	ReadBuf() []byte       //读取缓冲区
+	ReadJSON() interface{} //读取 JSON 数据
+	ReadKey() string       //读取键值
+}
+
+// UpdateDecoderV1 结构体，继承 DSDecoderV1
+type UpdateDecoderV1 struct {
+	*DSDecoderV1
+}
+
+// NewUpdateDecoderV1 创建一个新的 UpdateDecoderV1 实例
+func NewUpdateDecoderV1(decoder *core.Decoder) *UpdateDecoderV1 {
+	return &UpdateDecoderV1{
+		DSDecoderV1: NewDSDecoderV1(decoder),
+	}
+}
+
+// ReadLeftID 读取左侧 ID
+func (u *UpdateDecoderV1) ReadLeftID() *ID {
+	client := int(u.ReadVarUint())
+	clock := int(u.ReadVarUint())
+	return NewID(client, clock)
+}
+
+// ReadRightID 读取右侧 ID
+func (u *UpdateDecoderV1) ReadRightID() *ID {
+	client := int(u.ReadVarUint())
+	clock := int(u.ReadVarUint())
+	return NewID(client, clock)
+}
+
+// ReadClient 读取客户端 ID
+func (u *UpdateDecoderV1) ReadClient() int {
+	return int(u.ReadVarUint())
+}
+
+// ReadInfo 读取信息
+func (u *UpdateDecoderV1) ReadInfo() byte {
+	return u.ReadUint8()
+}
+
+// ReadString 读取字符串
+func (u *UpdateDecoderV1) ReadString() string {
+	return u.ReadVarString()
+}
+
+// ReadParentInfo 读取父信息，返回父类型是否由键值确定
+func (u *UpdateDecoderV1) ReadParentInfo() bool {
+	return u.ReadVarUint() == 1
+}
+
+// ReadTypeRef 读取类型引用
+func (u *UpdateDecoderV1) ReadTypeRef() int {
+	return int(u.ReadVarUint())
+}
+
+// ReadLen 读取长度值
+func (u *UpdateDecoderV1) ReadLen() int {
+	return int(u.ReadVarUint())
+}
+
+// ReadAny 读取任意数据
+func (u *UpdateDecoderV1) ReadAny() interface{} {
+	return u.Decoder.ReadAny()
+}
+
+// ReadBuf 读取缓冲区，返回数据的副本
+func (u *UpdateDecoderV1) ReadBuf() []byte {
+	return append([]byte{}, u.ReadVarUint8Array()...)
+}
+
+// ReadJSON 读取 JSON 数据
+func (u *UpdateDecoderV1) ReadJSON() interface{} {
+	var data interface{}
+	if err := json.Unmarshal([]byte(u.ReadVarString()), &data); err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// ReadKey 读取键值
+func (u *UpdateDecoderV1) ReadKey() string {
+	return u.ReadVarString()
+}
+
+// UpdateDecoderV2 与 UpdateEncoderV2 对应的解码器，结构的各个字段分别从对应的列解码器中读取
+type UpdateDecoderV2 struct {
+	*DSDecoderV2
+	keys              []string                   // keys 已经读取的键，按键时钟索引
+	keyClockDecoder   *core.IntDiffOptRleDecoder // keyClockDecoder 键时钟解码器
+	clientDecoder     *core.UintOptRleDecoder    // clientDecoder 客户端 ID 解码器
+	leftClockDecoder  *core.IntDiffOptRleDecoder // leftClockDecoder 左侧时钟解码器
+	rightClockDecoder *core.IntDiffOptRleDecoder // rightClockDecoder 右侧时钟解码器
+	infoDecoder       *core.RleDecoder           // infoDecoder 信息解码器
+	stringDecoder     *core.StringDecoder        // stringDecoder 字符串解码器
+	parentInfoDecoder *core.RleDecoder           // parentInfoDecoder 父信息解码器
+	typeRefDecoder    *core.UintOptRleDecoder    // typeRefDecoder 类型引用解码器
+	lenDecoder        *core.UintOptRleDecoder    // lenDecoder 长度解码器
+}
+
+// NewUpdateDecoderV2 创建一个新的 UpdateDecoderV2 实例，依次读取各个列解码器的数据，剩余的数据由 rest 解码器读取
+func NewUpdateDecoderV2(decoder *core.Decoder) *UpdateDecoderV2 {
+	decoder.ReadVarUint() // 读取功能标志，目前未使用
+	return &UpdateDecoderV2{
+		DSDecoderV2:       NewDSDecoderV2(decoder),
+		keys:              make([]string, 0),
+		keyClockDecoder:   core.NewIntDiffOptRleDecoder(decoder.ReadVarUint8Array()),
+		clientDecoder:     core.NewUintOptRleDecoder(decoder.ReadVarUint8Array()),
+		leftClockDecoder:  core.NewIntDiffOptRleDecoder(decoder.ReadVarUint8Array()),
+		rightClockDecoder: core.NewIntDiffOptRleDecoder(decoder.ReadVarUint8Array()),
+		infoDecoder:       core.NewRleDecoder(decoder.ReadVarUint8Array(), (*core.Decoder).ReadUint8),
+		stringDecoder:     core.NewStringDecoder(decoder.ReadVarUint8Array()),
+		parentInfoDecoder: core.NewRleDecoder(decoder.ReadVarUint8Array(), (*core.Decoder).ReadUint8),
+		typeRefDecoder:    core.NewUintOptRleDecoder(decoder.ReadVarUint8Array()),
+		lenDecoder:        core.NewUintOptRleDecoder(decoder.ReadVarUint8Array()),
+	}
+}
+
+// ReadLeftID 读取左侧 ID
+func (u *UpdateDecoderV2) ReadLeftID() *ID {
+	return NewID(u.clientDecoder.Read(), u.leftClockDecoder.Read())
+}
+
+// ReadRightID 读取右侧 ID
+func (u *UpdateDecoderV2) ReadRightID() *ID {
+	return NewID(u.clientDecoder.Read(), u.rightClockDecoder.Read())
+}
+
+// ReadClient 读取客户端 ID
+func (u *UpdateDecoderV2) ReadClient() int {
+	return u.clientDecoder.Read()
+}
+
+// ReadInfo 读取信息
+func (u *UpdateDecoderV2) ReadInfo() byte {
+	return u.infoDecoder.Read()
+}
+
+// ReadString 读取字符串
+func (u *UpdateDecoderV2) ReadString() string {
+	return u.stringDecoder.Read()
+}
+
+// ReadParentInfo 读取父信息，返回父类型是否由键值确定
+func (u *UpdateDecoderV2) ReadParentInfo() bool {
+	return u.parentInfoDecoder.Read() == 1
+}
+
+// ReadTypeRef 读取类型引用
+func (u *UpdateDecoderV2) ReadTypeRef() int {
+	return u.typeRefDecoder.Read()
+}
+
+// ReadLen 读取长度值
+func (u *UpdateDecoderV2) ReadLen() int {
+	return u.lenDecoder.Read()
+}
+
+// ReadAny 读取任意数据
+func (u *UpdateDecoderV2) ReadAny() interface{} {
+	return u.Decoder.ReadAny()
+}
+
+// ReadBuf 读取缓冲区，返回数据的副本
+func (u *UpdateDecoderV2) ReadBuf() []byte {
+	return append([]byte{}, u.ReadVarUint8Array()...)
+}
+
+// ReadJSON 读取 JSON 数据，V2 格式中 JSON 数据使用 Any 编码
+func (u *UpdateDecoderV2) ReadJSON() interface{} {
+	return u.Decoder.ReadAny()
+}
+
+// ReadKey 读取键值，键时钟小于已读取的键的数量时复用之前的键
+func (u *UpdateDecoderV2) ReadKey() string {
+	keyClock := u.keyClockDecoder.Read()
+	if keyClock < len(u.keys) {
+		return u.keys[keyClock]
+	}
+	key := u.stringDecoder.Read()
+	u.keys = append(u.keys, key)
+	return key
+}
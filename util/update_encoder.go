@@ -5,6 +5,23 @@ import (
 	"encoding/json"
 )
 
+// DSEncoderInterface 删除集合编码器接口
+type DSEncoderInterface interface {
+	RestEncoder() *core.Encoder           //获取底层编码器
+	SetRestEncoder(encoder *core.Encoder) //替换底层编码器
+	ToBytes() []byte                      //转换为字节数组
+	ResetDsCurVal()                       //重置当前值
+	WriteDsClock(clock int)               //写入时钟值
+	WriteDsLen(len int)                   //写入长度值
+}
+
+// UpdateEncoderInterface 更新编码器接口，同时支持写入结构与删除集合
+type UpdateEncoderInterface interface {
+	DSEncoderInterface
+	EncoderInterface
+}
+
+// EncoderInterface 结构编码器接口
 type EncoderInterface interface {
 	WriteLeftID(id ID)           //写入左侧 ID
 	WriteRightID(id ID)          //写入右侧 ID
@@ -31,6 +48,16 @@ func NewDSEncoderV1() *DSEncoderV1 {
 	}
 }
 
+// RestEncoder 获取底层编码器
+func (d *DSEncoderV1) RestEncoder() *core.Encoder {
+	return d.Encoder
+}
+
+// SetRestEncoder 替换底层编码器
+func (d *DSEncoderV1) SetRestEncoder(encoder *core.Encoder) {
+	d.Encoder = encoder
+}
+
 // ToBytes 转换为字节数组
 func (d *DSEncoderV1) ToBytes() []byte {
 	return d.Encoder.ToBytes()
@@ -82,7 +109,7 @@ func (u *UpdateEncoderV1) WriteClient(client int) {
 
 // WriteInfo 写入信息
 func (u *UpdateEncoderV1) WriteInfo(info byte) {
-	u.WriteByte(info)
+	u.WriteUint8(info)
 }
 
 // WriteString 写入字符串
@@ -144,6 +171,16 @@ func NewDSEncoderV2() *DSEncoderV2 {
 	}
 }
 
+// RestEncoder 获取底层编码器
+func (d *DSEncoderV2) RestEncoder() *core.Encoder {
+	return d.Encoder
+}
+
+// SetRestEncoder 替换底层编码器
+func (d *DSEncoderV2) SetRestEncoder(encoder *core.Encoder) {
+	d.Encoder = encoder
+}
+
 // ToBytes 将编码器内容转换为 Uint8Array
 func (d *DSEncoderV2) ToBytes() []byte {
 	return d.Encoder.ToBytes()
@@ -173,7 +210,6 @@ func (d *DSEncoderV2) WriteDsLen(len int) {
 // UpdateEncoderV2 结构体，继承 DSEncoderV2
 type UpdateEncoderV2 struct {
 	*DSEncoderV2
-	keyMap            map[string]int
 	keyClock          int
 	keyClockEncoder   *core.IntDiffOptRleEncoder
 	clientEncoder     *core.UintOptRleEncoder
@@ -191,33 +227,34 @@ func NewUpdateEncoderV2() *UpdateEncoderV2 {
 	return &UpdateEncoderV2{
 		DSEncoderV2:       NewDSEncoderV2(),
 		keyClock:          0,
-		keyMap:            make(map[string]int),
 		keyClockEncoder:   core.NewIntDiffOptRleEncoder(),
 		clientEncoder:     core.NewUintOptRleEncoder(),
 		leftClockEncoder:  core.NewIntDiffOptRleEncoder(),
 		rightClockEncoder: core.NewIntDiffOptRleEncoder(),
-		infoEncoder:       core.NewRleEncoder((*core.Encoder).WriteByte),
+		infoEncoder:       core.NewRleEncoder((*core.Encoder).WriteUint8),
 		stringEncoder:     core.NewStringEncoder(),
-		parentInfoEncoder: core.NewRleEncoder((*core.Encoder).WriteByte),
+		parentInfoEncoder: core.NewRleEncoder((*core.Encoder).WriteUint8),
 		typeRefEncoder:    core.NewUintOptRleEncoder(),
 		lenEncoder:        core.NewUintOptRleEncoder(),
 	}
 }
 
-// ToBytes 将编码器的数据转换为 Uint8Array
+// ToBytes 将编码器的数据转换为 Uint8Array，各个列编码器的数据依次写入，最后追加 rest 编码器的数据
 func (e *UpdateEncoderV2) ToBytes() []byte {
-	e.WriteVarUint(0) // 这是一个未来可能使用的功能标志
-	e.WriteVarByteArray(e.keyClockEncoder.ToBytes())
-	e.WriteVarByteArray(e.clientEncoder.ToBytes())
-	e.WriteVarByteArray(e.leftClockEncoder.ToBytes())
-	e.WriteVarByteArray(e.rightClockEncoder.ToBytes())
-	e.WriteVarByteArray(e.infoEncoder.ToBytes())
-	e.WriteVarByteArray(e.stringEncoder.ToBytes())
-	e.WriteVarByteArray(e.parentInfoEncoder.ToBytes())
-	e.WriteVarByteArray(e.typeRefEncoder.ToBytes())
-	e.WriteVarByteArray(e.lenEncoder.ToBytes())
-	e.WriteByteArray(e.Encoder.ToBytes())
-	return e.Encoder.ToBytes()
+	encoder := core.CreateEncoder()
+	encoder.WriteVarUint(0) // 这是一个未来可能使用的功能标志
+	encoder.WriteVarByteArray(e.keyClockEncoder.ToBytes())
+	encoder.WriteVarByteArray(e.clientEncoder.ToBytes())
+	encoder.WriteVarByteArray(e.leftClockEncoder.ToBytes())
+	encoder.WriteVarByteArray(e.rightClockEncoder.ToBytes())
+	encoder.WriteVarByteArray(e.infoEncoder.ToBytes())
+	encoder.WriteVarByteArray(e.stringEncoder.ToBytes())
+	encoder.WriteVarByteArray(e.parentInfoEncoder.ToBytes())
+	encoder.WriteVarByteArray(e.typeRefEncoder.ToBytes())
+	encoder.WriteVarByteArray(e.lenEncoder.ToBytes())
+	// rest 编码器的数据直接追加，不写入长度
+	encoder.WriteByteArray(e.Encoder.ToBytes())
+	return encoder.ToBytes()
 }
 
 // WriteLeftID 编码左ID
@@ -282,13 +319,9 @@ func (e *UpdateEncoderV2) WriteJSON(embed interface{}) {
 }
 
 // WriteKey 编码键
+// Yjs 目前不会在编码时复用已经写入的键，为了保持字节兼容，每个键都会重新写入
 func (e *UpdateEncoderV2) WriteKey(key string) {
-	if clock, exists := e.keyMap[key]; !exists {
-		e.keyClockEncoder.Write(e.keyClock)
-		e.stringEncoder.Write(key)
-		e.keyMap[key] = e.keyClock
-		e.keyClock++
-	} else {
-		e.keyClockEncoder.Write(clock)
-	}
+	e.keyClockEncoder.Write(e.keyClock)
+	e.keyClock++
+	e.stringEncoder.Write(key)
 }
@@ -7,4 +7,8 @@ var (
 	ErrMethodUnimplemented = errors.New("方法没有被实现")
 	ErrTypeConversion      = errors.New("类型转换错误")
 	ErrParamUnimplemented  = errors.New("参数未实现")
+	ErrLengthExceeded      = errors.New("长度超出范围")
+	ErrGCEnabled           = errors.New("原文档必须禁用垃圾回收")
+	ErrComputeChanges      = errors.New("只能在事件处理器中计算变化")
+	ErrUnknownMessageType  = errors.New("未知的消息类型")
 )
@@ -1,4 +0,0 @@
-package util
-
-type YEvent struct {
-}